@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var flagCacheOlderThan time.Duration
+
+func cacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspecting and cleaning up the local cache of remote files",
+	}
+	cmd.AddCommand(
+		cacheListCmd(),
+		cacheVerifyCmd(),
+		cachePruneCmd(),
+		cacheClearCmd(),
+	)
+	return cmd
+}
+
+func cacheListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the entries in the cache",
+		Long: `List the Git repositories, files from the web and files stored by their hashes
+in the cache, along with their sizes and when they were last used.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			entries := cacheEntries()
+			if len(entries) == 0 {
+				log.Info().Msg("Cache is empty")
+				return
+			}
+			var total int64
+			for _, entry := range entries {
+				log.Info().Msgf("%-6s %9s  %s  %s", entry.Kind, formatSize(entry.Size), entry.LastUsed.Format("2006-01-02 15:04"), entry.Location)
+				total += entry.Size
+			}
+			log.Info().Msgf("%d entries, %s in total", len(entries), formatSize(total))
+		},
+	}
+}
+
+func cacheVerifyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "Check the integrity of the entries in the cache",
+		Long: `Re-hash the files in the cache and compare them to the hashes recorded when they
+were cached, and check the integrity of cached Git repositories. Exits with a
+non-zero status code if any entry is corrupted (use "themis-contract cache
+clear" to remove it).`,
+		Run: func(cmd *cobra.Command, args []string) {
+			failed := 0
+			for _, entry := range cacheEntries() {
+				if entry.Kind == contract.WebCacheEntry && len(entry.Hash) == 0 {
+					log.Warn().Msgf("Cannot verify %s: no hash was recorded when it was cached", entry.Location)
+					continue
+				}
+				if err := ctx.Cache().Verify(entry); err != nil {
+					log.Error().Msgf("Corrupted cache entry %s: %s", entry.Location, err)
+					failed++
+					continue
+				}
+				log.Debug().Msgf("Verified cache entry: %s", entry.Location)
+			}
+			if failed > 0 {
+				log.Error().Msgf("%d cache entries failed verification", failed)
+				os.Exit(1)
+			}
+			log.Info().Msg("All cache entries verified")
+		},
+	}
+}
+
+func cachePruneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove entries from the cache that have not been used recently",
+		Run: func(cmd *cobra.Command, args []string) {
+			cutoff := time.Now().Add(-flagCacheOlderThan)
+			pruned := make([]*contract.CacheEntry, 0)
+			for _, entry := range cacheEntries() {
+				if entry.LastUsed.Before(cutoff) {
+					pruned = append(pruned, entry)
+				}
+			}
+			evictCacheEntries(pruned)
+		},
+	}
+	cmd.PersistentFlags().DurationVar(&flagCacheOlderThan, "older-than", 30*24*time.Hour, "remove entries last used longer ago than this (e.g. \"168h\")")
+	return cmd
+}
+
+func cacheClearCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear [url]",
+		Short: "Remove entries from the cache",
+		Long: `Remove all entries from the cache or, if a URL is given, only the entries
+retrieved from that URL. Git URLs remove the whole cached repository, and files
+stored by their hashes can be removed by giving their hash.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			entries := cacheEntries()
+			if len(args) > 0 {
+				matching := make([]*contract.CacheEntry, 0)
+				for _, entry := range entries {
+					if entry.Matches(args[0]) {
+						matching = append(matching, entry)
+					}
+				}
+				if len(matching) == 0 {
+					log.Error().Msgf("No cache entries found for %s", args[0])
+					os.Exit(1)
+				}
+				entries = matching
+			}
+			evictCacheEntries(entries)
+		},
+	}
+}
+
+func cacheEntries() []*contract.CacheEntry {
+	entries, err := ctx.Cache().Entries()
+	if err != nil {
+		log.Error().Msgf("Failed to list cache entries: %s", err)
+		os.Exit(1)
+	}
+	return entries
+}
+
+func evictCacheEntries(entries []*contract.CacheEntry) {
+	var total int64
+	for _, entry := range entries {
+		if err := ctx.Cache().Evict(entry); err != nil {
+			log.Error().Msgf("Failed to remove %s from cache: %s", entry.Location, err)
+			os.Exit(1)
+		}
+		log.Info().Msgf("Removed %s (%s)", entry.Location, formatSize(entry.Size))
+		total += entry.Size
+	}
+	log.Info().Msgf("Removed %d cache entries, freeing %s", len(entries), formatSize(total))
+}
+
+// formatSize formats the given number of bytes in human-readable form.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
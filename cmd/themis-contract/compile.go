@@ -1,7 +1,10 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"path"
+	"strings"
 
 	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
 	"github.com/rs/zerolog/log"
@@ -9,14 +12,60 @@ import (
 )
 
 var (
-	flagOutput string
+	flagOutput               string
+	flagFormat               string
+	flagAllowStaleSignatures bool
+	flagRenderer             string
+	flagRendererOpts         map[string]string
+	flagNoAttestation        bool
+	flagNoWatermark          bool
 )
 
+// addCompileFlags adds the output- and renderer-related flags shared by all
+// commands that compile contracts.
+func addCompileFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(&flagOutput, "output", "o", "contract.pdf", "where to write the output contract")
+	cmd.PersistentFlags().StringVarP(&flagFormat, "format", "f", "", "the output format (pdf, docx, odt, html or txt); inferred from the output file name if not specified")
+	cmd.PersistentFlags().BoolVar(&flagAllowStaleSignatures, "allow-stale-signatures", false, "render signatures even if they were applied to an older version of the contract")
+	cmd.PersistentFlags().StringVar(&flagRenderer, "renderer", "", fmt.Sprintf("the renderer to use (one of: %s); overrides the active profile's renderer", strings.Join(contract.RendererNames(), ", ")))
+	cmd.PersistentFlags().StringToStringVar(&flagRendererOpts, "renderer-opt", nil, "renderer-specific options, prefixed with the renderer's name (e.g. --renderer-opt pandoc.pdf-engine=xelatex); overrides the active profile's renderer options")
+	cmd.PersistentFlags().BoolVar(&flagNoAttestation, "no-attestation", false, "do not write a build attestation alongside the compiled contract")
+	cmd.PersistentFlags().BoolVar(&flagNoWatermark, "no-watermark", false, "do not watermark the compiled contract as a draft if some signatories have not yet signed it")
+}
+
+// compileContext configures the context for compiling a contract based on the
+// flags added by addCompileFlags. If a format is specified but no output file,
+// the default output file's extension is adjusted to match the format.
+func compileContext(cmd *cobra.Command) (*contract.Context, string, error) {
+	compileCtx := ctx.WithStaleSignatures(flagAllowStaleSignatures).
+		WithRenderer(flagRenderer).
+		WithRendererOptions(flagRendererOpts).
+		WithAttestation(!flagNoAttestation).
+		WithDraftWatermark(!flagNoWatermark)
+	output := flagOutput
+	if len(flagFormat) > 0 {
+		format, err := contract.ParseOutputFormat(flagFormat)
+		if err != nil {
+			return nil, "", err
+		}
+		compileCtx = compileCtx.WithOutputFormat(format)
+		if !cmd.Flags().Changed("output") {
+			output = strings.TrimSuffix(output, path.Ext(output)) + format.Ext()
+		}
+	}
+	return compileCtx, output, nil
+}
+
 func compileCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "compile [contract]",
-		Short: "Compile a contract's sources to produce a PDF",
+		Short: "Compile a contract's sources to produce a PDF (or other document format)",
 		Run: func(cmd *cobra.Command, args []string) {
+			compileCtx, output, err := compileContext(cmd)
+			if err != nil {
+				log.Error().Msgf("Invalid compile options: %s", err)
+				os.Exit(1)
+			}
 			contractPath := defaultContractPath
 			if len(args) > 0 {
 				contractPath = args[0]
@@ -26,7 +75,7 @@ func compileCmd() *cobra.Command {
 				log.Error().Msgf("Failed to load contract: %s", err)
 				os.Exit(1)
 			}
-			err = c.Compile(flagOutput, ctx)
+			err = c.Compile(output, compileCtx)
 			if err != nil {
 				log.Error().Msgf("Failed to compile contract: %s", err)
 				os.Exit(1)
@@ -34,6 +83,6 @@ func compileCmd() *cobra.Command {
 			log.Info().Msg("Successfully compiled contract")
 		},
 	}
-	cmd.PersistentFlags().StringVarP(&flagOutput, "output", "o", "contract.pdf", "where to write the output contract")
+	addCompileFlags(cmd)
 	return cmd
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var flagRedlineOutput string
+
+func diffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <original> <revised>",
+		Short: "Produce a redline of one contract against another",
+		Long: `Render both contracts with their parameters and compare their text word by
+word, producing a redline of the revised contract against the original in which
+deleted words are struck through and inserted words are underlined. The
+redline is compiled in the same way as contracts are (see the "compile"
+command), unless the output file has a ".md" extension, in which case the
+redline is written as Markdown.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			original, err := contract.Load(args[0], ctx)
+			if err != nil {
+				log.Error().Msgf("Failed to load original contract: %s", err)
+				os.Exit(1)
+			}
+			revised, err := contract.Load(args[1], ctx)
+			if err != nil {
+				log.Error().Msgf("Failed to load revised contract: %s", err)
+				os.Exit(1)
+			}
+			if err := original.Redline(revised, flagRedlineOutput, redlineContext()); err != nil {
+				log.Error().Msgf("Failed to produce redline: %s", err)
+				os.Exit(1)
+			}
+			log.Info().Msgf("Wrote redline to %s", flagRedlineOutput)
+		},
+	}
+	addRedlineFlags(cmd)
+	return cmd
+}
+
+// redlineContext configures the context for compiling a redline based on the
+// renderer-related flags supplied to the command.
+func redlineContext() *contract.Context {
+	return ctx.WithRenderer(flagRenderer).WithRendererOptions(flagRendererOpts)
+}
+
+func addRedlineFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(&flagRedlineOutput, "output", "o", "redline.pdf", "where to write the redline (use a .md extension to write it as Markdown)")
+	cmd.PersistentFlags().StringVar(&flagRenderer, "renderer", "", fmt.Sprintf("the renderer to use (one of: %s); overrides the active profile's renderer", strings.Join(contract.RendererNames(), ", ")))
+	cmd.PersistentFlags().StringToStringVar(&flagRendererOpts, "renderer-opt", nil, "renderer-specific options, prefixed with the renderer's name (e.g. --renderer-opt pandoc.pdf-engine=xelatex); overrides the active profile's renderer options")
+}
@@ -17,6 +17,11 @@ contract is stored in the same Git repository as the contract itself, Themis
 Contract will automatically try to commit and push the act of signing and the
 newly compiled contract.`,
 		Run: func(cmd *cobra.Command, args []string) {
+			compileCtx, output, err := compileContext(cmd)
+			if err != nil {
+				log.Error().Msgf("Invalid compile options: %s", err)
+				os.Exit(1)
+			}
 			contractPath := defaultContractPath
 			if len(args) > 0 {
 				contractPath = args[0]
@@ -26,7 +31,7 @@ newly compiled contract.`,
 				log.Error().Msgf("Failed to load contract: %s", err)
 				os.Exit(1)
 			}
-			err = c.Execute(flagSigId, flagOutput, ctx)
+			err = c.Execute(flagSigId, output, compileCtx)
 			if err != nil {
 				log.Error().Msgf("Failed to compile contract: %s", err)
 				os.Exit(1)
@@ -35,6 +40,6 @@ newly compiled contract.`,
 		},
 	}
 	cmd.PersistentFlags().StringVar(&flagSigId, "as", "", "the ID of the signatory on behalf of whom you want to sign")
-	cmd.PersistentFlags().StringVarP(&flagOutput, "output", "o", "contract.pdf", "where to write the output contract")
+	addCompileFlags(cmd)
 	return cmd
 }
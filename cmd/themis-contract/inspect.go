@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var flagInspectJSON bool
+
+func inspectCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "inspect <pdf> [contract]",
+		Short: "Show the provenance of a compiled contract",
+		Long: `Show the provenance metadata embedded in a compiled contract PDF (the hashes
+of the contract, its parameters and template, its upstream and its signatories'
+signatures). If a contract (or a folder containing one) is given, the metadata
+is compared with the contract's current state, and the command exits with a
+non-zero status code if they differ.`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			provenance, err := contract.ReadPDFProvenance(args[0])
+			if err != nil {
+				log.Error().Msgf("Failed to read provenance: %s", err)
+				os.Exit(1)
+			}
+			if flagInspectJSON {
+				content, err := json.MarshalIndent(provenance, "", "  ")
+				if err != nil {
+					log.Error().Msgf("Failed to encode provenance: %s", err)
+					os.Exit(1)
+				}
+				fmt.Println(string(content))
+			} else {
+				showProvenance(provenance)
+			}
+			if len(args) < 2 {
+				return
+			}
+			contractPath := args[1]
+			if fi, err := os.Stat(contractPath); err == nil && fi.IsDir() {
+				contractPath = path.Join(contractPath, defaultContractPath)
+			}
+			c, err := contract.Load(contractPath, ctx)
+			if err != nil {
+				log.Error().Msgf("Failed to load contract: %s", err)
+				os.Exit(1)
+			}
+			current, err := c.Provenance(ctx)
+			if err != nil {
+				log.Error().Msgf("Failed to compute contract provenance: %s", err)
+				os.Exit(1)
+			}
+			discrepancies := provenance.Discrepancies(current)
+			for _, d := range discrepancies {
+				log.Error().Msg(d)
+			}
+			if len(discrepancies) > 0 {
+				log.Error().Msgf("Compiled contract does not match %s", contractPath)
+				os.Exit(1)
+			}
+			log.Info().Msgf("Compiled contract matches %s", contractPath)
+		},
+	}
+	cmd.PersistentFlags().BoolVar(&flagInspectJSON, "json", false, "print the provenance metadata as JSON")
+	return cmd
+}
+
+func showProvenance(p *contract.Provenance) {
+	toolVersion := p.ToolVersion
+	if len(toolVersion) == 0 {
+		toolVersion = "(unknown version)"
+	}
+	log.Info().Msgf("Compiled with Themis Contract %s", toolVersion)
+	log.Info().Msgf("Contract hash: %s", p.Hashes.Contract)
+	log.Info().Msgf("Params hash:   %s", p.Hashes.Params)
+	log.Info().Msgf("Template hash: %s", p.Hashes.Template)
+	if p.Upstream != nil {
+		log.Info().Msgf("Upstream: %s (hash %s)", p.Upstream.Location, p.Upstream.Hash)
+	}
+	for _, sig := range p.Signatories {
+		status := "not signed"
+		if len(sig.SignedDate) > 0 {
+			status = "signed " + sig.SignedDate
+		}
+		if sig.Stale {
+			status += " (stale)"
+		}
+		log.Info().Msgf("Signatory %s (%s): %s", sig.ID, sig.Email, status)
+	}
+}
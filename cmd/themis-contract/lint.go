@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var flagStrict bool
+
+func lintCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint [contract]",
+		Short: "Check a contract's template against its parameters",
+		Long: `Check a contract's template (and its partials) against its parameters,
+reporting references to parameters that do not exist as well as parameters that
+the template never uses. Exits with a non-zero status code if any undefined
+references are found (or, with --strict, if any parameters are unused).`,
+		Run: func(cmd *cobra.Command, args []string) {
+			contractPath := defaultContractPath
+			if len(args) > 0 {
+				contractPath = args[0]
+			}
+			c, err := contract.Load(contractPath, ctx)
+			if err != nil {
+				log.Error().Msgf("Failed to load contract: %s", err)
+				os.Exit(1)
+			}
+			issues, err := c.Lint()
+			if err != nil {
+				log.Error().Msgf("Failed to lint contract: %s", err)
+				os.Exit(1)
+			}
+			failed := false
+			for _, issue := range issues {
+				if issue.IsError() || flagStrict {
+					log.Error().Msg(issue.String())
+					failed = true
+				} else {
+					log.Warn().Msg(issue.String())
+				}
+			}
+			if failed {
+				log.Error().Msg("Contract template has problems")
+				os.Exit(1)
+			}
+			log.Info().Msg("No problems found with contract template")
+		},
+	}
+	cmd.PersistentFlags().BoolVar(&flagStrict, "strict", false, "treat unused parameters as errors")
+	return cmd
+}
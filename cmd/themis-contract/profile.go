@@ -149,7 +149,13 @@ func profileSetCmd() *cobra.Command {
 Set a specific profile parameter to the given value. If no profile ID is 
 supplied, the currently active profile's parameter will be set.
 
-Valid profile parameter names include: %s`, strings.Join(contract.ValidProfileParamNames(), ", ")),
+Valid profile parameter names include: %s
+
+The "renderer" parameter selects the renderer used to compile contracts
+("pandoc" by default). The "builtin" renderer doesn't need pandoc or LaTeX, but
+only supports Western European (Windows-1252) characters. Each
+"renderer-option" is prefixed with the name of the renderer to which it
+applies, e.g. "pandoc.pdf-engine=xelatex" (an empty value removes the option).`, strings.Join(contract.ValidProfileParamNames(), ", ")),
 		Args: cobra.ExactArgs(2),
 		Run: func(cmd *cobra.Command, args []string) {
 			profile := ctx.ActiveProfile()
@@ -4,6 +4,7 @@ import (
 	"os"
 	"os/user"
 	"path"
+	"time"
 
 	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
 	"github.com/rs/zerolog"
@@ -18,6 +19,8 @@ var (
 	flagHome         string
 	flagNoAutoCommit bool
 	flagNoAutoPush   bool
+	flagOffline      bool
+	flagCacheTTL     time.Duration
 
 	ctx *contract.Context
 )
@@ -47,15 +50,21 @@ func rootCmd() (*cobra.Command, error) {
 			zerolog.SetGlobalLevel(level)
 			log.Debug().Msg("Increasing output verbosity to debug level")
 
-			ctx, err = contract.InitContext(flagHome, !flagNoAutoCommit, !flagNoAutoPush)
+			ctx, err = contract.InitContext(flagHome, !flagNoAutoCommit, !flagNoAutoPush, &contract.FSCacheOptions{
+				TTL:     flagCacheTTL,
+				Offline: flagOffline,
+			})
 			if err != nil {
 				log.Error().Msgf("Failed to initialize context: %s", err)
 				os.Exit(1)
 			}
+			ctx = ctx.WithToolVersion(version)
 		},
 	}
 	cmd.PersistentFlags().BoolVar(&flagNoAutoCommit, "no-auto-commit", false, "do not attempt to automatically commit changes to contracts to their parent Git repository")
 	cmd.PersistentFlags().BoolVar(&flagNoAutoPush, "no-auto-push", false, "do not attempt to automatically push changes to contracts to their remote Git repository")
+	cmd.PersistentFlags().BoolVar(&flagOffline, "offline", false, "only use cached copies of remote files and repositories, without accessing the network")
+	cmd.PersistentFlags().DurationVar(&flagCacheTTL, "cache-ttl", 0, "how long cached copies of files from the web are used before being revalidated (e.g. \"1h\")")
 	cmd.PersistentFlags().BoolVarP(&flagVerbose, "verbose", "v", false, "increase output logging verbosity")
 	cmd.PersistentFlags().StringVar(&flagHome, "home", home, "path to the root of your Themis Contract configuration directory")
 	cmd.AddCommand(
@@ -68,6 +77,11 @@ func rootCmd() (*cobra.Command, error) {
 		signatureCmd(),
 		executeCmd(),
 		upstreamCmd(),
+		diffCmd(),
+		verifyCmd(),
+		lintCmd(),
+		inspectCmd(),
+		cacheCmd(),
 		//reviewCmd(),
 		versionCmd(),
 	)
@@ -49,15 +49,27 @@ func signatureListCmd() *cobra.Command {
 	}
 }
 
+var (
+	flagSignatureKeyID  string
+	flagSignatureSSHKey string
+)
+
 func signatureAddCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "add [name] [email] [image]",
 		Short: "Add a new signature",
 		Long: `Add a new signature with the given name and e-mail address, and copy the 
-specified image to use when signing contracts`,
-		Args: cobra.ExactArgs(3),
+specified image to use when signing contracts. If a GPG key ID and/or an SSH
+private key file is supplied, contracts will also be cryptographically signed
+using those keys. The image is optional when at least one signing key is
+supplied.`,
+		Args: cobra.RangeArgs(2, 3),
 		Run: func(cmd *cobra.Command, args []string) {
-			sig, err := ctx.AddSignature(args[0], args[1], args[2])
+			sigImage := ""
+			if len(args) > 2 {
+				sigImage = args[2]
+			}
+			sig, err := ctx.AddSignature(args[0], args[1], sigImage, flagSignatureKeyID, flagSignatureSSHKey)
 			if err != nil {
 				log.Error().Msgf("Failed to add new signature: %s", err)
 				os.Exit(1)
@@ -65,6 +77,9 @@ specified image to use when signing contracts`,
 			log.Info().Msgf("Added signature: %s", sig.Display())
 		},
 	}
+	cmd.PersistentFlags().StringVar(&flagSignatureKeyID, "key-id", "", "the ID or fingerprint of the GPG key with which to cryptographically sign contracts")
+	cmd.PersistentFlags().StringVar(&flagSignatureSSHKey, "ssh-key", "", "the path to the SSH private key with which to cryptographically sign contracts (overrides the profile's SSH key)")
+	return cmd
 }
 
 func signatureRemoveCmd() *cobra.Command {
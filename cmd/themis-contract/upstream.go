@@ -1,22 +1,33 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
+	"strings"
 
 	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
-var flagDiffProg string
+var (
+	flagDiffProg     string
+	flagUpstreamJSON bool
+	flagRedline      bool
+	flagKeepOurs     bool
+)
 
 func upstreamCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "upstream",
-		Short: "Comparing a contract to its upstream",
+		Short: "Comparing a contract to, and merging changes from, its upstream",
 	}
 	cmd.AddCommand(
 		upstreamDiffCmd(),
+		upstreamPullCmd(),
+		upstreamStatusCmd(),
+		upstreamLogCmd(),
 	)
 	return cmd
 }
@@ -27,7 +38,13 @@ func upstreamDiffCmd() *cobra.Command {
 		Short: "Show a diff of a contract compared to its upstream",
 		Long: `Show a diff of the various components of a contract compared to its upstream.
 This will show differences between both the parameters of the upstream and the
-template used in the upstream contract.`,
+template used in the upstream contract. Parameters are compared structurally,
+regardless of the format of the parameters files, and differences are reported
+by way of each parameter's dotted path.
+
+Use --redline to also produce a redline of the contract against its upstream,
+in which the words deleted from the upstream's text are struck through and the
+words inserted are underlined (see the "diff" command).`,
 		Run: func(cmd *cobra.Command, args []string) {
 			contractPath := defaultContractPath
 			if len(args) > 0 {
@@ -43,10 +60,26 @@ template used in the upstream contract.`,
 				log.Error().Msgf("Upstream diff failed: %s", err)
 				os.Exit(1)
 			}
+			if flagRedline {
+				if err := c.UpstreamRedline(flagRedlineOutput, redlineContext()); err != nil {
+					log.Error().Msgf("Failed to produce redline: %s", err)
+					os.Exit(1)
+				}
+				log.Info().Msgf("Wrote redline to %s", flagRedlineOutput)
+			}
+			if flagUpstreamJSON {
+				content, err := json.MarshalIndent(diff, "", "  ")
+				if err != nil {
+					log.Error().Msgf("Failed to encode diff: %s", err)
+					os.Exit(1)
+				}
+				fmt.Println(string(content))
+				return
+			}
 			if len(diff.ParamsDiff) == 0 {
-				log.Info().Msgf("Parameters files are identical")
+				log.Info().Msgf("Parameters are identical")
 			} else {
-				log.Info().Msgf("Comparing our parameters (left) to upstream (right):\n%s\n", diff.ParamsDiff)
+				log.Info().Msgf("Comparing our parameters to upstream (+ only in upstream, - only in ours, ~ changed):\n%s\n", diff.ParamsDiff)
 			}
 			if len(diff.TemplateDiff) == 0 {
 				log.Info().Msgf("Template files are identical")
@@ -55,6 +88,200 @@ template used in the upstream contract.`,
 			}
 		},
 	}
-	cmd.PersistentFlags().StringVar(&flagDiffProg, "diff-prog", "diff", "the program to use to perform the diff (try colordiff too)")
+	cmd.PersistentFlags().StringVar(&flagDiffProg, "diff-prog", "diff", "the program to use to perform the diff of the templates (try colordiff too)")
+	cmd.PersistentFlags().BoolVar(&flagUpstreamJSON, "json", false, "print the diff as JSON")
+	cmd.PersistentFlags().BoolVar(&flagRedline, "redline", false, "also produce a redline of the contract against its upstream")
+	addRedlineFlags(cmd)
+	return cmd
+}
+
+func upstreamPullCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pull [contract]",
+		Short: "Merge changes from a contract's upstream into the contract",
+		Long: `Perform a three-way merge of the changes made to the upstream's parameters,
+template, template partials and schema into the contract, using the version of
+the upstream from which the contract was derived (or last merged) as the base.
+The upstream contract must be in a Git repository whose history contains this
+version.
+
+If there are conflicts, they are marked in the affected files and need to be
+resolved manually, after which this command must be run again to complete the
+merge. Until then, the contract keeps referring to the previous version of its
+upstream. Partials and schemas that were changed both in the contract and in
+its upstream, and which cannot be merged line by line, are reported and left
+as they are in the contract. The merge cannot be completed until they refer to
+the same content as the upstream's, unless --keep-ours is given to keep the
+contract's own. Once the merge is complete, the contract is updated and
+committed automatically.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			contractPath := defaultContractPath
+			if len(args) > 0 {
+				contractPath = args[0]
+			}
+			c, err := contract.Load(contractPath, ctx)
+			if err != nil {
+				log.Error().Msgf("Failed to load contract: %s", err)
+				os.Exit(1)
+			}
+			merge, err := c.UpstreamPull(flagKeepOurs, ctx)
+			if err != nil {
+				log.Error().Msgf("Failed to merge upstream changes: %s", err)
+				os.Exit(1)
+			}
+			if merge.UpToDate() {
+				log.Info().Msg("Contract is already up to date with its upstream")
+				return
+			}
+			if merge.HasConflicts() {
+				for _, f := range merge.Conflicts {
+					log.Error().Msgf("Merge conflict in %s", f)
+				}
+				for _, component := range merge.Unmerged {
+					log.Error().Msgf("Upstream changes to %s conflict with the contract's and must be merged manually", component)
+				}
+				log.Error().Msgf("Resolve the conflicts and then run \"themis-contract upstream pull %s\" again", contractPath)
+				os.Exit(1)
+			}
+			log.Info().Msgf("Merged upstream changes (upstream hash %s)", merge.Upstream.Hash)
+		},
+	}
+	cmd.PersistentFlags().BoolVar(&flagKeepOurs, "keep-ours", false, "keep the contract's own partials and schema where they conflict with the upstream's")
+	return cmd
+}
+
+func upstreamStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status [contract]",
+		Short: "Show whether a contract is behind its upstream",
+		Long: `Compare the version of the upstream recorded in the contract with the current
+version of the upstream (fetching it first if it's remote), and report whether
+the contract is up to date with its upstream, how many revisions behind it is,
+or whether it has diverged from the upstream (i.e. the version it was derived
+from is no longer in the upstream's history). If the upstream is in a Git
+repository, the commits that touched the upstream's files and the tags added
+since the recorded version are listed too.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			contractPath := defaultContractPath
+			if len(args) > 0 {
+				contractPath = args[0]
+			}
+			c, err := contract.Load(contractPath, ctx)
+			if err != nil {
+				log.Error().Msgf("Failed to load contract: %s", err)
+				os.Exit(1)
+			}
+			status, err := c.UpstreamStatus(ctx)
+			if err != nil {
+				log.Error().Msgf("Failed to determine upstream status: %s", err)
+				os.Exit(1)
+			}
+			if flagUpstreamJSON {
+				content, err := json.MarshalIndent(status, "", "  ")
+				if err != nil {
+					log.Error().Msgf("Failed to encode upstream status: %s", err)
+					os.Exit(1)
+				}
+				fmt.Println(string(content))
+				return
+			}
+			showUpstreamStatus(status)
+		},
+	}
+	cmd.PersistentFlags().BoolVar(&flagUpstreamJSON, "json", false, "print the upstream status as JSON")
 	return cmd
 }
+
+func showUpstreamStatus(status *contract.UpstreamStatus) {
+	switch status.State {
+	case contract.UpstreamUpToDate:
+		log.Info().Msgf("Contract is up to date with its upstream %s (hash %s)", status.Location, status.CurrentHash)
+		return
+	case contract.UpstreamChanged:
+		log.Info().Msgf("Upstream %s has changed (hash %s, previously %s), but it's not in a Git repository so its revisions cannot be listed", status.Location, status.CurrentHash, status.RecordedHash)
+		return
+	case contract.UpstreamDiverged:
+		log.Info().Msgf("Contract has diverged from its upstream %s: the version it was derived from (hash %s) is not in the upstream's history", status.Location, status.RecordedHash)
+		return
+	}
+	log.Info().Msgf("Contract is %d revision(s) behind its upstream %s (since commit %s)", len(status.Revisions), status.Location, status.BaseCommit)
+	for _, rev := range status.Revisions {
+		tags := ""
+		if len(rev.Tags) > 0 {
+			tags = fmt.Sprintf(" (%s)", strings.Join(rev.Tags, ", "))
+		}
+		log.Info().Msgf("  %s %s %s%s", rev.Commit[:7], rev.Date.Format("2006-01-02"), rev.Subject, tags)
+	}
+	if len(status.Tags) > 0 {
+		log.Info().Msgf("New upstream tags: %s", strings.Join(status.Tags, ", "))
+	}
+	if status.Uncommitted {
+		log.Info().Msg("Upstream also has uncommitted changes")
+	}
+}
+
+func upstreamLogCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "log [contract]",
+		Short: "Show a contract's lineage and the changes introduced by each layer",
+		Long: `Walk the lineage of a contract (the chain of contracts from which it was
+derived) back to the root template, showing the changes that each contract in
+the lineage made to the parameters and template of its own upstream.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			contractPath := defaultContractPath
+			if len(args) > 0 {
+				contractPath = args[0]
+			}
+			c, err := contract.Load(contractPath, ctx)
+			if err != nil {
+				log.Error().Msgf("Failed to load contract: %s", err)
+				os.Exit(1)
+			}
+			layers, err := c.UpstreamLog(flagDiffProg, ctx)
+			if err != nil {
+				log.Error().Msgf("Failed to walk contract lineage: %s", err)
+				os.Exit(1)
+			}
+			if flagUpstreamJSON {
+				content, err := json.MarshalIndent(layers, "", "  ")
+				if err != nil {
+					log.Error().Msgf("Failed to encode contract lineage: %s", err)
+					os.Exit(1)
+				}
+				fmt.Println(string(content))
+				return
+			}
+			for i, layer := range layers {
+				showLineageLayer(i, layer)
+			}
+		},
+	}
+	cmd.PersistentFlags().StringVar(&flagDiffProg, "diff-prog", "diff", "the program to use to perform the diff of the templates (try colordiff too)")
+	cmd.PersistentFlags().BoolVar(&flagUpstreamJSON, "json", false, "print the lineage as JSON")
+	return cmd
+}
+
+func showLineageLayer(i int, layer *contract.LineageLayer) {
+	details := fmt.Sprintf("hash %s", layer.Hash)
+	if len(layer.Timestamp) > 0 {
+		details += ", recorded " + layer.Timestamp
+	}
+	if layer.Changed() {
+		details += fmt.Sprintf(", changed since recorded hash %s", layer.RecordedHash)
+	}
+	log.Info().Msgf("[%d] %s (%s)", i, layer.Location, details)
+	if layer.Changes == nil {
+		log.Info().Msg("    Root of the lineage")
+		return
+	}
+	if len(layer.Changes.ParamsDiff) == 0 && len(layer.Changes.TemplateDiff) == 0 {
+		log.Info().Msg("    No changes relative to its upstream")
+		return
+	}
+	if len(layer.Changes.ParamsDiff) > 0 {
+		log.Info().Msgf("    Parameters compared to its upstream (+ only in upstream, - only in this contract, ~ changed):\n%s\n", layer.Changes.ParamsDiff)
+	}
+	if len(layer.Changes.TemplateDiff) > 0 {
+		log.Info().Msgf("    Template (left) compared to its upstream (right):\n%s\n", layer.Changes.TemplateDiff)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagAllowMissing   bool
+	flagAllowedSigners string
+)
+
+func verifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify [contract]",
+		Short: "Verify all signatories' signatures on a contract",
+		Long: `Verify that each signatory's cryptographic signature covers the current
+versions of the contract, its parameters and its template. Exits with a non-zero
+status code if any signature is not valid, which makes it suitable for use in
+continuous integration.
+
+By default, SSH signatures are verified against the SSH public key recorded for
+each signatory's e-mail address. To only trust the keys you maintain yourself,
+supply an SSH allowed signers file (see the "ALLOWED SIGNERS" section of the
+ssh-keygen manual) via --allowed-signers, or configure one in the active
+profile.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			contractPath := defaultContractPath
+			if len(args) > 0 {
+				contractPath = args[0]
+			}
+			c, err := contract.Load(contractPath, ctx)
+			if err != nil {
+				log.Error().Msgf("Failed to load contract: %s", err)
+				os.Exit(1)
+			}
+			allowedSigners := flagAllowedSigners
+			if profile := ctx.ActiveProfile(); len(allowedSigners) == 0 && profile != nil {
+				allowedSigners = profile.SSHAllowedSigners
+			}
+			results, err := c.Verify(allowedSigners)
+			if err != nil {
+				log.Error().Msgf("Failed to verify contract: %s", err)
+				os.Exit(1)
+			}
+			failed := false
+			for _, r := range results {
+				reason := ""
+				if len(r.Reason) > 0 {
+					reason = " - " + r.Reason
+				}
+				switch {
+				case r.Status == contract.SignatureValid:
+					log.Info().Msgf("%s (%s): %s", r.Signatory.Id, r.Signatory.Email, r.Status)
+				case r.Status == contract.SignatureMissing && flagAllowMissing:
+					log.Warn().Msgf("%s (%s): %s%s", r.Signatory.Id, r.Signatory.Email, r.Status, reason)
+				default:
+					log.Error().Msgf("%s (%s): %s%s", r.Signatory.Id, r.Signatory.Email, r.Status, reason)
+					failed = true
+				}
+			}
+			if failed {
+				log.Error().Msg("Contract signature verification failed")
+				os.Exit(1)
+			}
+			log.Info().Msg("Successfully verified contract signatures")
+		},
+	}
+	cmd.PersistentFlags().BoolVar(&flagAllowMissing, "allow-missing", false, "do not fail if some signatories have not yet signed")
+	cmd.PersistentFlags().StringVar(&flagAllowedSigners, "allowed-signers", "", "an SSH allowed signers file listing the keys to trust for each signatory instead of the recorded ones (overrides the profile's)")
+	return cmd
+}
@@ -5,8 +5,14 @@ go 1.14
 require (
 	github.com/BurntSushi/toml v0.3.1
 	github.com/alexkappa/mustache v0.0.0-20191113130723-8bb9cfca2bfa
+	github.com/aymerick/raymond v2.0.2+incompatible
+	github.com/gomarkdown/markdown v0.0.0-20230922105210-14b16010c2ee
+	github.com/jung-kurt/gofpdf v1.16.2
+	github.com/philandstuff/dhall-golang/v6 v6.0.2
 	github.com/rakyll/statik v0.1.7
 	github.com/rs/zerolog v1.19.0
+	github.com/sergi/go-diff v1.3.1
 	github.com/spf13/cobra v1.0.0
+	github.com/xeipuuv/gojsonschema v1.2.0
 	gopkg.in/yaml.v3 v3.0.0-20200603094226-e3079894b1e8
 )
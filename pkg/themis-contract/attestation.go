@@ -0,0 +1,159 @@
+package themis_contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// The suffix appended to a compiled contract's file name to obtain the name of
+// its attestation file.
+const attestationFileSuffix = ".attestation.json"
+
+// Attestation records everything needed to reproduce a compiled contract, along
+// with the hash of the compiled output, so that anyone can recompile the
+// contract from its sources and compare the result byte for byte.
+type Attestation struct {
+	Output          *FileRef          `json:"output"`                     // The file name and hash of the compiled contract.
+	Format          OutputFormat      `json:"format"`                     // The output format.
+	Renderer        string            `json:"renderer"`                   // The name of the renderer used to compile the contract.
+	RendererOptions map[string]string `json:"renderer_options,omitempty"` // The options passed to the renderer.
+	SourceDateEpoch int64             `json:"source_date_epoch"`          // The timestamp used for all dates embedded in the output.
+	GitCommit       string            `json:"git_commit,omitempty"`       // The most recent commit that touched the contract's inputs (if any).
+	GitDirty        bool              `json:"git_dirty,omitempty"`        // Did any of the inputs have uncommitted changes at the time of compilation?
+	Inputs          []*FileRef        `json:"inputs"`                     // All of the files from which the contract was compiled.
+	Provenance      *Provenance       `json:"provenance"`                 // The contract's provenance at the time of compilation.
+}
+
+// AttestationFile returns the path to the attestation file for the given
+// compiled contract.
+func AttestationFile(output string) string {
+	return output + attestationFileSuffix
+}
+
+// buildInfo captures the source date and Git state from which a contract is
+// compiled.
+type buildInfo struct {
+	sourceDate time.Time
+	gitCommit  string
+	gitDirty   bool
+}
+
+// contractBuildInfo determines the source date to use when compiling the
+// contract. In order of precedence, this is the SOURCE_DATE_EPOCH environment
+// variable, the time of the most recent Git commit that touched any of the
+// contract's inputs, or the most recent modification time of the inputs.
+func (c *Contract) contractBuildInfo(inputs []string) (*buildInfo, error) {
+	info := &buildInfo{}
+	contractDir := path.Dir(c.path.localPath)
+	if isGitRepo(contractDir) {
+		var err error
+		var commitTime time.Time
+		if info.gitCommit, commitTime, err = gitLastCommit(contractDir, inputs); err != nil {
+			return nil, err
+		}
+		if info.gitDirty, err = gitHasChanges(contractDir, inputs); err != nil {
+			return nil, err
+		}
+		if info.gitDirty {
+			log.Warn().Msg("Contract has uncommitted changes, so its compiled output cannot be reproduced from a Git commit")
+		}
+		info.sourceDate = commitTime
+	}
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); len(epoch) > 0 {
+		timestamp, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SOURCE_DATE_EPOCH \"%s\": %s", epoch, err)
+		}
+		info.sourceDate = time.Unix(timestamp, 0).UTC()
+	}
+	if info.sourceDate.IsZero() {
+		for _, input := range inputs {
+			fi, err := os.Stat(path.Join(contractDir, input))
+			if err != nil {
+				return nil, err
+			}
+			if fi.ModTime().After(info.sourceDate) {
+				info.sourceDate = fi.ModTime().Truncate(time.Second).UTC()
+			}
+		}
+	}
+	log.Debug().Msgf("Using source date: %s", info.sourceDate.Format(time.RFC3339))
+	return info, nil
+}
+
+// localInputFiles returns the paths (relative to the contract's folder) of all
+// of the local files that contribute to the compiled contract, including
+// signatures.
+func (c *Contract) localInputFiles() ([]string, error) {
+	contractDir := path.Dir(c.path.localPath)
+	files := make([]string, 0)
+	for _, f := range append(c.allLocalRelativeFiles(), path.Base(c.signatures.Path())) {
+		if _, err := os.Stat(path.Join(contractDir, f)); err == nil {
+			files = append(files, f)
+		}
+	}
+	for _, signatory := range c.signatories {
+		for _, sigFile := range []string{signatory.Signature, signatory.DetachedSignature, signatory.SSHSignature} {
+			if len(sigFile) == 0 {
+				continue
+			}
+			rel, err := filepath.Rel(contractDir, sigFile)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, rel)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// attestationInputs lists all of the inputs to the compiled contract along
+// with their hashes. Remote inputs are identified by their location.
+func (c *Contract) attestationInputs(localFiles []string) ([]*FileRef, error) {
+	contractDir := path.Dir(c.path.localPath)
+	inputs := make([]*FileRef, 0, len(localFiles))
+	for _, f := range localFiles {
+		hash, err := hashOfFile(path.Join(contractDir, f))
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, &FileRef{Location: f, Hash: hash})
+	}
+	remoteRefs := []*FileRef{c.ParamsFile, c.Template.File, c.Schema}
+	for _, partial := range c.Template.Partials {
+		remoteRefs = append(remoteRefs, partial.File)
+	}
+	for _, ref := range remoteRefs {
+		if ref != nil && !ref.IsRelative() {
+			inputs = append(inputs, &FileRef{Location: ref.Location, Hash: ref.Hash})
+		}
+	}
+	return inputs, nil
+}
+
+// writeAttestation writes the attestation for the given compiled contract
+// alongside it.
+func writeAttestation(output string, a *Attestation) error {
+	hash, err := hashOfFile(output)
+	if err != nil {
+		return err
+	}
+	a.Output = &FileRef{Location: path.Base(output), Hash: hash}
+	content, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	attestationFile := AttestationFile(output)
+	log.Info().Msgf("Writing build attestation: %s", attestationFile)
+	return ioutil.WriteFile(attestationFile, append(content, '\n'), 0644)
+}
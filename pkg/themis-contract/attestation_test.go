@@ -0,0 +1,90 @@
+package themis_contract_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+)
+
+func TestReproducibleCompilation(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	files := map[string]string{
+		"params.json":   `{"client": "Acme Corp", "signatories": [{"id": "alice", "name": "Alice", "email": "alice@somewhere.com"}]}`,
+		"contract.md":   "# Agreement with {{client}}\n\nThis is an agreement.\n",
+		"contract.json": `{"params": {"location": "./params.json"}, "template": {"format": "Mustache", "file": {"location": "./contract.md"}}}`,
+	}
+	for filename, content := range files {
+		if err := ioutil.WriteFile(path.Join(tempDir, filename), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := contract.NewTestContext(nil, contract.NewTestProfile("test", "", nil)).
+		WithRenderer(contract.BuiltinRendererName).
+		WithAttestation(true)
+	if err := contract.Update(path.Join(tempDir, "contract.json"), ctx); err != nil {
+		t.Fatal(err)
+	}
+	c, err := contract.Load(path.Join(tempDir, "contract.json"), ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// compiling concurrently must neither interfere with nor affect the output
+	output := path.Join(tempDir, "contract.pdf")
+	outputFiles := []string{output, path.Join(tempDir, "contract-copy.pdf")}
+	errs := make([]error, len(outputFiles))
+	var wg sync.WaitGroup
+	for i, outputFile := range outputFiles {
+		wg.Add(1)
+		go func(i int, outputFile string) {
+			defer wg.Done()
+			errs[i] = c.Compile(outputFile, ctx)
+		}(i, outputFile)
+	}
+	wg.Wait()
+	outputs := make([][]byte, len(outputFiles))
+	for i, outputFile := range outputFiles {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if outputs[i], err = ioutil.ReadFile(outputFile); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(outputs[0], outputs[1]) {
+		t.Error("expected compiling the same contract twice to produce identical output")
+	}
+
+	content, err := ioutil.ReadFile(contract.AttestationFile(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var attestation contract.Attestation
+	if err := json.Unmarshal(content, &attestation); err != nil {
+		t.Fatal(err)
+	}
+	if attestation.Output.Location != "contract.pdf" || len(attestation.Output.Hash) == 0 {
+		t.Errorf("unexpected output in attestation: %v", attestation.Output)
+	}
+	if attestation.Renderer != contract.BuiltinRendererName {
+		t.Errorf("expected renderer \"%s\" in attestation, but got \"%s\"", contract.BuiltinRendererName, attestation.Renderer)
+	}
+	expectedInputs := []string{"contract.json", "contract.md", "params.json"}
+	if len(attestation.Inputs) != len(expectedInputs) {
+		t.Fatalf("expected %d inputs in attestation, but got %d: %v", len(expectedInputs), len(attestation.Inputs), attestation.Inputs)
+	}
+	for i, input := range attestation.Inputs {
+		if input.Location != expectedInputs[i] {
+			t.Errorf("expected input %d in attestation to be %s, but got %s", i, expectedInputs[i], input.Location)
+		}
+	}
+}
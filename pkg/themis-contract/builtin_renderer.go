@@ -0,0 +1,708 @@
+package themis_contract
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gomarkdown/markdown/ast"
+	"github.com/gomarkdown/markdown/parser"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Layout parameters for the built-in PDF renderer (in millimeters and points).
+const (
+	builtinPageMargin      = 25.0
+	builtinFontFamily      = "Times"
+	builtinBaseFontSize    = 11.0
+	builtinLineHeight      = 5.0
+	builtinParagraphGap    = 2.5
+	builtinListIndent      = 7.0
+	builtinImageHeight     = 15.0
+	builtinTableCellMargin = 1.5
+	builtinSectionPrefix   = "Section"
+	// Offsets the watermark's baseline so that it's vertically centered.
+	builtinWatermarkBaseline = 13.0
+)
+
+var (
+	// Heading attributes, as per pandoc's Markdown, e.g. `# Heading {#sec:id -}`.
+	builtinHeadingAttrs = regexp.MustCompile(`^(#+\s.*?)\s*\{([^}]*)\}\s*$`)
+	// Bracketed spans, as per pandoc's Markdown, e.g. `[some text]{.class}`.
+	builtinBracketedSpan = regexp.MustCompile(`\[([^\]]*)\]\{([^}]*)\}`)
+	// The underline class in bracketed spans' attributes.
+	builtinUnderlineClass = regexp.MustCompile(`(^|\s)\.underline(\s|$)`)
+	// Cross-references, as per pandoc-crossref, e.g. `@sec:id` or `[@sec:id]`.
+	builtinCrossRef = regexp.MustCompile(`\[@(sec:[\w-]+(?:[.:][\w-]+)*)\]|@(sec:[\w-]+(?:[.:][\w-]+)*)`)
+	// Fenced code block delimiters.
+	builtinCodeFence = regexp.MustCompile("^\\s*(```|~~~)")
+)
+
+// builtinRenderer is a pure-Go renderer that converts the Markdown version of
+// a contract into a PDF, without requiring pandoc or LaTeX. It supports a
+// subset of pandoc's Markdown that covers most contracts: a YAML title block,
+// headings (automatically numbered), paragraphs with basic inline formatting,
+// lists, tables, images (e.g. signatures), fenced divs, strikeout, bracketed
+// spans (whose attributes are ignored, except for the `underline` class) and
+// pandoc-crossref section references.
+//
+// The only option supported by this renderer is `paper`, which sets the paper
+// size (A4, A5, Letter or Legal; A4 by default).
+//
+// Since this renderer uses the standard PDF fonts, it can only render
+// characters from the Windows-1252 (Western European) character set. Rendering
+// fails if the contract contains any other characters.
+type builtinRenderer struct{}
+
+var _ Renderer = &builtinRenderer{}
+
+func (r *builtinRenderer) Render(job *RenderJob) error {
+	if job.Format != PDFOutput {
+		return fmt.Errorf("the %s renderer only supports %s output", BuiltinRendererName, PDFOutput)
+	}
+	paper := "A4"
+	for name, value := range job.Options {
+		switch name {
+		case "paper":
+			paper = value
+		default:
+			return fmt.Errorf("unrecognized option for the %s renderer: %s", BuiltinRendererName, name)
+		}
+	}
+	switch strings.ToLower(paper) {
+	case "a4", "a5", "letter", "legal":
+	default:
+		return fmt.Errorf("unsupported paper size for the %s renderer: %s", BuiltinRendererName, paper)
+	}
+	content, err := ioutil.ReadFile(job.Input)
+	if err != nil {
+		return err
+	}
+	meta, body, err := splitMarkdownMetadata(content)
+	if err != nil {
+		return err
+	}
+	body = preprocessBuiltinMarkdown(body)
+	log.Debug().Msgf("Preprocessed Markdown for built-in renderer:\n%s\n", body)
+	p := parser.NewWithExtensions(parser.Tables | parser.FencedCode | parser.Strikethrough |
+		parser.SpaceHeadings | parser.BackslashLineBreak | parser.NoIntraEmphasis | parser.Autolink)
+	doc := p.Parse([]byte(body))
+
+	w := newPDFWriter(paper, job.Watermark, job.ResourcePaths)
+	if !job.SourceDate.IsZero() {
+		w.pdf.SetCreationDate(job.SourceDate)
+		w.pdf.SetModificationDate(job.SourceDate)
+	}
+	w.title(meta)
+	w.blocks(doc.GetChildren())
+	if len(w.unsupported) > 0 {
+		chars := make([]string, 0, len(w.unsupported))
+		for r := range w.unsupported {
+			chars = append(chars, fmt.Sprintf("%q (%U)", r, r))
+		}
+		sort.Strings(chars)
+		return fmt.Errorf(
+			"the %s renderer only supports Western European (Windows-1252) characters, but the contract contains: %s - use the %s renderer instead",
+			BuiltinRendererName,
+			strings.Join(chars, ", "),
+			PandocRendererName,
+		)
+	}
+	if err := w.pdf.Error(); err != nil {
+		return fmt.Errorf("failed to render PDF: %s", err)
+	}
+	return w.pdf.OutputFileAndClose(job.Output)
+}
+
+// splitMarkdownMetadata separates the YAML metadata block (if any) from the
+// body of the given Markdown document.
+func splitMarkdownMetadata(content []byte) (map[string]interface{}, string, error) {
+	meta := make(map[string]interface{})
+	frontMatter, body := splitFrontMatter(string(content))
+	if len(frontMatter) == 0 {
+		return meta, body, nil
+	}
+	// strip the metadata block's delimiters
+	lines := strings.Split(strings.TrimSuffix(frontMatter, "\n"), "\n")
+	if err := yaml.Unmarshal([]byte(strings.Join(lines[1:len(lines)-1], "\n")), &meta); err != nil {
+		return nil, "", fmt.Errorf("failed to parse YAML metadata block: %s", err)
+	}
+	return meta, body, nil
+}
+
+// preprocessBuiltinMarkdown converts the pandoc-specific Markdown constructs
+// we support into standard Markdown. Section numbers are inserted into
+// headings (unless they are marked as unnumbered), and cross-references to
+// sections are replaced with the section numbers.
+func preprocessBuiltinMarkdown(body string) string {
+	lines := strings.Split(body, "\n")
+	// first we figure out the top-level heading level, so that documents
+	// starting at `##` aren't numbered 0.1, 0.2, etc.
+	topLevel := 0
+	forEachMarkdownLine(lines, func(_ int, line string) {
+		if level := headingLevel(line); level > 0 && (topLevel == 0 || level < topLevel) {
+			topLevel = level
+		}
+	})
+	counters := make([]int, 7)
+	refs := make(map[string]string)
+	result := make([]string, 0, len(lines))
+	forEachMarkdownLine(lines, func(i int, line string) {
+		switch {
+		case strings.HasPrefix(strings.TrimSpace(line), ":::"):
+			// fenced div delimiters are dropped, but their content is kept
+			return
+
+		case headingLevel(line) > 0:
+			level := headingLevel(line)
+			numbered := true
+			id := ""
+			if m := builtinHeadingAttrs.FindStringSubmatch(line); m != nil {
+				line = m[1]
+				for _, attr := range strings.Fields(m[2]) {
+					switch {
+					case attr == "-" || attr == ".unnumbered":
+						numbered = false
+					case strings.HasPrefix(attr, "#"):
+						id = attr[1:]
+					}
+				}
+			}
+			if numbered {
+				rel := level - topLevel
+				counters[rel]++
+				for j := rel + 1; j < len(counters); j++ {
+					counters[j] = 0
+				}
+				parts := make([]string, rel+1)
+				for j := 0; j <= rel; j++ {
+					parts[j] = strconv.Itoa(counters[j])
+				}
+				number := strings.Join(parts, ".")
+				if len(id) > 0 {
+					refs[id] = number
+				}
+				line = fmt.Sprintf("%s %s %s", strings.Repeat("#", level), number, strings.TrimSpace(strings.TrimLeft(line, "#")))
+			}
+		}
+		result = append(result, builtinBracketedSpan.ReplaceAllStringFunc(line, func(span string) string {
+			m := builtinBracketedSpan.FindStringSubmatch(span)
+			if builtinUnderlineClass.MatchString(m[2]) {
+				return "<u>" + m[1] + "</u>"
+			}
+			return m[1]
+		}))
+	}, func(line string) {
+		// code blocks are passed through verbatim
+		result = append(result, line)
+	})
+	// now replace cross-references, which may refer to sections defined later
+	// in the document
+	text := strings.Join(result, "\n")
+	return builtinCrossRef.ReplaceAllStringFunc(text, func(ref string) string {
+		m := builtinCrossRef.FindStringSubmatch(ref)
+		id := m[1] + m[2]
+		if number, ok := refs[id]; ok {
+			return fmt.Sprintf("%s %s", builtinSectionPrefix, number)
+		}
+		log.Warn().Msgf("Unresolved cross-reference: %s", ref)
+		return ref
+	})
+}
+
+// forEachMarkdownLine calls fn for each line of the given Markdown that is not
+// within a fenced code block, and (optionally) code for each line that is.
+func forEachMarkdownLine(lines []string, fn func(int, string), code ...func(string)) {
+	inCode := false
+	for i, line := range lines {
+		if builtinCodeFence.MatchString(line) {
+			inCode = !inCode
+			if len(code) > 0 {
+				code[0](line)
+			}
+			continue
+		}
+		if inCode {
+			if len(code) > 0 {
+				code[0](line)
+			}
+			continue
+		}
+		fn(i, line)
+	}
+}
+
+func headingLevel(line string) int {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level >= len(line) || line[level] != ' ' {
+		return 0
+	}
+	return level
+}
+
+// pdfWriter walks a Markdown document's AST and writes it out to a PDF.
+type pdfWriter struct {
+	pdf           *gofpdf.Fpdf
+	tr            func(string) string // Translates UTF-8 text to the encoding of the standard PDF fonts.
+	unsupported   map[rune]bool       // All of the characters we've encountered that the standard PDF fonts cannot represent.
+	resourcePaths []string
+	bold, italic  int  // Nesting depth of strong/emphasized inline elements.
+	underline     int  // Nesting depth of underlined inline elements.
+	strikeout     int  // Nesting depth of struck out inline elements.
+	mono          bool // Are we writing inline code?
+	fontSize      float64
+}
+
+func newPDFWriter(paper, watermark string, resourcePaths []string) *pdfWriter {
+	pdf := gofpdf.New("P", "mm", paper, "")
+	// makes sure resources are always written in the same order
+	pdf.SetCatalogSort(true)
+	pdf.SetMargins(builtinPageMargin, builtinPageMargin, builtinPageMargin)
+	pdf.SetAutoPageBreak(true, builtinPageMargin)
+	w := &pdfWriter{
+		pdf:           pdf,
+		unsupported:   make(map[rune]bool),
+		resourcePaths: resourcePaths,
+		fontSize:      builtinBaseFontSize,
+	}
+	// the translator silently replaces characters it can't represent with a
+	// period, so we keep track of those characters to be able to fail instead
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	w.tr = func(s string) string {
+		for _, r := range s {
+			if r >= 0x80 && !w.unsupported[r] && tr(string(r)) == "." {
+				w.unsupported[r] = true
+			}
+		}
+		return tr(s)
+	}
+	if len(watermark) > 0 {
+		// the header is drawn before each page's content, so the watermark
+		// appears behind the content
+		pdf.SetHeaderFunc(func() {
+			pageWidth, pageHeight := pdf.GetPageSize()
+			pdf.SetFont("Helvetica", "B", 110)
+			pdf.SetTextColor(225, 225, 225)
+			textWidth := pdf.GetStringWidth(watermark)
+			pdf.TransformBegin()
+			pdf.TransformRotate(45, pageWidth/2, pageHeight/2)
+			pdf.Text((pageWidth-textWidth)/2, pageHeight/2+builtinWatermarkBaseline, w.tr(watermark))
+			pdf.TransformEnd()
+			pdf.SetTextColor(0, 0, 0)
+		})
+	}
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-builtinPageMargin / 2)
+		pdf.SetFont(builtinFontFamily, "", builtinBaseFontSize-2)
+		pdf.CellFormat(0, builtinLineHeight, strconv.Itoa(pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+	w.applyFont()
+	return w
+}
+
+func (w *pdfWriter) applyFont() {
+	family, style := builtinFontFamily, ""
+	if w.mono {
+		family = "Courier"
+	}
+	if w.bold > 0 {
+		style += "B"
+	}
+	if w.italic > 0 {
+		style += "I"
+	}
+	if w.underline > 0 {
+		style += "U"
+	}
+	if w.strikeout > 0 {
+		style += "S"
+	}
+	w.pdf.SetFont(family, style, w.fontSize)
+}
+
+func (w *pdfWriter) lineHeight() float64 {
+	return builtinLineHeight * w.fontSize / builtinBaseFontSize
+}
+
+func (w *pdfWriter) title(meta map[string]interface{}) {
+	title, _ := meta["title"].(string)
+	subtitle, _ := meta["subtitle"].(string)
+	if len(title) == 0 {
+		return
+	}
+	w.pdf.SetFont(builtinFontFamily, "B", 18)
+	w.pdf.MultiCell(0, 8, w.tr(plainMetadataText(title)), "", "C", false)
+	if len(subtitle) > 0 {
+		w.pdf.SetFont(builtinFontFamily, "", 14)
+		w.pdf.MultiCell(0, 7, w.tr(plainMetadataText(subtitle)), "", "C", false)
+	}
+	w.pdf.Ln(builtinLineHeight * 2)
+	w.applyFont()
+}
+
+// plainMetadataText strips YAML line block markers and basic Markdown
+// emphasis from metadata values such as titles.
+func plainMetadataText(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "|"))
+	}
+	return strings.NewReplacer("**", "", "__", "", "*", "", "_", "").Replace(strings.Join(lines, "\n"))
+}
+
+func (w *pdfWriter) blocks(nodes []ast.Node) {
+	for _, node := range nodes {
+		w.block(node)
+	}
+}
+
+func (w *pdfWriter) block(node ast.Node) {
+	switch n := node.(type) {
+	case *ast.Heading:
+		w.heading(n)
+
+	case *ast.Paragraph:
+		if w.rawCommand(n) {
+			return
+		}
+		w.inlines(n.Children)
+		w.pdf.Ln(w.lineHeight())
+		w.pdf.Ln(builtinParagraphGap)
+
+	case *ast.List:
+		w.list(n)
+
+	case *ast.BlockQuote:
+		left, _, right, _ := w.pdf.GetMargins()
+		w.pdf.SetLeftMargin(left + builtinListIndent)
+		w.pdf.SetRightMargin(right + builtinListIndent)
+		w.pdf.SetX(left + builtinListIndent)
+		w.italic++
+		w.applyFont()
+		w.blocks(n.Children)
+		w.italic--
+		w.applyFont()
+		w.pdf.SetLeftMargin(left)
+		w.pdf.SetRightMargin(right)
+
+	case *ast.CodeBlock:
+		w.pdf.SetFont("Courier", "", w.fontSize-2)
+		w.pdf.SetFillColor(240, 240, 240)
+		w.pdf.MultiCell(0, w.lineHeight()*0.9, w.tr(strings.TrimRight(string(n.Literal), "\n")), "", "L", true)
+		w.applyFont()
+		w.pdf.Ln(builtinParagraphGap)
+
+	case *ast.Table:
+		w.table(n)
+
+	case *ast.HorizontalRule:
+		left, _, right, _ := w.pdf.GetMargins()
+		pageWidth, _ := w.pdf.GetPageSize()
+		y := w.pdf.GetY() + builtinParagraphGap
+		w.pdf.Line(left, y, pageWidth-right, y)
+		w.pdf.SetY(y + builtinParagraphGap)
+
+	case *ast.HTMLBlock:
+		// raw HTML (e.g. comments) is ignored
+
+	default:
+		if container := node.AsContainer(); container != nil {
+			w.blocks(container.Children)
+		}
+	}
+}
+
+// rawCommand handles paragraphs that consist solely of a raw LaTeX page break
+// command, which are commonly used in contracts. Other raw LaTeX commands are
+// not supported, and are rendered as text.
+func (w *pdfWriter) rawCommand(p *ast.Paragraph) bool {
+	text := strings.TrimSpace(inlineText(p))
+	switch text {
+	case `\newpage`, `\pagebreak`, `\clearpage`:
+		w.pdf.AddPage()
+		return true
+	}
+	if strings.HasPrefix(text, `\`) && !strings.ContainsAny(text, " \n") {
+		log.Warn().Msgf("The %s renderer does not support the LaTeX command %s, so it is rendered as text", BuiltinRendererName, text)
+	}
+	return false
+}
+
+func (w *pdfWriter) heading(h *ast.Heading) {
+	sizes := map[int]float64{1: 16, 2: 13.5, 3: 12}
+	size, ok := sizes[h.Level]
+	if !ok {
+		size = builtinBaseFontSize
+	}
+	w.pdf.Ln(builtinParagraphGap)
+	// avoid leaving a heading stranded at the bottom of a page
+	_, pageHeight := w.pdf.GetPageSize()
+	if w.pdf.GetY()+3*builtinLineHeight > pageHeight-builtinPageMargin {
+		w.pdf.AddPage()
+	}
+	w.pdf.Bookmark(w.tr(inlineText(h)), h.Level-1, -1)
+	prevSize := w.fontSize
+	w.fontSize = size
+	w.bold++
+	w.applyFont()
+	w.inlines(h.Children)
+	w.pdf.Ln(w.lineHeight())
+	w.bold--
+	w.fontSize = prevSize
+	w.applyFont()
+	w.pdf.Ln(builtinParagraphGap)
+}
+
+func (w *pdfWriter) list(l *ast.List) {
+	left, _, _, _ := w.pdf.GetMargins()
+	indent := left + builtinListIndent
+	number := l.Start
+	if number == 0 {
+		number = 1
+	}
+	for _, child := range l.Children {
+		item, ok := child.(*ast.ListItem)
+		if !ok {
+			continue
+		}
+		marker := "•"
+		if l.ListFlags&ast.ListTypeOrdered != 0 {
+			marker = fmt.Sprintf("%d%c", number, orDefault(l.Delimiter, '.'))
+			number++
+		}
+		w.pdf.SetLeftMargin(indent)
+		w.pdf.SetX(indent - builtinListIndent)
+		w.pdf.CellFormat(builtinListIndent, w.lineHeight(), w.tr(marker), "", 0, "L", false, 0, "")
+		for i, block := range item.Children {
+			// tight list items' paragraphs shouldn't be separated by gaps
+			if p, ok := block.(*ast.Paragraph); ok && l.Tight {
+				w.inlines(p.Children)
+				w.pdf.Ln(w.lineHeight())
+				continue
+			}
+			if i > 0 {
+				w.pdf.SetX(indent)
+			}
+			w.block(block)
+		}
+		w.pdf.SetLeftMargin(left)
+	}
+	if _, nested := l.Parent.(*ast.ListItem); !nested {
+		w.pdf.Ln(builtinParagraphGap)
+	}
+}
+
+func orDefault(c byte, def byte) byte {
+	if c == 0 {
+		return def
+	}
+	return c
+}
+
+func (w *pdfWriter) table(t *ast.Table) {
+	var rows []*ast.TableRow
+	ast.WalkFunc(t, func(node ast.Node, entering bool) ast.WalkStatus {
+		if row, ok := node.(*ast.TableRow); ok && entering {
+			rows = append(rows, row)
+		}
+		return ast.GoToNext
+	})
+	if len(rows) == 0 {
+		return
+	}
+	cols := len(rows[0].Children)
+	left, _, right, _ := w.pdf.GetMargins()
+	pageWidth, pageHeight := w.pdf.GetPageSize()
+	colWidth := (pageWidth - left - right) / float64(cols)
+	lineHeight := w.lineHeight()
+	for _, row := range rows {
+		cells := make([]string, cols)
+		header := false
+		for i, child := range row.Children {
+			if i >= cols {
+				break
+			}
+			cell := child.(*ast.TableCell)
+			header = header || cell.IsHeader
+			cells[i] = w.tr(inlineText(cell))
+		}
+		if header {
+			w.bold++
+			w.applyFont()
+		}
+		// figure out the height of the row from its tallest cell
+		lines := 1
+		for _, cell := range cells {
+			if n := len(w.pdf.SplitLines([]byte(cell), colWidth-2*builtinTableCellMargin)); n > lines {
+				lines = n
+			}
+		}
+		rowHeight := float64(lines)*lineHeight + 2*builtinTableCellMargin
+		if w.pdf.GetY()+rowHeight > pageHeight-builtinPageMargin {
+			w.pdf.AddPage()
+		}
+		y := w.pdf.GetY()
+		for i, cell := range cells {
+			x := left + float64(i)*colWidth
+			w.pdf.Rect(x, y, colWidth, rowHeight, "D")
+			w.pdf.SetXY(x+builtinTableCellMargin, y+builtinTableCellMargin)
+			w.pdf.MultiCell(colWidth-2*builtinTableCellMargin, lineHeight, cell, "", "L", false)
+		}
+		w.pdf.SetXY(left, y+rowHeight)
+		if header {
+			w.bold--
+			w.applyFont()
+		}
+	}
+	w.pdf.Ln(builtinParagraphGap * 2)
+}
+
+func (w *pdfWriter) inlines(nodes []ast.Node) {
+	for _, node := range nodes {
+		w.inline(node)
+	}
+}
+
+func (w *pdfWriter) inline(node ast.Node) {
+	switch n := node.(type) {
+	case *ast.Text:
+		w.pdf.Write(w.lineHeight(), w.tr(string(n.Literal)))
+
+	case *ast.Softbreak:
+		w.pdf.Write(w.lineHeight(), " ")
+
+	case *ast.Hardbreak:
+		w.pdf.Ln(w.lineHeight())
+
+	case *ast.Emph:
+		w.italic++
+		w.applyFont()
+		w.inlines(n.Children)
+		w.italic--
+		w.applyFont()
+
+	case *ast.Strong:
+		w.bold++
+		w.applyFont()
+		w.inlines(n.Children)
+		w.bold--
+		w.applyFont()
+
+	case *ast.Del:
+		w.strikeout++
+		w.applyFont()
+		w.inlines(n.Children)
+		w.strikeout--
+		w.applyFont()
+
+	case *ast.Code:
+		w.mono = true
+		w.applyFont()
+		w.pdf.Write(w.lineHeight(), w.tr(string(n.Literal)))
+		w.mono = false
+		w.applyFont()
+
+	case *ast.Link:
+		w.pdf.SetTextColor(0, 0, 160)
+		w.pdf.WriteLinkString(w.lineHeight(), w.tr(inlineText(n)), string(n.Destination))
+		w.pdf.SetTextColor(0, 0, 0)
+
+	case *ast.Image:
+		w.image(n)
+
+	case *ast.HTMLSpan:
+		// raw HTML is ignored, except for the underline tags produced from
+		// bracketed spans
+		switch string(n.Literal) {
+		case "<u>":
+			w.underline++
+			w.applyFont()
+		case "</u>":
+			if w.underline > 0 {
+				w.underline--
+				w.applyFont()
+			}
+		}
+
+	default:
+		if container := node.AsContainer(); container != nil {
+			w.inlines(container.Children)
+		} else if leaf := node.AsLeaf(); leaf != nil {
+			w.pdf.Write(w.lineHeight(), w.tr(string(leaf.Literal)))
+		}
+	}
+}
+
+// image renders the given image on its own line. Images that cannot be found
+// are replaced by their alternative text.
+func (w *pdfWriter) image(img *ast.Image) {
+	dest := string(img.Destination)
+	if len(dest) == 0 {
+		return
+	}
+	imageFile := w.resolveResource(dest)
+	if len(imageFile) == 0 {
+		log.Warn().Msgf("Cannot find image: %s", dest)
+		w.pdf.Write(w.lineHeight(), w.tr(fmt.Sprintf("[%s]", inlineText(img))))
+		return
+	}
+	left, _, _, _ := w.pdf.GetMargins()
+	if w.pdf.GetX() > left+0.1 {
+		w.pdf.Ln(w.lineHeight())
+	}
+	_, pageHeight := w.pdf.GetPageSize()
+	if w.pdf.GetY()+builtinImageHeight > pageHeight-builtinPageMargin {
+		w.pdf.AddPage()
+	}
+	y := w.pdf.GetY()
+	w.pdf.ImageOptions(imageFile, w.pdf.GetX(), y, 0, builtinImageHeight, false, gofpdf.ImageOptions{ReadDpi: true}, 0, "")
+	w.pdf.SetXY(left, y+builtinImageHeight)
+}
+
+func (w *pdfWriter) resolveResource(filename string) string {
+	if path.IsAbs(filename) {
+		if _, err := os.Stat(filename); err == nil {
+			return filename
+		}
+		return ""
+	}
+	for _, dir := range w.resourcePaths {
+		candidate := path.Join(dir, filename)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// inlineText extracts the plain text content of the given node.
+func inlineText(node ast.Node) string {
+	var buf bytes.Buffer
+	ast.WalkFunc(node, func(n ast.Node, entering bool) ast.WalkStatus {
+		if !entering {
+			return ast.GoToNext
+		}
+		switch n.(type) {
+		case *ast.Softbreak, *ast.Hardbreak:
+			buf.WriteString(" ")
+		default:
+			if leaf := n.AsLeaf(); leaf != nil {
+				buf.Write(leaf.Literal)
+			}
+		}
+		return ast.GoToNext
+	})
+	return buf.String()
+}
@@ -0,0 +1,163 @@
+package themis_contract_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+)
+
+const builtinRendererTestMarkdown = `
+::: {.preamble}
+This agreement is made between [Acme Corp]{.party} and **Bob**.
+:::
+
+## Definitions {#sec:definitions}
+
+Terms are defined as per @sec:payment.
+
+## Preamble {-}
+
+Not numbered.
+
+## Payment {#sec:payment}
+
+### Invoicing
+
+See [@sec:definitions].
+
+` + "```" + `
+## Not a heading {#sec:code}
+` + "```" + `
+
+| Party | Role |
+|-------|------|
+| Acme  | Client |
+
+1. First
+2. Second
+
+![Signature](missing.png)
+`
+
+func TestBuiltinMarkdownPreprocessing(t *testing.T) {
+	const expected = `
+This agreement is made between Acme Corp and **Bob**.
+
+## 1 Definitions
+
+Terms are defined as per Section 2.
+
+## Preamble
+
+Not numbered.
+
+## 2 Payment
+
+### 2.1 Invoicing
+
+See Section 1.
+
+` + "```" + `
+## Not a heading {#sec:code}
+` + "```"
+	actual := contract.PreprocessBuiltinMarkdown(builtinRendererTestMarkdown)
+	if !bytes.HasPrefix([]byte(actual), []byte(expected)) {
+		t.Errorf("expected preprocessed Markdown to start with:\n%s\n\nbut got:\n%s", expected, actual)
+	}
+}
+
+func TestBuiltinRenderer(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-builtin-renderer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	input := path.Join(tempDir, "contract.md")
+	output := path.Join(tempDir, "contract.pdf")
+	if err := ioutil.WriteFile(input, []byte("---\ntitle: Services Agreement\n---\n"+builtinRendererTestMarkdown), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := contract.RenderBuiltinPDF(input, output, []string{tempDir}); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(content, []byte("%PDF-")) {
+		t.Errorf("expected output to be a PDF file")
+	}
+}
+
+func TestBuiltinRendererRawCommands(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-builtin-renderer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// only page breaks are supported, and other commands are rendered as text
+	// rather than being dropped
+	testCases := map[string]string{
+		"Before.\n\n\\newpage\n\nAfter.\n":   "/Count 2",
+		"Before.\n\n\\clearpage\n\nAfter.\n": "/Count 2",
+		"Before.\n\n\\vfill\n\nAfter.\n":     "/Count 1",
+	}
+	for markdown, expected := range testCases {
+		input := path.Join(tempDir, "contract.md")
+		output := path.Join(tempDir, "contract.pdf")
+		if err := ioutil.WriteFile(input, []byte(markdown), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := contract.RenderBuiltinPDF(input, output, []string{tempDir}); err != nil {
+			t.Fatal(err)
+		}
+		if content := readTestFile(t, output); !strings.Contains(content, expected) {
+			t.Errorf("expected PDF rendered from %q to contain \"%s\"", markdown, expected)
+		}
+	}
+}
+
+func TestBuiltinRendererCharacterSet(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-builtin-renderer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	testCases := []struct {
+		markdown string
+		err      string
+	}{
+		{"# Café\n\nThe “Client” pays €100 – in full.\n", ""},
+		{"# Договор\n\nThe parties agree.\n", "'Д' (U+0414)"},
+		{"Payment terms → net 30 ✓\n", "'→' (U+2192), '✓' (U+2713)"},
+	}
+	for i, tc := range testCases {
+		input := path.Join(tempDir, "contract.md")
+		output := path.Join(tempDir, "contract.pdf")
+		os.Remove(output)
+		if err := ioutil.WriteFile(input, []byte(tc.markdown), 0644); err != nil {
+			t.Fatal(err)
+		}
+		err := contract.RenderBuiltinPDF(input, output, []string{tempDir})
+		if len(tc.err) == 0 {
+			if err != nil {
+				t.Errorf("case %d: expected rendering to succeed, but got: %v", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("case %d: expected an error about %s, but got: %v", i, tc.err, err)
+		}
+		if _, err := os.Stat(output); !os.IsNotExist(err) {
+			t.Errorf("case %d: expected no output to be written", i)
+		}
+	}
+}
@@ -1,11 +1,16 @@
 package themis_contract
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
@@ -25,71 +30,626 @@ type Cache interface {
 	// LocalPathForGitURL must return the local filesystem path where the
 	// contents of the specified Git repo will be cached.
 	LocalPathForGitURL(u *GitURL) string
+
+	// FromHash looks up the file with the given SHA256 hash in the cache's
+	// content-addressed store, verifying that its content still matches the
+	// hash. If a file name is given, only a copy stored under that name is
+	// returned. Returns an empty path if no intact copy of the file is stored.
+	FromHash(hash, name string) (string, error)
+
+	// StoreByHash copies the given file into the cache's content-addressed
+	// store, keyed by the SHA256 hash of its content and stored under its own
+	// file name. The given imports (if any) map the paths of files the file
+	// refers to (relative to its folder) to their hashes, so that they can be
+	// resolved alongside the stored copy. On success, returns the path to the
+	// stored copy of the file.
+	StoreByHash(file string, imports map[string]string) (string, error)
+
+	// StoredImports returns the imports recorded for the given file in the
+	// cache's content-addressed store (if any).
+	StoredImports(storedFile string) (map[string]string, error)
+
+	// Entries lists all of the entries currently in the cache.
+	Entries() ([]*CacheEntry, error)
+
+	// Evict removes the given entry from the cache.
+	Evict(entry *CacheEntry) error
+
+	// Verify checks the integrity of the given entry's content, returning an
+	// error if it is corrupted.
+	Verify(entry *CacheEntry) error
+
+	// Offline reports whether the cache only ever serves files it already
+	// has, without accessing the network.
+	Offline() bool
+}
+
+type CacheEntryKind string
+
+const (
+	GitCacheEntry  CacheEntryKind = "git"    // A clone of a Git repository.
+	WebCacheEntry  CacheEntryKind = "web"    // A file retrieved from the web.
+	HashCacheEntry CacheEntryKind = "sha256" // A file in the content-addressed store.
+)
+
+// CacheEntry describes a single entry in the cache.
+type CacheEntry struct {
+	Kind     CacheEntryKind `json:"kind"`
+	Location string         `json:"location"`       // The URL from which the entry was retrieved (or, for stored files, their hash).
+	Path     string         `json:"path"`           // The path to the entry in the local file system.
+	Size     int64          `json:"size"`           // The total size of the entry's files, in bytes.
+	LastUsed time.Time      `json:"last_used"`      // When the entry was last retrieved from the cache.
+	Hash     string         `json:"hash,omitempty"` // The hash recorded for the entry's content (if any).
+
+	key string // The path to the entry relative to the root of the cache.
+}
+
+// Matches checks whether this entry was cached from the given location. Git
+// URLs match their repository's entry, regardless of the path and ref, and
+// files in the content-addressed store match their hash.
+func (e *CacheEntry) Matches(loc string) bool {
+	switch e.Kind {
+	case GitCacheEntry:
+		u, err := ParseGitURL(loc)
+		if err != nil {
+			return false
+		}
+		return e.key == path.Join(string(GitCacheEntry), u.Host, u.Repo)
+	default:
+		return e.Location == loc
+	}
+}
+
+// The suffix appended to the path of a file cached from the web to obtain the
+// path to the file containing its cache metadata.
+const webCacheMetaSuffix = ".cache-meta.json"
+
+// The folder within the cache's root in which files are stored by their
+// SHA256 hashes.
+const contentStoreDir = "sha256"
+
+// The prefix of the names of temporary files written to the content-addressed
+// store.
+const contentStoreTempPrefix = ".tmp-"
+
+// The suffix appended to the path of a file in the content-addressed store to
+// obtain the path to the file recording its imports.
+const contentStoreImportsSuffix = ".imports.json"
+
+var sha256HashRegexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// FSCacheOptions allows us to configure how an FSCache makes use of the
+// network.
+type FSCacheOptions struct {
+	// How long files fetched from the web are considered fresh, during which
+	// time they are served from the cache without being revalidated. If zero,
+	// cached files are always revalidated.
+	TTL time.Duration
+	// If set, files are only ever served from the cache and the network is
+	// never touched.
+	Offline bool
 }
 
 // FSCache allows us to cache files and folders we've fetched from remote
 // sources. It caches them locally in the file system.
 type FSCache struct {
-	root string
+	root    string
+	ttl     time.Duration
+	offline bool
 }
 
 var _ Cache = &FSCache{}
 
+// webCacheMeta records the details of the response from which a file was
+// cached, so we can make conditional requests when revalidating the file.
+type webCacheMeta struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Fetched      time.Time `json:"fetched"`
+	Hash         string    `json:"hash,omitempty"` // The hash of the file as it was fetched.
+}
+
 // OpenFSCache will open an existing file cache at the given path in the file
-// system, or will create the relevant paths/files to facilitate the cache.
-func OpenFSCache(root string) (*FSCache, error) {
+// system, or will create the relevant paths/files to facilitate the cache. If
+// no options are given, cached files are always revalidated.
+func OpenFSCache(root string, opts *FSCacheOptions) (*FSCache, error) {
 	// ensure that the root of the cache folder exists
 	err := os.MkdirAll(root, 0755)
 	if err != nil {
 		return nil, err
 	}
+	if opts == nil {
+		opts = &FSCacheOptions{}
+	}
 	return &FSCache{
-		root: root,
+		root:    root,
+		ttl:     opts.TTL,
+		offline: opts.Offline,
 	}, nil
 }
 
+func (c *FSCache) Offline() bool {
+	return c.offline
+}
+
 func (c *FSCache) FromGit(u *GitURL) (string, error) {
 	log.Debug().Msgf("Looking up cached entries for Git URL: %s", u)
 	repoURL := u.RepoURL()
-	cachedRepoPath := path.Join(c.root, "git", u.Host, u.Repo)
+	cachedRepoPath := path.Join(c.root, string(GitCacheEntry), u.Host, u.Repo)
 	exists, err := dirExists(cachedRepoPath)
 	if err != nil {
 		return "", err
 	}
+	ref := "master"
+	if len(u.Ref) > 0 {
+		ref = u.Ref
+	}
 	if exists {
 		log.Debug().Msgf("Git repository %s is already cached at %s", repoURL, cachedRepoPath)
 	} else {
+		if c.offline {
+			return "", fmt.Errorf("Git repository %s has not been cached, so it is not available offline", repoURL)
+		}
 		log.Debug().Msgf("Git repository %s has not yet been cached", repoURL)
 		if err := gitClone(repoURL, cachedRepoPath); err != nil {
 			return "", err
 		}
 	}
-	ref := "master"
-	if len(u.Ref) > 0 {
-		ref = u.Ref
-	}
-	if err := gitFetchAndCheckout(repoURL, cachedRepoPath, ref); err != nil {
+	if c.offline {
+		log.Debug().Msgf("Offline: using cached copy of Git repository %s without fetching", repoURL)
+		if _, err := gitCheckout(repoURL, cachedRepoPath, ref); err != nil {
+			return "", fmt.Errorf("\"%s\" is not available offline: %s", ref, err)
+		}
+	} else if err := gitFetchAndCheckout(repoURL, cachedRepoPath, ref); err != nil {
 		return "", err
 	}
+	touchCacheEntry(cachedRepoPath)
 	return path.Join(cachedRepoPath, path.Join(strings.Split(u.Path, "/")...)), nil
 }
 
 // FromWeb attempts to fetch the file at the given URL, caching it locally in
-// the file system.
-// TODO: Implement caching (right now we always just fetch the file).
+// the file system. Cached files are served as-is while they are fresh (see
+// FSCacheOptions), and are otherwise revalidated using a conditional request.
+// If the server cannot be reached, a previously cached copy is served.
 func (c *FSCache) FromWeb(u *url.URL) (string, error) {
-	destFile := path.Join(c.root, "web", u.Host, path.Join(strings.Split(u.Path, "/")...))
-	if err := downloadFile(u, destFile); err != nil {
+	cachedFile, err := c.fromWeb(u)
+	if err != nil {
+		return "", err
+	}
+	touchCacheEntry(cachedFile)
+	return cachedFile, nil
+}
+
+func (c *FSCache) fromWeb(u *url.URL) (string, error) {
+	destFile := c.localPathForWebURL(u)
+	meta, err := readWebCacheMeta(destFile)
+	if err != nil {
+		return "", err
+	}
+	if c.offline {
+		if meta == nil {
+			// files cached without metadata (e.g. whose metadata was lost) are
+			// still better than nothing when offline
+			if _, err := os.Stat(destFile); err != nil {
+				return "", fmt.Errorf("%s has not been cached, so it is not available offline", u)
+			}
+			log.Warn().Msgf("Offline: using cached copy of %s, although its cache metadata is missing", u)
+			return destFile, nil
+		}
+		log.Debug().Msgf("Offline: using cached copy of %s (fetched %s)", u, meta.Fetched.Format(time.RFC3339))
+		return destFile, nil
+	}
+	var etag, lastModified string
+	if meta != nil {
+		if time.Since(meta.Fetched) < c.ttl {
+			log.Debug().Msgf("Using cached copy of %s (fetched %s)", u, meta.Fetched.Format(time.RFC3339))
+			return destFile, nil
+		}
+		etag, lastModified = meta.ETag, meta.LastModified
+	}
+	res, err := downloadFile(u, destFile, etag, lastModified)
+	if err != nil {
+		if _, ok := err.(*networkError); ok && meta != nil {
+			log.Warn().Msgf("Using cached copy of %s fetched %s: %s", u, meta.Fetched.Format(time.RFC3339), err)
+			return destFile, nil
+		}
+		return "", err
+	}
+	if meta == nil || !res.notModified {
+		hash, err := hashOfFile(destFile)
+		if err != nil {
+			return "", err
+		}
+		meta = &webCacheMeta{URL: u.String(), Hash: hash}
+	}
+	// servers need not repeat validators in "304 Not Modified" responses
+	if len(res.etag) > 0 || !res.notModified {
+		meta.ETag = res.etag
+	}
+	if len(res.lastModified) > 0 || !res.notModified {
+		meta.LastModified = res.lastModified
+	}
+	meta.Fetched = time.Now().UTC()
+	if err := writeWebCacheMeta(destFile, meta); err != nil {
 		return "", err
 	}
 	return destFile, nil
 }
 
+func (c *FSCache) localPathForWebURL(u *url.URL) string {
+	return path.Join(c.root, string(WebCacheEntry), u.Host, path.Join(strings.Split(u.Path, "/")...))
+}
+
 func (c *FSCache) LocalPathForGitURL(u *GitURL) string {
-	cachedRepoPath := path.Join(c.root, "git", u.Host, u.Repo)
+	cachedRepoPath := path.Join(c.root, string(GitCacheEntry), u.Host, u.Repo)
 	return path.Join(cachedRepoPath, path.Join(strings.Split(u.Path, "/")...))
 }
 
+// FromHash looks up the file with the given hash in the content-addressed
+// store, which keeps each file at "sha256/<hash>/<filename>" within the cache.
+// Since the same content may be stored under several file names, a file name
+// may be given to only look up the copy stored under that name. Stored files
+// whose content no longer matches their hash are evicted.
+func (c *FSCache) FromHash(hash, name string) (string, error) {
+	if !sha256HashRegexp.MatchString(hash) {
+		return "", fmt.Errorf("invalid SHA256 hash: \"%s\"", hash)
+	}
+	entryDir := path.Join(c.root, contentStoreDir, hash)
+	entries, err := ioutil.ReadDir(entryDir)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !isContentStoreFile(entry.Name()) {
+			continue
+		}
+		if len(name) > 0 && entry.Name() != name {
+			continue
+		}
+		storedFile := path.Join(entryDir, entry.Name())
+		actualHash, err := hashOfFile(storedFile)
+		if err != nil {
+			return "", err
+		}
+		if actualHash == hash {
+			log.Debug().Msgf("Found file with hash %s in cache: %s", hash, storedFile)
+			touchCacheEntry(entryDir)
+			return storedFile, nil
+		}
+		log.Warn().
+			Str("expected", hash).
+			Str("actual", actualHash).
+			Msgf("Evicting corrupted file from cache: %s", storedFile)
+		if err := os.Remove(storedFile); err != nil {
+			return "", err
+		}
+		_ = os.Remove(storedFile + contentStoreImportsSuffix)
+	}
+	// the entry is removed altogether once all of its files have been evicted
+	_ = os.Remove(entryDir)
+	return "", nil
+}
+
+func (c *FSCache) StoreByHash(file string, imports map[string]string) (string, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	hash := hashOfBytes(content)
+	storedFile, err := c.FromHash(hash, path.Base(file))
+	if err != nil {
+		return "", err
+	}
+	if len(storedFile) == 0 {
+		if storedFile, err = c.storeContent(hash, path.Base(file), content); err != nil {
+			return "", err
+		}
+		log.Debug().Msgf("Stored %s in cache as %s", file, storedFile)
+	}
+	if len(imports) > 0 {
+		importsContent, err := json.MarshalIndent(imports, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		if err := ioutil.WriteFile(storedFile+contentStoreImportsSuffix, importsContent, 0644); err != nil {
+			return "", err
+		}
+	}
+	return storedFile, nil
+}
+
+// StoredImports reads the imports recorded alongside the given file in the
+// content-addressed store. Files that aren't in the store have no recorded
+// imports.
+func (c *FSCache) StoredImports(storedFile string) (map[string]string, error) {
+	if !strings.HasPrefix(storedFile, path.Join(c.root, contentStoreDir)+"/") {
+		return nil, nil
+	}
+	content, err := ioutil.ReadFile(storedFile + contentStoreImportsSuffix)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	imports := make(map[string]string)
+	if err := json.Unmarshal(content, &imports); err != nil {
+		return nil, fmt.Errorf("failed to parse imports of stored file %s: %s", storedFile, err)
+	}
+	return imports, nil
+}
+
+// storeContent writes the given content to the content-addressed store under
+// the given hash and file name.
+func (c *FSCache) storeContent(hash, name string, content []byte) (string, error) {
+	entryDir := path.Join(c.root, contentStoreDir, hash)
+	if err := os.MkdirAll(entryDir, 0755); err != nil {
+		return "", err
+	}
+	// we write to a temporary file first so that interrupted writes never
+	// leave a partially written file in the store
+	tempFile, err := ioutil.TempFile(entryDir, contentStoreTempPrefix)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tempFile.Name())
+	_, err = tempFile.Write(content)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	storedFile := path.Join(entryDir, name)
+	if err := os.Rename(tempFile.Name(), storedFile); err != nil {
+		return "", err
+	}
+	return storedFile, nil
+}
+
+// isContentStoreFile checks whether the file with the given name in an entry
+// of the content-addressed store is a stored file (as opposed to a temporary
+// file or a record of a stored file's imports).
+func isContentStoreFile(name string) bool {
+	return !strings.HasPrefix(name, contentStoreTempPrefix) && !strings.HasSuffix(name, contentStoreImportsSuffix)
+}
+
+// Entries lists the Git repositories, web files and stored files in the cache.
+// An entry's last use is tracked by way of its modification time.
+func (c *FSCache) Entries() ([]*CacheEntry, error) {
+	entries := make([]*CacheEntry, 0)
+	// Git repositories are nested at varying depths (depending on their
+	// paths), so we look for their ".git" folders
+	gitRoot := path.Join(c.root, string(GitCacheEntry))
+	err := walkCache(gitRoot, func(p string, fi os.FileInfo) (bool, error) {
+		if !fi.IsDir() {
+			return false, nil
+		}
+		if _, err := os.Stat(path.Join(p, ".git")); err != nil {
+			return false, nil
+		}
+		entry, err := c.cacheEntry(GitCacheEntry, p)
+		if err != nil {
+			return false, err
+		}
+		entry.Location = gitRemoteURL(p)
+		if len(entry.Location) == 0 {
+			entry.Location = strings.TrimPrefix(entry.key, string(GitCacheEntry)+"/")
+		}
+		entries = append(entries, entry)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	webRoot := path.Join(c.root, string(WebCacheEntry))
+	err = walkCache(webRoot, func(p string, fi os.FileInfo) (bool, error) {
+		if fi.IsDir() || strings.HasSuffix(p, webCacheMetaSuffix) {
+			return false, nil
+		}
+		entry, err := c.cacheEntry(WebCacheEntry, p)
+		if err != nil {
+			return false, err
+		}
+		meta, err := readWebCacheMeta(p)
+		if err != nil {
+			return false, err
+		}
+		if meta != nil {
+			entry.Location = meta.URL
+			entry.Hash = meta.Hash
+		} else {
+			entry.Location = strings.TrimPrefix(entry.key, string(WebCacheEntry)+"/")
+		}
+		entries = append(entries, entry)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	storedFiles, err := ioutil.ReadDir(path.Join(c.root, contentStoreDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, fi := range storedFiles {
+		if !fi.IsDir() || !sha256HashRegexp.MatchString(fi.Name()) {
+			continue
+		}
+		entry, err := c.cacheEntry(HashCacheEntry, path.Join(c.root, contentStoreDir, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		entry.Location = fi.Name()
+		entry.Hash = fi.Name()
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// cacheEntry creates an entry for the file or folder at the given path,
+// computing its total size.
+func (c *FSCache) cacheEntry(kind CacheEntryKind, entryPath string) (*CacheEntry, error) {
+	fi, err := os.Stat(entryPath)
+	if err != nil {
+		return nil, err
+	}
+	key, err := filepath.Rel(c.root, entryPath)
+	if err != nil {
+		return nil, err
+	}
+	entry := &CacheEntry{
+		Kind:     kind,
+		Path:     entryPath,
+		LastUsed: fi.ModTime(),
+		key:      filepath.ToSlash(key),
+	}
+	if !fi.IsDir() {
+		entry.Size = fi.Size()
+		return entry, nil
+	}
+	err = filepath.Walk(entryPath, func(_ string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			entry.Size += fi.Size()
+		}
+		return nil
+	})
+	return entry, err
+}
+
+// Evict removes the given entry (along with its metadata, if any) from the
+// cache, as well as any folders left empty as a result.
+func (c *FSCache) Evict(entry *CacheEntry) error {
+	log.Debug().Msgf("Evicting %s from cache: %s", entry.Location, entry.Path)
+	if err := os.RemoveAll(entry.Path); err != nil {
+		return err
+	}
+	if entry.Kind == WebCacheEntry {
+		if err := os.Remove(entry.Path + webCacheMetaSuffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	// clean up empty parent folders within the cache
+	for dir := path.Dir(entry.Path); len(dir) > len(c.root); dir = path.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// Verify re-hashes the given entry's content and compares it to the entry's
+// recorded hash. Git repositories are checked using "git fsck". Files from the
+// web that were cached without recording their hash cannot be verified.
+func (c *FSCache) Verify(entry *CacheEntry) error {
+	switch entry.Kind {
+	case GitCacheEntry:
+		return gitFsck(entry.Path)
+	case WebCacheEntry:
+		if len(entry.Hash) == 0 {
+			return nil
+		}
+		return verifyFileHash(entry.Path, entry.Hash)
+	case HashCacheEntry:
+		files, err := ioutil.ReadDir(entry.Path)
+		if err != nil {
+			return err
+		}
+		verified := 0
+		for _, fi := range files {
+			if fi.IsDir() || !isContentStoreFile(fi.Name()) {
+				continue
+			}
+			if err := verifyFileHash(path.Join(entry.Path, fi.Name()), entry.Hash); err != nil {
+				return err
+			}
+			verified++
+		}
+		if verified == 0 {
+			return fmt.Errorf("no stored file found in %s", entry.Path)
+		}
+		return nil
+	}
+	return fmt.Errorf("unrecognized cache entry kind: %s", entry.Kind)
+}
+
+func verifyFileHash(file, expectedHash string) error {
+	hash, err := hashOfFile(file)
+	if err != nil {
+		return err
+	}
+	if hash != expectedHash {
+		return fmt.Errorf("hash mismatch on %s (expected %s, but got %s)", file, expectedHash, hash)
+	}
+	return nil
+}
+
+// walkCache walks the given folder within the cache (if it exists), calling fn
+// for each file and folder. If fn returns true for a folder, the folder's
+// contents are skipped.
+func walkCache(root string, fn func(p string, fi os.FileInfo) (bool, error)) error {
+	err := filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		skip, err := fn(p, fi)
+		if err != nil {
+			return err
+		}
+		if skip && fi.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// touchCacheEntry records that the cache entry at the given path has just been
+// used by updating its modification time.
+func touchCacheEntry(entryPath string) {
+	now := time.Now()
+	if err := os.Chtimes(entryPath, now, now); err != nil {
+		log.Debug().Msgf("Failed to record use of cache entry %s: %s", entryPath, err)
+	}
+}
+
+// readWebCacheMeta reads the cache metadata for the given cached file. If the
+// file has not been cached, or was cached without metadata, it returns nil.
+func readWebCacheMeta(cachedFile string) (*webCacheMeta, error) {
+	if _, err := os.Stat(cachedFile); os.IsNotExist(err) {
+		return nil, nil
+	}
+	content, err := ioutil.ReadFile(cachedFile + webCacheMetaSuffix)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	meta := &webCacheMeta{}
+	if err := json.Unmarshal(content, meta); err != nil {
+		return nil, fmt.Errorf("failed to parse cache metadata for %s: %s", cachedFile, err)
+	}
+	return meta, nil
+}
+
+func writeWebCacheMeta(cachedFile string, meta *webCacheMeta) error {
+	content, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(cachedFile+webCacheMetaSuffix, append(content, '\n'), 0644)
+}
+
 func dirExists(d string) (bool, error) {
 	stat, err := os.Stat(d)
 	if os.IsNotExist(err) {
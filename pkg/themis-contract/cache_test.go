@@ -2,7 +2,15 @@ package themis_contract_test
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
 
 	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
 )
@@ -29,6 +37,34 @@ func (c *mockCache) LocalPathForGitURL(u *contract.GitURL) string {
 	return path
 }
 
+func (c *mockCache) FromHash(hash, name string) (string, error) {
+	return "", nil
+}
+
+func (c *mockCache) StoreByHash(file string, imports map[string]string) (string, error) {
+	return file, nil
+}
+
+func (c *mockCache) StoredImports(storedFile string) (map[string]string, error) {
+	return nil, nil
+}
+
+func (c *mockCache) Entries() ([]*contract.CacheEntry, error) {
+	return nil, nil
+}
+
+func (c *mockCache) Evict(entry *contract.CacheEntry) error {
+	return nil
+}
+
+func (c *mockCache) Verify(entry *contract.CacheEntry) error {
+	return nil
+}
+
+func (c *mockCache) Offline() bool {
+	return false
+}
+
 func (c *mockCache) entry(path string) (string, error) {
 	path, ok := c.successes[path]
 	if !ok {
@@ -36,3 +72,334 @@ func (c *mockCache) entry(path string) (string, error) {
 	}
 	return path, nil
 }
+
+func TestFSCacheFromWeb(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	requests, revalidations := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidations++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, "# Contract")
+	}))
+	u, err := url.Parse(server.URL + "/path/to/contract.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offlineCache, err := contract.OpenFSCache(tempDir, &contract.FSCacheOptions{Offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := offlineCache.FromWeb(u); err == nil {
+		t.Error("expected an error when fetching an uncached file offline")
+	}
+
+	cache, err := contract.OpenFSCache(tempDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		cachedFile, err := cache.FromWeb(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if content := readTestFile(t, cachedFile); content != "# Contract" {
+			t.Errorf("expected cached file content to be \"# Contract\", but got \"%s\"", content)
+		}
+	}
+	if requests != 2 || revalidations != 1 {
+		t.Errorf("expected 2 requests, one of them a revalidation, but got %d request(s) and %d revalidation(s)", requests, revalidations)
+	}
+
+	freshCache, err := contract.OpenFSCache(tempDir, &contract.FSCacheOptions{TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := freshCache.FromWeb(u); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Errorf("expected fresh file to be served from the cache, but got %d request(s)", requests)
+	}
+
+	// once the server is gone, we should still have access to cached files
+	server.Close()
+	for _, c := range []*contract.FSCache{cache, offlineCache} {
+		if _, err := c.FromWeb(u); err != nil {
+			t.Errorf("expected cached file to be available without the server, but got: %s", err)
+		}
+	}
+	// even if the cached file's metadata is missing
+	cachedFile, err := offlineCache.FromWeb(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(cachedFile + ".cache-meta.json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := offlineCache.FromWeb(u); err != nil {
+		t.Errorf("expected cached file without metadata to be available offline, but got: %s", err)
+	}
+}
+
+func TestOfflineDhallImports(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{ client = "Acme Corp" }`)
+	}))
+	defer server.Close()
+	paramsFile := path.Join(tempDir, "params.dhall")
+	if err := ioutil.WriteFile(paramsFile, []byte(server.URL+"/params.dhall"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	uncachedFile := path.Join(tempDir, "uncached.dhall")
+	if err := ioutil.WriteFile(uncachedFile, []byte(server.URL+"/uncached.dhall"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cacheRoot := path.Join(tempDir, "cache")
+	cache, err := contract.OpenFSCache(cacheRoot, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offlineCache, err := contract.OpenFSCache(cacheRoot, &contract.FSCacheOptions{Offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := contract.DhallToJSON(paramsFile, cache); err != nil {
+		t.Fatal(err)
+	}
+
+	// remote imports are served from the cache when offline, and otherwise
+	// fail without touching the network
+	fetched := requests
+	content, err := contract.DhallToJSON(paramsFile, offlineCache)
+	if err != nil {
+		t.Fatalf("expected cached remote Dhall import to be available offline, but got: %s", err)
+	}
+	if string(content) != `{"client":"Acme Corp"}` {
+		t.Errorf("unexpected evaluated Dhall params: %s", content)
+	}
+	_, err = contract.DhallToJSON(uncachedFile, offlineCache)
+	if err == nil || !strings.Contains(err.Error(), "not available offline") {
+		t.Errorf("expected uncached remote Dhall import to be reported as not available offline, but got: %v", err)
+	}
+	if requests != fetched {
+		t.Errorf("expected remote Dhall imports not to be fetched when offline, but %d request(s) were made", requests-fetched)
+	}
+}
+
+func TestFSCacheContentAddressedStore(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	requests := 0
+	fileServer := http.FileServer(http.Dir(path.Join(tempDir, "server")))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fileServer.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	paramsFile := path.Join(tempDir, "server", "params.json")
+	if err := writeTestFiles([]string{paramsFile}, `{"client": "Acme Corp"}`); err != nil {
+		t.Fatal(err)
+	}
+	paramsURL := server.URL + "/params.json"
+	cacheRoot := path.Join(tempDir, "cache")
+	cache, err := contract.OpenFSCache(cacheRoot, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := contract.NewTestContext(cache, contract.NewTestProfile("test", "", nil))
+	ref, err := contract.ResolveFileRef(paramsURL, "", false, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storedFile, err := cache.FromHash(ref.Hash, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedStoredFile := path.Join(cacheRoot, "sha256", ref.Hash, "params.json")
+	if storedFile != expectedStoredFile {
+		t.Fatalf("expected fetched file to be stored at %s, but got \"%s\"", expectedStoredFile, storedFile)
+	}
+
+	// pinned references resolve to the stored file without being fetched,
+	// even once they have changed at their location
+	writeReplaced(t, paramsFile, "Acme Corp", "Widgets Inc")
+	modified := time.Now().Add(time.Minute)
+	if err := os.Chtimes(paramsFile, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	fetched := requests
+	pinned, err := contract.ResolveFileRef(paramsURL, ref.Hash, true, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := pinned.ReadAll(); content != `{"client": "Acme Corp"}` {
+		t.Errorf("expected pinned reference to resolve to the stored file, but got content: %s", content)
+	}
+	if requests != fetched {
+		t.Errorf("expected pinned reference to be resolved without accessing the network, but %d request(s) were made", requests-fetched)
+	}
+	if _, err := contract.ResolveFileRef(paramsURL, ref.Hash, false, ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// stored files are only served under the same file name as at their
+	// location
+	server.Close()
+	if _, err := contract.ResolveFileRef(server.URL+"/params.yaml", ref.Hash, true, ctx); err == nil {
+		t.Error("expected pinned reference not to resolve to a stored file with a different file name")
+	}
+
+	// corrupted files are evicted from the store
+	if err := ioutil.WriteFile(storedFile, []byte("corrupted"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if storedFile, err := cache.FromHash(ref.Hash, ""); err != nil || len(storedFile) > 0 {
+		t.Errorf("expected corrupted file to be evicted from the store, but got \"%s\" (error: %v)", storedFile, err)
+	}
+	if _, err := contract.ResolveFileRef(paramsURL, ref.Hash, true, ctx); err == nil {
+		t.Error("expected pinned reference not to resolve once the stored file was evicted and its location is unreachable")
+	}
+}
+
+func TestPinnedGitDhallFileRef(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	repoDir := path.Join(tempDir, "repo")
+	files := map[string]string{
+		"params.dhall":           `let types = ./types/params.dhall in { client = "Acme Corp", rate = ../rates.dhall } : types.Params`,
+		"types/params.dhall":     `{ Params = { client : Text, rate : Natural } }`,
+		"../rates.dhall":         `./rates/default.dhall`,
+		"../rates/default.dhall": `100`,
+	}
+	for filename, content := range files {
+		if err := writeTestFiles([]string{path.Join(repoDir, filename)}, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cacheRoot := path.Join(tempDir, "cache")
+	fsCache, err := contract.OpenFSCache(cacheRoot, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := &gitUpstreamCache{FSCache: fsCache, repoDir: repoDir}
+	ctx := contract.NewTestContext(cache, contract.NewTestProfile("test", "", nil))
+	paramsURL := "git://github.com:somewhere/templates.git/params.dhall"
+	ref, err := contract.ResolveFileRef(paramsURL, "", false, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pinned files are served from the content-addressed store, along with
+	// the files they import, even once those files have changed
+	writeReplaced(t, path.Join(tempDir, "rates", "default.dhall"), "100", "200")
+	pinned, err := contract.ResolveFileRef(paramsURL, ref.Hash, true, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(pinned.Dir(), path.Join(cacheRoot, "sha256")) {
+		t.Errorf("expected pinned reference to resolve to the content-addressed store, but got %s", pinned.Dir())
+	}
+	content, err := contract.DhallToJSON(path.Join(pinned.Dir(), pinned.Filename()), cache)
+	if err != nil {
+		t.Fatalf("expected pinned Dhall file's relative imports to be resolved, but got: %s", err)
+	}
+	if string(content) != `{"client":"Acme Corp","rate":100}` {
+		t.Errorf("unexpected evaluated Dhall params: %s", content)
+	}
+}
+
+func TestFSCacheEntries(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "# Contract")
+	}))
+	defer server.Close()
+	templateURL := server.URL + "/templates/contract.md"
+	cacheRoot := path.Join(tempDir, "cache")
+	cache, err := contract.OpenFSCache(cacheRoot, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := contract.NewTestContext(cache, contract.NewTestProfile("test", "", nil))
+	if _, err := contract.ResolveFileRef(templateURL, "", false, ctx); err != nil {
+		t.Fatal(err)
+	}
+	repoPath := path.Join(cacheRoot, "git", "github.com", "somewhere", "templates.git")
+	if err := writeTestFiles([]string{path.Join(repoPath, "contract.md")}, "# Contract"); err != nil {
+		t.Fatal(err)
+	}
+	runTestGit(t, repoPath, "init")
+	runTestGit(t, repoPath, "add", ".")
+	runTestGit(t, repoPath, "commit", "-m", "Add template")
+
+	entries, err := cache.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := make(map[contract.CacheEntryKind]*contract.CacheEntry)
+	for _, entry := range entries {
+		kinds[entry.Kind] = entry
+		if err := cache.Verify(entry); err != nil {
+			t.Errorf("expected cache entry %s to pass verification, but got: %s", entry.Location, err)
+		}
+	}
+	if len(entries) != 3 || len(kinds) != 3 {
+		t.Fatalf("expected one Git, web and stored cache entry each, but got %d entries: %v", len(entries), kinds)
+	}
+	gitEntry, webEntry := kinds[contract.GitCacheEntry], kinds[contract.WebCacheEntry]
+	if !gitEntry.Matches("git://github.com:somewhere/templates.git/contract.md#main") {
+		t.Error("expected Git URL to match cached repository")
+	}
+	if !webEntry.Matches(templateURL) || webEntry.Size != int64(len("# Contract")) {
+		t.Errorf("expected web cache entry for %s with size %d, but got %s with size %d", templateURL, len("# Contract"), webEntry.Location, webEntry.Size)
+	}
+
+	if err := ioutil.WriteFile(webEntry.Path, []byte("corrupted"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Verify(webEntry); err == nil {
+		t.Error("expected corrupted web cache entry to fail verification")
+	}
+	for _, entry := range entries {
+		if err := cache.Evict(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if entries, err := cache.Entries(); err != nil || len(entries) > 0 {
+		t.Errorf("expected cache to be empty after evicting all entries, but got %d entries (error: %v)", len(entries), err)
+	}
+	if _, err := os.Stat(path.Join(cacheRoot, "web")); !os.IsNotExist(err) {
+		t.Error("expected empty folders to be removed from the cache")
+	}
+}
@@ -0,0 +1,76 @@
+package themis_contract_test
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/philandstuff/dhall-golang/v6/binary"
+	"github.com/philandstuff/dhall-golang/v6/core"
+	"github.com/philandstuff/dhall-golang/v6/imports"
+	"github.com/philandstuff/dhall-golang/v6/parser"
+	"github.com/philandstuff/dhall-golang/v6/term"
+)
+
+// Contracts pin the Themis Contract Dhall package by its hash, so published
+// versions of the package must never change.
+var publishedConfigPackages = map[string]string{
+	"config/package.dhall":    "016b3829eaee279f2ce7a740a974f1ac75758893c42d220865a487c35ff9a890",
+	"config/v2/package.dhall": "c4e3d0c755d136acec453356153549dc1518ede8198aadf486c706c6e6e7c4b7",
+	"config/v3/package.dhall": "c5e62a9348161dc808a068ff18d65f82e2f9b43565be1c6f7d52d768b70691fc",
+	"config/v4/package.dhall": "5403cb2ee808a07a9f78ff22e492a2fd60a320a01dd39c1a999f5158322e441a",
+	"config/v5/package.dhall": "cafc51206871f47e56cff9cb9000d4cd73637149864b72d990e0d12fcafc78be",
+}
+
+func TestPublishedConfigPackagesUnchanged(t *testing.T) {
+	for pkg, expected := range publishedConfigPackages {
+		if actual := configPackageHash(t, pkg); actual != expected {
+			t.Errorf("expected %s to have hash sha256:%s, but it has sha256:%s (changes must go into a new version of the package)", pkg, expected, actual)
+		}
+	}
+}
+
+// The contract template must pin the latest version of the package by its
+// current hash.
+func TestContractTemplatePackageHash(t *testing.T) {
+	content, err := ioutil.ReadFile("../../assets/templates/contract.dhall.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	match := regexp.MustCompile(`/master/(config/\S*package\.dhall)\s+sha256:([0-9a-f]{64})`).FindSubmatch(content)
+	if match == nil {
+		t.Fatal("expected contract template to import the Dhall package with a hash")
+	}
+	if actual := configPackageHash(t, string(match[1])); actual != string(match[2]) {
+		t.Errorf("expected contract template to pin %s with hash sha256:%s, but it pins sha256:%s", match[1], actual, match[2])
+	}
+	if _, published := publishedConfigPackages[string(match[1])]; !published {
+		t.Errorf("expected %s to be listed as a published package version", match[1])
+	}
+}
+
+func configPackageHash(t *testing.T, pkg string) string {
+	filename, err := filepath.Abs(filepath.Join("../..", pkg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, err := parser.ParseFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := imports.Load(expr, term.LocalFile(filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := core.TypeOf(resolved); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := binary.SemanticHash(core.Eval(resolved))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// skip the multihash prefix
+	return hex.EncodeToString(hash[2:])
+}
@@ -5,7 +5,9 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/rakyll/statik/fs"
 	"github.com/rs/zerolog/log"
@@ -16,19 +18,27 @@ import (
 // TODO: Create a file reference resolver interface member to allow for mocking and better testing.
 // TODO: Look at splitting this up as per TODO on InitContext.
 type Context struct {
-	home            string          // The path to the Themis Contract home folder.
-	cache           Cache           // The cache we're currently using for storing files we retrieve from remote sources.
-	fs              http.FileSystem // For reading static resources pre-built into our binary.
-	profileDB       *ProfileDB      // Our local database of profiles.
-	sigDB           *SignatureDB    // Our local database of signatures.
-	autoCommit      bool            // Should we automatically commit changes as we update the contract?
-	autoPushChanges bool            // Should we automatically push local commits as we update the contract?
+	home            string            // The path to the Themis Contract home folder.
+	cache           Cache             // The cache we're currently using for storing files we retrieve from remote sources.
+	fs              http.FileSystem   // For reading static resources pre-built into our binary.
+	profileDB       *ProfileDB        // Our local database of profiles.
+	sigDB           *SignatureDB      // Our local database of signatures.
+	autoCommit      bool              // Should we automatically commit changes as we update the contract?
+	autoPushChanges bool              // Should we automatically push local commits as we update the contract?
+	allowStaleSigs  bool              // Should we render signatures that were applied to older versions of a contract?
+	outputFormat    OutputFormat      // The format to which to compile contracts (inferred from the output file name if not set).
+	renderer        string            // The name of the renderer to use when compiling contracts (overrides the profile's renderer).
+	rendererOpts    map[string]string // Renderer-specific options, keyed by "renderer.name" (overriding those in the profile).
+	toolVersion     string            // The version of Themis Contract in use (embedded in compiled contracts).
+	attestation     bool              // Should we write a build attestation alongside compiled contracts?
+	draftWatermark  bool              // Should we watermark compiled contracts that have not been signed by all signatories?
 }
 
 // InitContext creates a contracting context using the given Themis Contract
-// home directory (usually located at `~/.themis/contract`).
+// home directory (usually located at `~/.themis/contract`). The given cache
+// options (if any) configure how remote files are cached.
 // TODO: Perhaps this, or parts of this, should exist as its own standalone CLI command? e.g. "themis-contract init"
-func InitContext(home string, autoCommit, autoPush bool) (*Context, error) {
+func InitContext(home string, autoCommit, autoPush bool, cacheOpts *FSCacheOptions) (*Context, error) {
 	if err := os.MkdirAll(home, 0755); err != nil {
 		return nil, fmt.Errorf("failed to initialize Themis Contract home directory \"%s\": %s", home, err)
 	}
@@ -47,7 +57,7 @@ func InitContext(home string, autoCommit, autoPush bool) (*Context, error) {
 		return nil, err
 	}
 	// gain access to our filesystem-based cache
-	cache, err := OpenFSCache(path.Join(home, "cache"))
+	cache, err := OpenFSCache(path.Join(home, "cache"), cacheOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open local cache: %s", err)
 	}
@@ -67,6 +77,8 @@ func InitContext(home string, autoCommit, autoPush bool) (*Context, error) {
 		sigDB:           sigDB,
 		autoCommit:      autoCommit,
 		autoPushChanges: autoPush,
+		attestation:     true,
+		draftWatermark:  true,
 	}, nil
 }
 
@@ -76,6 +88,74 @@ func (ctx *Context) WithAutoPush(autoPush bool) *Context {
 	return &dupCtx
 }
 
+// WithOutputFormat returns a copy of this context that compiles contracts to
+// the given output format. If no output format is specified, it is inferred
+// from the output file's extension.
+func (ctx *Context) WithOutputFormat(format OutputFormat) *Context {
+	dupCtx := *ctx
+	dupCtx.outputFormat = format
+	return &dupCtx
+}
+
+// WithRenderer returns a copy of this context that compiles contracts using
+// the renderer with the given name, regardless of the active profile's
+// renderer. If no name is given, the active profile's renderer is used.
+func (ctx *Context) WithRenderer(name string) *Context {
+	dupCtx := *ctx
+	dupCtx.renderer = name
+	return &dupCtx
+}
+
+// WithRendererOptions returns a copy of this context that passes the given
+// options through to the renderer when compiling contracts. Options are keyed
+// by "renderer.name", and are only passed to the renderer they're prefixed
+// with. These options take precedence over the same options in the active
+// profile.
+func (ctx *Context) WithRendererOptions(opts map[string]string) *Context {
+	dupCtx := *ctx
+	dupCtx.rendererOpts = opts
+	return &dupCtx
+}
+
+// WithToolVersion returns a copy of this context that records the given
+// version of Themis Contract in the provenance metadata of compiled contracts.
+func (ctx *Context) WithToolVersion(version string) *Context {
+	dupCtx := *ctx
+	dupCtx.toolVersion = version
+	return &dupCtx
+}
+
+// WithAttestation returns a copy of this context that either writes or skips
+// writing build attestations alongside compiled contracts.
+func (ctx *Context) WithAttestation(attestation bool) *Context {
+	dupCtx := *ctx
+	dupCtx.attestation = attestation
+	return &dupCtx
+}
+
+// WithDraftWatermark returns a copy of this context that either applies or
+// suppresses the draft watermark on compiled contracts that have not yet been
+// signed by all signatories.
+func (ctx *Context) WithDraftWatermark(watermark bool) *Context {
+	dupCtx := *ctx
+	dupCtx.draftWatermark = watermark
+	return &dupCtx
+}
+
+// WithStaleSignatures returns a copy of this context that either allows or
+// prevents the rendering of signatures that were applied to an older version of
+// a contract.
+func (ctx *Context) WithStaleSignatures(allow bool) *Context {
+	dupCtx := *ctx
+	dupCtx.allowStaleSigs = allow
+	return &dupCtx
+}
+
+// Cache returns the cache in which we store local copies of remote files.
+func (ctx *Context) Cache() Cache {
+	return ctx.cache
+}
+
 func (ctx *Context) ActiveProfile() *Profile {
 	return ctx.profileDB.activeProfile
 }
@@ -88,6 +168,9 @@ func (ctx *Context) UseProfile(id string) (*Profile, error) {
 // profile.
 func (ctx *Context) CurSignature() (*Signature, error) {
 	activeProfile := ctx.ActiveProfile()
+	if activeProfile == nil {
+		return nil, fmt.Errorf("no profile currently active (use \"themis-contract use\" to select one)")
+	}
 	if len(activeProfile.SignatureID) == 0 {
 		return nil, fmt.Errorf("no signature associated with current profile (\"%s\")", activeProfile.id)
 	}
@@ -117,15 +200,28 @@ func (ctx *Context) AddProfile(name, sigID, contractsRepo string) (*Profile, err
 		return nil, err
 	}
 	// now copy over the default profile configuration files
+	if err := ctx.ensureProfileResources(profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// ensureProfileResources copies any of the default profile configuration
+// files that are missing from the given profile's folder (e.g. the pandoc
+// defaults for output formats that were added after the profile was created).
+func (ctx *Context) ensureProfileResources(profile *Profile) error {
 	profileFiles := []string{
-		"/pandoc/pandoc-defaults.yaml",
 		"/pandoc/header-includes.tex",
 		"/pandoc/include-before.tex",
+		"/pandoc/reproducible.tex",
+	}
+	for _, f := range OutputFormats {
+		profileFiles = append(profileFiles, "/pandoc/"+f.pandocDefaultsFile())
 	}
 	if err := copyStaticResources(profileFiles, profile.path, false, ctx.fs); err != nil {
-		return nil, fmt.Errorf("failed to copy default profile configuration files: %s", err)
+		return fmt.Errorf("failed to copy default profile configuration files: %s", err)
 	}
-	return profile, nil
+	return nil
 }
 
 // SetProfileParam will attempt to set the given property of the specified
@@ -137,6 +233,14 @@ func (ctx *Context) SetProfileParam(profile *Profile, param, val string) error {
 		return ctx.setProfileSignatureID(profile, val)
 	case string(ProfileContractsRepo):
 		return ctx.setProfileContractsRepo(profile, val)
+	case string(ProfileSSHKey):
+		return ctx.setProfileSSHKey(profile, val)
+	case string(ProfileSSHAllowedSigners):
+		return ctx.setProfileSSHAllowedSigners(profile, val)
+	case string(ProfileRenderer):
+		return ctx.setProfileRenderer(profile, val)
+	case string(ProfileRendererOpt):
+		return ctx.setProfileRendererOpt(profile, val)
 	}
 	return fmt.Errorf("unrecognized parameter \"%s\"", param)
 }
@@ -161,6 +265,106 @@ func (ctx *Context) setProfileContractsRepo(profile *Profile, contractsRepo stri
 	return nil
 }
 
+func (ctx *Context) setProfileSSHKey(profile *Profile, keyFile string) error {
+	// if we're unsetting the SSH key
+	if len(keyFile) == 0 {
+		profile.SSHKey = ""
+		return nil
+	}
+	absKeyFile, err := sshKeyPath(keyFile)
+	if err != nil {
+		return err
+	}
+	profile.SSHKey = absKeyFile
+	return nil
+}
+
+func (ctx *Context) setProfileSSHAllowedSigners(profile *Profile, allowedSignersFile string) error {
+	// if we're unsetting the allowed signers file
+	if len(allowedSignersFile) == 0 {
+		profile.SSHAllowedSigners = ""
+		return nil
+	}
+	absAllowedSignersFile, err := filepath.Abs(allowedSignersFile)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(absAllowedSignersFile); err != nil {
+		return fmt.Errorf("cannot access allowed signers file %s: %s", absAllowedSignersFile, err)
+	}
+	profile.SSHAllowedSigners = absAllowedSignersFile
+	return nil
+}
+
+func (ctx *Context) setProfileRenderer(profile *Profile, name string) error {
+	// make sure the renderer exists (unless we're unsetting it)
+	if len(name) > 0 {
+		if _, err := rendererByName(name); err != nil {
+			return err
+		}
+	}
+	profile.Renderer = name
+	return nil
+}
+
+// setProfileRendererOpt sets a renderer option of the form
+// "renderer.name=value", where "renderer" is the name of the renderer to which
+// the option applies. If the value is empty, the option is removed.
+func (ctx *Context) setProfileRendererOpt(profile *Profile, opt string) error {
+	parts := strings.SplitN(opt, "=", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 {
+		return fmt.Errorf("renderer options must be of the form \"renderer.name=value\", but got \"%s\"", opt)
+	}
+	key, val := parts[0], parts[1]
+	rendererName, _, err := splitRendererOpt(key)
+	if err != nil {
+		return err
+	}
+	if len(val) == 0 {
+		delete(profile.RendererOpts, key)
+		return nil
+	}
+	if _, err := rendererByName(rendererName); err != nil {
+		return err
+	}
+	if profile.RendererOpts == nil {
+		profile.RendererOpts = make(map[string]string)
+	}
+	profile.RendererOpts[key] = val
+	return nil
+}
+
+// rendererOptions merges the options for the renderer with the given name
+// from the given profile with those from this context. Options are keyed by
+// "renderer.name", and only the options for the given renderer are returned
+// (keyed by their names alone).
+func (ctx *Context) rendererOptions(profile *Profile, rendererName string) (map[string]string, error) {
+	opts := make(map[string]string)
+	for _, src := range []map[string]string{profile.RendererOpts, ctx.rendererOpts} {
+		for key, val := range src {
+			optRenderer, name, err := splitRendererOpt(key)
+			if err != nil {
+				return nil, err
+			}
+			if optRenderer == rendererName {
+				opts[name] = val
+			}
+		}
+	}
+	return opts, nil
+}
+
+// splitRendererOpt splits a renderer option key of the form "renderer.name"
+// into the name of the renderer to which the option applies and the name of
+// the option itself.
+func splitRendererOpt(key string) (string, string, error) {
+	parts := strings.SplitN(key, ".", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return "", "", fmt.Errorf("renderer options must be prefixed with the name of their renderer (e.g. \"pandoc.pdf-engine\"), but got \"%s\"", key)
+	}
+	return parts[0], parts[1], nil
+}
+
 func (ctx *Context) RemoveProfile(id string) error {
 	return ctx.profileDB.remove(id)
 }
@@ -197,8 +401,11 @@ func (ctx *Context) GetSignatureByID(id string) (*Signature, error) {
 	return sig, nil
 }
 
-func (ctx *Context) AddSignature(name, email, sigImage string) (*Signature, error) {
-	return ctx.sigDB.newSignature(name, email, sigImage)
+// AddSignature will create a new signature with the given name and e-mail
+// address. At least one of a signature image, a GPG key ID or an SSH private
+// key file must be supplied.
+func (ctx *Context) AddSignature(name, email, sigImage, keyID, sshKey string) (*Signature, error) {
+	return ctx.sigDB.newSignature(name, email, sigImage, keyID, sshKey)
 }
 
 // RemoveSignature will attempt to delete the signature with the given ID. If
@@ -238,6 +445,26 @@ func (ctx *Context) SetSignatureParam(sig *Signature, param, val string) error {
 		return nil
 	case string(SignatureImage):
 		return ctx.setSignatureImage(sig, val)
+	case string(SignatureKeyID):
+		if len(val) == 0 && len(sig.ImagePath) == 0 && len(sig.SSHKey) == 0 {
+			return fmt.Errorf("cannot remove the only signing key from a signature without an image")
+		}
+		sig.KeyID = val
+		return nil
+	case string(SignatureSSHKey):
+		if len(val) == 0 {
+			if len(sig.ImagePath) == 0 && len(sig.KeyID) == 0 {
+				return fmt.Errorf("cannot remove the only signing key from a signature without an image")
+			}
+			sig.SSHKey = ""
+			return nil
+		}
+		keyFile, err := sshKeyPath(val)
+		if err != nil {
+			return err
+		}
+		sig.SSHKey = keyFile
+		return nil
 	}
 	return fmt.Errorf("unrecognized parameter \"%s\"", param)
 }
@@ -6,14 +6,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"text/template"
 
 	"github.com/BurntSushi/toml"
-	"github.com/alexkappa/mustache"
 	"github.com/rs/zerolog/log"
 	"gopkg.in/yaml.v3"
 
@@ -30,6 +29,19 @@ const (
 	TOMLType  FileType = "toml"
 )
 
+// The names of the parameters describing the contract's signing status, which
+// are made available to templates in addition to the contract's own
+// parameters.
+const (
+	paramIsFullySigned       = "is_fully_signed"
+	paramUnsignedSignatories = "unsigned_signatories"
+	paramDraft               = "draft"
+
+	// The watermark applied to PDFs of contracts that have not yet been signed
+	// by all signatories.
+	draftWatermark = "DRAFT"
+)
+
 const (
 	gitMsgNewContract string = `Add new contract
 
@@ -39,6 +51,10 @@ Add contract derived from upstream at "{{.Upstream.Location}}"`
 
 Update contract {{if .Upstream}}with upstream at "{{.Upstream.Location}}" (hash {{.Upstream.Hash}}){{else}}(no upstream){{end}} and Template at {{.Template.File.Location}} (hash {{.Template.File.Hash}})`
 
+	gitMsgPullUpstream string = `Merge upstream changes
+
+Merge changes from upstream at "{{.Upstream.Location}}" (hash {{.Upstream.Hash}}) into contract`
+
 	gitMsgSignContract string = `Sign contract
 
 Sign the contract {{.ContractFile}} with hash {{.ContractHash}} (signatory e-mail: {{.Email}})
@@ -52,14 +68,25 @@ Compile the contract {{.ContractFile}} with hash {{.ContractHash}}`
 // Contract encapsulates all of the relevant data we need in order to deal with
 // the contract (rendering, signature management, etc.).
 type Contract struct {
-	ParamsFile *FileRef  `json:"params" yaml:"params" toml:"params"`       // Where to find the parameters file for the contract.
-	Template   *Template `json:"template" yaml:"template" toml:"template"` // The details of the contract text template to use when rendering the contract.
-	Upstream   *FileRef  `json:"upstream" yaml:"upstream" toml:"upstream"` // The upstream contract from which this contract has been derived (if any).
+	ParamsFile *FileRef        `json:"params" yaml:"params" toml:"params"`                                  // Where to find the parameters file for the contract.
+	Template   *Template       `json:"template" yaml:"template" toml:"template"`                            // The details of the contract text template to use when rendering the contract.
+	Upstream   *FileRef        `json:"upstream" yaml:"upstream" toml:"upstream"`                            // The upstream contract from which this contract has been derived (if any).
+	Schema     *FileRef        `json:"schema,omitempty" yaml:"schema,omitempty" toml:"schema,omitempty"`    // The schema against which to validate the contract's parameters (if any).
+	Lineage    []*LineageEntry `json:"lineage,omitempty" yaml:"lineage,omitempty" toml:"lineage,omitempty"` // All of the contracts from which this contract has been derived, from its upstream back to the root.
 
 	path        *FileRef               // The path to the contract (remote and/or local).
 	fileType    FileType               // What type of file is the original contract file?
 	params      map[string]interface{} // The parameters extracted from the parameters file.
 	signatories []*Signatory           // Cached signatories extracted from the parameters.
+	signatures  *SignatureManifest     // The manifest of signatures applied to this contract.
+}
+
+// ContractHashes captures the hashes of all of the components of a contract
+// at a particular point in time.
+type ContractHashes struct {
+	Contract string `json:"contract"` // The SHA256 hash of the contract file itself.
+	Params   string `json:"params"`   // The SHA256 hash of the contract's parameters file.
+	Template string `json:"template"` // The SHA256 hash of the contract's template file.
 }
 
 // New creates a new contract in the configured path from the specified upstream
@@ -118,13 +145,22 @@ func Load(loc string, ctx *Context) (*Contract, error) {
 	log.Debug().Msgf("Loaded contract components: %v", contract)
 
 	// parse the parameters file
-	contract.params, err = readContractParams(contract.ParamsFile.localPath)
+	contract.params, err = readContractParams(contract.ParamsFile.localPath, ctx.cache)
 	if err != nil {
 		return nil, err
 	}
 	log.Debug().Msgf("Extracted contract parameters: %v", contract.params)
+	if contract.Schema != nil {
+		if err := validateContractParams(contract.params, contract.Schema.localPath, ctx.cache); err != nil {
+			return nil, err
+		}
+	}
+	contract.signatures, err = loadSignatureManifest(path.Dir(contract.path.localPath))
+	if err != nil {
+		return nil, err
+	}
 	// update signatories from the parameters
-	contract.signatories, err = extractContractSignatories(contract.params, path.Dir(contract.path.localPath))
+	contract.signatories, err = extractContractSignatories(contract.params, contract.signatures, contract.Hashes(), path.Dir(contract.path.localPath))
 	if err != nil {
 		return nil, err
 	}
@@ -142,37 +178,60 @@ func loadContractComponents(loc string, checkHashes bool, ctx *Context) (*Contra
 	if err != nil {
 		return nil, err
 	}
-	contract, err := parseFileRefAsContract(entrypoint)
+	contract, err := parseFileRefAsContract(entrypoint, ctx.cache)
 	if err != nil {
 		return nil, err
 	}
-	// see if we need to resolve the parameters file or the template relative
+	// resolve the parameters file and the template's files, possibly relative
 	// to the contract entrypoint
-	if contract.ParamsFile.IsRelative() {
-		contract.ParamsFile, err = ResolveRelFileRef(entrypoint, contract.ParamsFile, checkHashes, ctx)
-	} else {
-		contract.ParamsFile, err = ResolveFileRef(contract.ParamsFile.Location, contract.ParamsFile.Hash, checkHashes, ctx)
-	}
+	contract.ParamsFile, err = resolveContractComponent(entrypoint, contract.ParamsFile, checkHashes, ctx)
 	if err != nil {
 		return nil, err
 	}
-
-	if contract.Template.File.IsRelative() {
-		contract.Template.File, err = ResolveRelFileRef(entrypoint, contract.Template.File, checkHashes, ctx)
-	} else {
-		contract.Template.File, err = ResolveFileRef(contract.Template.File.Location, contract.Template.File.Hash, checkHashes, ctx)
-	}
+	contract.Template.File, err = resolveContractComponent(entrypoint, contract.Template.File, checkHashes, ctx)
 	if err != nil {
 		return nil, err
 	}
+	if contract.Schema != nil {
+		contract.Schema, err = resolveContractComponent(entrypoint, contract.Schema, checkHashes, ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	names := make(map[string]bool)
+	for _, partial := range contract.Template.Partials {
+		if names[partial.Name] {
+			return nil, fmt.Errorf("template partial \"%s\" is declared more than once", partial.Name)
+		}
+		names[partial.Name] = true
+		partial.File, err = resolveContractComponent(entrypoint, partial.File, checkHashes, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve template partial \"%s\": %s", partial.Name, err)
+		}
+	}
 	return contract, nil
 }
 
+// resolveContractComponent resolves the given file reference, either relative
+// to the contract entrypoint (if it is a relative reference) or on its own.
+func resolveContractComponent(entrypoint, ref *FileRef, checkHashes bool, ctx *Context) (*FileRef, error) {
+	if ref.IsRelative() {
+		return ResolveRelFileRef(entrypoint, ref, checkHashes, ctx)
+	}
+	return ResolveFileRef(ref.Location, ref.Hash, checkHashes, ctx)
+}
+
 // Update will attempt to load the contract at the given location and update the
 // hashes to its parameters and/or template file(s). It necessarily does not do
 // any integrity checks on the parameters and/or template files prior to loading
 // them.
 func Update(loc string, ctx *Context) error {
+	return update(loc, gitMsgUpdateContract, ctx)
+}
+
+// update implements Update, using the given Git commit message template when
+// automatically committing the changes.
+func update(loc, gitMsg string, ctx *Context) error {
 	if fileRefType(loc, ctx) != LocalRef {
 		return fmt.Errorf("only contracts located in the local filesystem can be updated")
 	}
@@ -186,6 +245,17 @@ func Update(loc string, ctx *Context) error {
 	if err := contract.Save(ctx); err != nil {
 		return err
 	}
+	contract.path.Hash, err = hashOfFile(contract.path.localPath)
+	if err != nil {
+		return err
+	}
+	contract.signatures, err = loadSignatureManifest(path.Dir(contract.path.localPath))
+	if err != nil {
+		return err
+	}
+	for _, r := range contract.signatures.Stale(contract.Hashes()) {
+		log.Warn().Msgf("Signature by \"%s\" (%s) predates the current contract and must be applied again", r.SignatoryID, r.Email)
+	}
 
 	if ctx.autoCommit {
 		contractDir := path.Dir(contract.path.localPath)
@@ -196,7 +266,7 @@ func Update(loc string, ctx *Context) error {
 			log.Info().Msgf("No changes to contract files since last Git commit")
 			return nil
 		}
-		if err := gitCommit(contractDir, false, gitMsgUpdateContract, contract); err != nil {
+		if err := gitCommit(contractDir, false, gitMsg, contract); err != nil {
 			return fmt.Errorf("failed to automatically commit changes to contract at %s: %s", contract.path.localPath, err)
 		}
 
@@ -243,6 +313,43 @@ func (c *Contract) deriveTo(outputFile string, ctx *Context) (*Contract, error)
 		c.ParamsFile.localPath:    destParamsFile,
 		c.Template.File.localPath: destTemplateFile,
 	}
+	var destSchema *FileRef
+	if c.Schema != nil {
+		destSchemaFile := path.Join(destPath, c.Schema.Filename())
+		files[c.Schema.localPath] = destSchemaFile
+		destSchema = &FileRef{
+			Location:  c.Schema.Filename(),
+			Hash:      c.Schema.Hash,
+			localPath: destSchemaFile,
+		}
+	}
+	// relative partials are copied across, preserving their paths relative to
+	// the contract, whereas remote partials (e.g. shared clauses) continue to
+	// refer to their original locations
+	destPartials := make([]*Partial, 0, len(c.Template.Partials))
+	for _, partial := range c.Template.Partials {
+		destPartial := &Partial{
+			Name: partial.Name,
+			File: &FileRef{
+				Location:  partial.File.Location,
+				Hash:      partial.File.Hash,
+				localPath: partial.File.localPath,
+			},
+		}
+		if partial.File.IsRelative() {
+			relPath := path.Clean(partial.File.Location)
+			if strings.HasPrefix(relPath, "..") {
+				relPath = partial.File.Filename()
+			}
+			destPartial.File.Location = "./" + relPath
+			destPartial.File.localPath = path.Join(destPath, relPath)
+			if err := os.MkdirAll(path.Dir(destPartial.File.localPath), 0755); err != nil {
+				return nil, err
+			}
+			files[partial.File.localPath] = destPartial.File.localPath
+		}
+		destPartials = append(destPartials, destPartial)
+	}
 	for srcFile, destFile := range files {
 		log.Debug().Msgf("Copying %s to %s", srcFile, destFile)
 		if err := copyFile(srcFile, destFile); err != nil {
@@ -263,12 +370,14 @@ func (c *Contract) deriveTo(outputFile string, ctx *Context) (*Contract, error)
 				Hash:      c.Template.File.Hash,
 				localPath: destTemplateFile,
 			},
+			Partials: destPartials,
 		},
 		Upstream: &FileRef{
 			Location:  c.path.Location,
 			Hash:      c.path.Hash,
 			localPath: c.path.localPath,
 		},
+		Schema: destSchema,
 		path: &FileRef{
 			Location:  destContractFile,
 			Hash:      "",
@@ -276,10 +385,13 @@ func (c *Contract) deriveTo(outputFile string, ctx *Context) (*Contract, error)
 		},
 		fileType: c.fileType,
 	}
+	var err error
+	if dest.Lineage, err = lineageFrom(c, path.Dir(destContractFile), ctx); err != nil {
+		return nil, err
+	}
 	if err := dest.Save(ctx); err != nil {
 		return nil, err
 	}
-	var err error
 	// update the file hash
 	dest.path.Hash, err = hashOfFile(dest.path.localPath)
 	if err != nil {
@@ -336,47 +448,85 @@ func (c *Contract) Save(ctx *Context) error {
 }
 
 // Compile takes a parsed contract and attempts to generate the output artifact
-// that constitutes the final contract (as a PDF file).
+// that constitutes the final contract. The output format is determined by the
+// output file's extension (see OutputFormat).
 func (c *Contract) Compile(output string, ctx *Context) error {
 	activeProfile := ctx.ActiveProfile()
 	if activeProfile == nil {
 		return fmt.Errorf("no profile currently active (use \"themis-contract use\" to select one)")
 	}
-	// first we render the contract with its parameters to a temporary location
-	tempDir, err := ioutil.TempDir("", "themis-contract")
+	if stale := c.staleSignatoryIDs(); len(stale) > 0 {
+		if !ctx.allowStaleSigs {
+			return fmt.Errorf("signatures for %s were applied to an older version of the contract and must be applied again", strings.Join(stale, ", "))
+		}
+		log.Warn().Msgf("Rendering stale signatures for: %s", strings.Join(stale, ", "))
+	}
+	inputs, err := c.localInputFiles()
 	if err != nil {
 		return err
 	}
-	tempContract := path.Join(tempDir, c.Template.File.Filename())
-	if err := c.Render(tempContract); err != nil {
-		return err
+	build, err := c.contractBuildInfo(inputs)
+	if err != nil {
+		return fmt.Errorf("failed to determine source date for contract: %s", err)
+	}
+	// first we render the contract with its parameters to a temporary location
+	// (renderers must keep this location out of their output, so that
+	// compilation remains deterministic)
+	tempDir, err := ioutil.TempDir("", "themis-contract-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary folder for compilation: %s", err)
 	}
 	defer os.RemoveAll(tempDir)
+	tempContract := path.Join(tempDir, c.Template.renderedFilename())
+	if err := c.Render(tempContract, ctx); err != nil {
+		return err
+	}
 
 	// if it's not explicitly absolute or relative, assume we want the file in
 	// the same directory as the contract (it seems a reasonable assumption)
 	if path.Base(output) == output {
 		output = path.Join(path.Dir(c.path.localPath), output)
 	}
-	log.Info().Msgf("Compiling contract to: %s", output)
-	// then we use pandoc to convert the temporary contract to a PDF file
-	resourcePaths := strings.Join([]string{".", activeProfile.Path()}, ":")
-	pandocArgs := []string{
-		tempContract,
-		"-o",
-		output,
-		"--resource-path",
-		resourcePaths,
-		"--defaults",
-		path.Join(activeProfile.Path(), "pandoc-defaults.yaml"),
-	}
-	log.Debug().Msgf("Using pandoc arguments: %s", strings.Join(pandocArgs, " "))
-	pandocOutput, err := exec.Command("pandoc", pandocArgs...).CombinedOutput()
-	log.Debug().Msgf("pandoc execution output:\n%s\n", pandocOutput)
+	// then we convert the temporary contract to the output format, making sure
+	// the renderer can find signature images relative to the contract
+	job := &RenderJob{
+		Input:         tempContract,
+		Output:        output,
+		ResourcePaths: []string{".", path.Dir(c.path.localPath), activeProfile.Path()},
+		SourceDate:    build.sourceDate,
+		Watermark:     c.watermark(ctx),
+	}
+	rendererName, err := renderDocument(job, ctx)
 	if err != nil {
 		return err
 	}
-	return nil
+	format := job.Format
+	provenance, err := c.Provenance(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to compute contract provenance: %s", err)
+	}
+	if format == PDFOutput {
+		if err := embedPDFProvenance(output, provenance); err != nil {
+			return fmt.Errorf("failed to embed provenance metadata in %s: %s", output, err)
+		}
+	}
+	if !ctx.attestation {
+		return nil
+	}
+	attestationInputs, err := c.attestationInputs(inputs)
+	if err != nil {
+		return err
+	}
+	return writeAttestation(output, &Attestation{
+		Format:          format,
+		Renderer:        rendererName,
+		RendererOptions: job.Options,
+		SourceDateEpoch: build.sourceDate.Unix(),
+		GitCommit:       build.gitCommit,
+		GitDirty:        build.gitDirty,
+		Inputs:          attestationInputs,
+		Provenance:      provenance,
+	})
 }
 
 // Execute is a convenience function that will automatically sign and compile
@@ -442,29 +592,37 @@ func (c *Contract) Execute(sigID, output string, ctx *Context) error {
 
 // Render takes the current contract template and renders it using the current
 // parameters. The output file is the same format as the template, just with all
-// of the parameters substituted in.
-func (c *Contract) Render(output string) error {
+// of the parameters substituted in. How the template is rendered depends on the
+// template's format.
+func (c *Contract) Render(output string, ctx *Context) error {
 	log.Info().Msg("Rendering contract")
-	log.Debug().Msgf("Attempting to load template file: %s", c.Template.File.localPath)
-	tf, err := os.Open(c.Template.File.localPath)
-	if err != nil {
-		return err
+	log.Debug().Msgf("Attempting to render %s template file: %s", c.Template.Format, c.Template.File.localPath)
+	// render the template in-memory so if it fails we don't leave a partially
+	// rendered output file lying around
+	var buf bytes.Buffer
+	var err error
+	switch c.Template.Format {
+	case Mustache, "":
+		err = renderMustacheTemplate(c.Template.File.localPath, c.Template.partialFiles(), c.params, &buf)
+	case GoTemplate:
+		err = renderGoTemplate(c.Template.File.localPath, c.Template.partialFiles(), c.params, &buf)
+	case Handlebars:
+		err = renderHandlebarsTemplate(c.Template.File.localPath, c.Template.partialFiles(), c.params, &buf)
+	case Dhall:
+		if len(c.Template.Partials) > 0 {
+			return fmt.Errorf("Dhall templates do not support partials (Dhall templates can import other Dhall files directly)")
+		}
+		err = renderDhallTemplate(c.Template.File.localPath, c.params, &buf, ctx.cache)
+	default:
+		return fmt.Errorf("unsupported template format: %s", c.Template.Format)
 	}
-	defer tf.Close()
-	tpl, err := mustache.Parse(tf)
 	if err != nil {
-		return fmt.Errorf("failed to parse template: %s", err)
+		return fmt.Errorf("failed to render template: %s", err)
 	}
 
 	log.Debug().Msgf("Writing rendered template to output file: %s", output)
-	of, err := os.Create(output)
-	if err != nil {
-		return fmt.Errorf("failed to create output file: %s", err)
-	}
-	defer of.Close()
-
-	if err := tpl.Render(of, c.params); err != nil {
-		return fmt.Errorf("failed to render template: %s", err)
+	if err := ioutil.WriteFile(output, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write output file: %s", err)
 	}
 	return nil
 }
@@ -474,6 +632,10 @@ func (c *Contract) Render(output string) error {
 // behalf of whom you want to sign based on the default signatory for your
 // current profile.
 func (c *Contract) Sign(signatoryId string, ctx *Context) error {
+	activeProfile := ctx.ActiveProfile()
+	if activeProfile == nil {
+		return fmt.Errorf("no profile currently active (use \"themis-contract use\" to select one)")
+	}
 	signature, err := ctx.CurSignature()
 	if err != nil {
 		return err
@@ -493,13 +655,17 @@ func (c *Contract) Sign(signatoryId string, ctx *Context) error {
 	}
 	log.Info().Msgf("Signing contract on behalf of \"%s\" (%s)", signatory.Id, signatory.Email)
 	// apply the signature to our contract on behalf of the given signatory
-	sigImagePath, err := signature.applyTo(c.path.localPath, signatory.Id)
+	record, sigFiles, err := signature.applyTo(c, signatory, activeProfile.SSHKey)
 	if err != nil {
 		return fmt.Errorf("failed to apply signature \"%s\" to contract: %s", signature.id, err)
 	}
+	c.signatures.Record(record)
+	if err := c.signatures.Save(); err != nil {
+		return fmt.Errorf("failed to update signature manifest: %s", err)
+	}
 
 	// update signatories, since we just signed now
-	c.signatories, err = extractContractSignatories(c.params, path.Dir(c.path.localPath))
+	c.signatories, err = extractContractSignatories(c.params, c.signatures, c.Hashes(), path.Dir(c.path.localPath))
 	if err != nil {
 		return err
 	}
@@ -512,7 +678,10 @@ func (c *Contract) Sign(signatoryId string, ctx *Context) error {
 
 	if ctx.autoCommit {
 		contractDir := path.Dir(c.path.localPath)
-		commitFiles := []string{path.Base(c.path.localPath), path.Base(sigImagePath)}
+		commitFiles := []string{path.Base(c.path.localPath), path.Base(c.signatures.Path())}
+		for _, sigFile := range sigFiles {
+			commitFiles = append(commitFiles, path.Base(sigFile))
+		}
 		commitCtx := struct {
 			Email        string
 			ContractFile string
@@ -557,8 +726,54 @@ func (c *Contract) Signatories() []*Signatory {
 	return c.signatories
 }
 
+// IsFullySigned checks whether all of the contract's signatories have signed
+// its current version.
+func (c *Contract) IsFullySigned() bool {
+	for _, sig := range c.signatories {
+		if !sig.IsSigned() {
+			return false
+		}
+	}
+	return true
+}
+
+// watermark returns the watermark to apply when compiling the contract (if
+// any).
+func (c *Contract) watermark(ctx *Context) string {
+	if !ctx.draftWatermark || c.IsFullySigned() {
+		return ""
+	}
+	unsigned := make([]string, 0)
+	for _, sig := range c.signatories {
+		if !sig.IsSigned() {
+			unsigned = append(unsigned, sig.Id)
+		}
+	}
+	log.Info().Msgf("Contract has not yet been signed by %s, so it will be watermarked as a draft", strings.Join(unsigned, ", "))
+	return draftWatermark
+}
+
+func (c *Contract) staleSignatoryIDs() []string {
+	stale := make([]string, 0)
+	for _, sig := range c.signatories {
+		if sig.Stale {
+			stale = append(stale, sig.Id)
+		}
+	}
+	return stale
+}
+
+// Hashes returns the current hashes of the contract and its components.
+func (c *Contract) Hashes() *ContractHashes {
+	return &ContractHashes{
+		Contract: c.path.Hash,
+		Params:   c.ParamsFile.Hash,
+		Template: c.Template.File.Hash,
+	}
+}
+
 func (c *Contract) String() string {
-	return fmt.Sprintf("Contract{ParamsFile: %v, Template: %v, Upstream: %v, path: %v}", c.ParamsFile, c.Template, c.Upstream, c.path)
+	return fmt.Sprintf("Contract{ParamsFile: %v, Template: %v, Upstream: %v, Schema: %v, path: %v}", c.ParamsFile, c.Template, c.Upstream, c.Schema, c.path)
 }
 
 func (c *Contract) UpstreamDiff(diffProg string, ctx *Context) (*Diff, error) {
@@ -568,9 +783,23 @@ func (c *Contract) UpstreamDiff(diffProg string, ctx *Context) (*Diff, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to load upstream contract: %s", err)
 	}
-	paramsDiff, err := fileDiff(c.ParamsFile.localPath, upstream.ParamsFile.localPath, diffProg)
+	return c.diffAgainst(upstream, diffProg, ctx)
+}
+
+// diffAgainst compares this contract's parameters and template to those of the
+// given upstream contract.
+func (c *Contract) diffAgainst(upstream *Contract, diffProg string, ctx *Context) (*Diff, error) {
+	ourParams, err := readContractParams(c.ParamsFile.localPath, ctx.cache)
 	if err != nil {
-		return nil, fmt.Errorf("failed to perform diff on parameters file: %s", err)
+		return nil, fmt.Errorf("failed to read parameters file: %s", err)
+	}
+	upstreamParams, err := readContractParams(upstream.ParamsFile.localPath, ctx.cache)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upstream parameters file: %s", err)
+	}
+	paramsDiff, err := diffParams(ourParams, upstreamParams)
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform diff on parameters: %s", err)
 	}
 	templateDiff, err := fileDiff(c.Template.File.localPath, upstream.Template.File.localPath, diffProg)
 	if err != nil {
@@ -583,116 +812,77 @@ func (c *Contract) UpstreamDiff(diffProg string, ctx *Context) (*Diff, error) {
 }
 
 func (c *Contract) allLocalRelativeFiles() []string {
-	return []string{
+	files := []string{
 		path.Base(c.path.localPath),
 		path.Base(c.ParamsFile.localPath),
 		path.Base(c.Template.File.localPath),
 	}
-}
-
-func parseFileRefAsContract(ref *FileRef) (*Contract, error) {
-	origDir, err := os.Getwd()
-	if err != nil {
-		return nil, err
-	}
-	// we have to chdir here to ensure that Dhall relative import resolution works
-	if err := os.Chdir(ref.Dir()); err != nil {
-		return nil, err
+	if c.Schema != nil {
+		files = append(files, path.Base(c.Schema.localPath))
 	}
-	// try to reset the working directory
-	defer os.Chdir(origDir)
-
-	var contract *Contract
-
-	switch ref.Ext() {
-	case ".dhall":
-		contract, err = parseDhallContract(ref.localPath)
-	case ".json":
-		contract, err = parseJSONContract(ref.localPath)
-	case ".toml":
-		contract, err = parseTOMLContract(ref.localPath)
-	case ".yml", ".yaml":
-		contract, err = parseYAMLContract(ref.localPath)
-	default:
-		return nil, fmt.Errorf("unrecognized contract format with extension \"%s\"", ref.Ext())
-	}
-	if err != nil {
-		return nil, err
+	for _, partial := range c.Template.Partials {
+		if partial.File.IsRelative() {
+			files = append(files, path.Clean(partial.File.Location))
+		}
 	}
-	contract.path = ref
-	return contract, nil
+	return files
 }
 
-// The Dhall library for Golang doesn't seem to handle deserialization of
-// optional records into structs quite well, so for the time being we'll be
-// converting the Dhall contract to JSON first and then parsing it from JSON.
-func parseDhallContract(filename string) (*Contract, error) {
-	log.Debug().Msgf("Converting Dhall file to JSON: %s", filename)
-	content, err := exec.Command("dhall-to-json", "--file", filename).CombinedOutput()
-	log.Debug().Msgf("dhall-to-json output:\n%s\n", content)
+func parseFileRefAsContract(ref *FileRef, cache Cache) (*Contract, error) {
+	content, err := ioutil.ReadFile(ref.localPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert Dhall file %s to JSON: %v", filename, err)
-	}
-	contract := &Contract{}
-	if err := json.Unmarshal(content, contract); err != nil {
 		return nil, err
 	}
-	contract.fileType = DhallType
-	return contract, nil
-}
-
-func parseJSONContract(filename string) (*Contract, error) {
-	content, err := ioutil.ReadFile(filename)
+	contract, err := parseContract(content, ref.localPath, cache)
 	if err != nil {
 		return nil, err
 	}
-	contract := &Contract{}
-	if err := json.Unmarshal(content, contract); err != nil {
-		return nil, err
-	}
-	contract.fileType = JSONType
+	contract.path = ref
 	return contract, nil
 }
 
-func parseYAMLContract(filename string) (*Contract, error) {
-	content, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return nil, err
-	}
+// parseContract parses the given content as the content of the contract file
+// with the given name, whose extension determines the contract's format. Any
+// relative imports in Dhall contracts are resolved relative to the file, and
+// remote imports are retrieved by way of the given cache.
+func parseContract(content []byte, filename string, cache Cache) (*Contract, error) {
 	contract := &Contract{}
-	if err := yaml.Unmarshal(content, contract); err != nil {
-		return nil, err
+	var err error
+	switch ext := path.Ext(filename); ext {
+	case ".dhall":
+		// we convert the Dhall contract to JSON first and then parse it from
+		// JSON, so that Dhall contracts share the same decoding logic as JSON
+		// contracts
+		if content, err = dhallContentToJSON(content, filename, cache); err == nil {
+			err = json.Unmarshal(content, contract)
+		}
+		contract.fileType = DhallType
+	case ".json":
+		err = json.Unmarshal(content, contract)
+		contract.fileType = JSONType
+	case ".toml":
+		err = toml.Unmarshal(content, contract)
+		contract.fileType = TOMLType
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(content, contract)
+		contract.fileType = YAMLType
+	default:
+		return nil, fmt.Errorf("unrecognized contract format with extension \"%s\"", ext)
 	}
-	contract.fileType = YAMLType
-	return contract, nil
-}
-
-func parseTOMLContract(filename string) (*Contract, error) {
-	content, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	contract := &Contract{}
-	if err := toml.Unmarshal(content, contract); err != nil {
-		return nil, err
-	}
-	contract.fileType = TOMLType
 	return contract, nil
 }
 
-func readContractParams(filename string) (map[string]interface{}, error) {
+func readContractParams(filename string, cache Cache) (map[string]interface{}, error) {
 	var content []byte
 	var err error
 	params := make(map[string]interface{})
 	ext := path.Ext(filename)
 	switch ext {
 	case ".dhall":
-		log.Debug().Msgf("Converting params file from Dhall to JSON: %s", filename)
-		content, err = exec.Command("dhall-to-json", "--file", filename).CombinedOutput()
-		log.Debug().Msgf("dhall-to-json output:\n%s\n", content)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert Dhall file %s to JSON: %v", filename, err)
-		}
+		content, err = dhallToJSON(filename, cache)
 
 	case ".json", ".yml", ".yaml", ".toml":
 		content, err = ioutil.ReadFile(filename)
@@ -719,8 +909,11 @@ func readContractParams(filename string) (map[string]interface{}, error) {
 
 // We extract signatories by grabbing the "signatories" field, marshalling it
 // to JSON, and then unmarshalling it into our desired array of Signatory
-// instances. Inefficient, but it works and it was quick to code.
-func extractContractSignatories(params map[string]interface{}, contractPath string) ([]*Signatory, error) {
+// instances. Inefficient, but it works and it was quick to code. Signature
+// details are then populated from the contract's signature manifest, and any
+// signatures applied to a version of the contract other than the one described
+// by `hashes` are marked as stale.
+func extractContractSignatories(params map[string]interface{}, manifest *SignatureManifest, hashes *ContractHashes, contractPath string) ([]*Signatory, error) {
 	sigs, exists := params["signatories"]
 	if !exists {
 		return nil, fmt.Errorf("missing field \"signatories\" in contract parameters")
@@ -736,38 +929,118 @@ func extractContractSignatories(params map[string]interface{}, contractPath stri
 		return nil, err
 	}
 
-	// clear out any potential signature info
-	for _, r := range result {
-		r.Signature = ""
-	}
-
-	// scan the contract path for signatures for each signatory
-	expectedSigImages := map[string]int{}
-	for i, sig := range result {
-		sigImgFile := sigImageFilename(sig.Id)
-		if _, ok := expectedSigImages[sigImgFile]; ok {
+	seenIDs := make(map[string]bool)
+	for _, sig := range result {
+		if seenIDs[sig.Id] {
 			return nil, fmt.Errorf("duplicate signatory ID in contract parameters: %s", sig.Id)
 		}
-		expectedSigImages[sigImgFile] = i
+		seenIDs[sig.Id] = true
+		// clear out any potential signature info
+		sig.Signature = ""
+		sig.DetachedSignature = ""
+		sig.SSHSignature = ""
+		sig.SignedDate = ""
+		sig.Stale = false
+
+		record := manifest.ForSignatory(sig.Id)
+		if record == nil {
+			if _, err := os.Stat(path.Join(contractPath, sigImageFilename(sig.Id))); err == nil {
+				log.Warn().Msgf("Ignoring signature image for signatory \"%s\" because it is not recorded in %s - please sign again", sig.Id, signatureManifestFilename)
+			}
+			continue
+		}
+		if len(record.Image) > 0 {
+			sigImagePath := path.Join(contractPath, record.Image)
+			imageHash, err := hashOfFile(sigImagePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read signature image for \"%s\": %s", sig.Id, err)
+			}
+			if imageHash != record.ImageHash {
+				log.Warn().
+					Str("expected", record.ImageHash).
+					Str("actual", imageHash).
+					Msgf("Ignoring signature image for signatory \"%s\" because it has changed since signing", sig.Id)
+			} else {
+				sig.Signature = sigImagePath
+			}
+		}
+		if len(record.DetachedSignature) > 0 {
+			sig.DetachedSignature = path.Join(contractPath, record.DetachedSignature)
+		}
+		if len(record.SSHSignature) > 0 {
+			sig.SSHSignature = path.Join(contractPath, record.SSHSignature)
+		}
+		sig.SignedDate = record.Timestamp.Format(SignatureTimestampFormat)
+		if record.IsStale(hashes) {
+			sig.Stale = true
+			log.Warn().Msgf("Signatory \"%s\" must sign again: the %s changed since they signed", sig.Id, strings.Join(changedComponents(record.Hashes, hashes), ", "))
+		}
+		log.Debug().Msgf("Loaded signature details for signatory \"%s\" from manifest: %v", sig.Id, record)
 	}
-	files, err := ioutil.ReadDir(contractPath)
+	return result, nil
+}
+
+// normalizeParams returns a copy of the given parameters in which all values
+// have been converted to the types produced by decoding JSON (e.g. all lists
+// are []interface{} and all objects are map[string]interface{}).
+func normalizeParams(params map[string]interface{}) (map[string]interface{}, error) {
+	content, err := json.Marshal(params)
 	if err != nil {
 		return nil, err
 	}
-	for _, fi := range files {
-		if fi.IsDir() {
-			continue
-		}
-		sigId, ok := expectedSigImages[fi.Name()]
+	var result map[string]interface{}
+	if err := json.Unmarshal(content, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// withAllSignatoryFields returns a copy of the given parameters in which every
+// signatory has all of the fields that could possibly be populated for them
+// (e.g. their signature image, which is only present once they have signed),
+// set to their zero values where absent. This allows templates to refer to
+// those fields without them being flagged as undefined (when linting) or
+// failing to render (Go templates).
+func withAllSignatoryFields(rawParams map[string]interface{}) (map[string]interface{}, error) {
+	params, err := normalizeParams(rawParams)
+	if err != nil {
+		return nil, err
+	}
+	sigFields := make(map[string]interface{})
+	t := reflect.TypeOf(Signatory{})
+	for i := 0; i < t.NumField(); i++ {
+		field := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		sigFields[field] = reflect.Zero(t.Field(i).Type).Interface()
+	}
+	withSigFields := func(v interface{}) interface{} {
+		sig, ok := v.(map[string]interface{})
 		if !ok {
-			continue
+			return v
 		}
-		result[sigId].Signature = path.Join(contractPath, fi.Name())
-		result[sigId].SignedDate, err = getLatestSignedDate(result[sigId].Signature)
-		if err != nil {
-			return nil, fmt.Errorf("failed to obtain signature timestamp for \"%s\": %s", result[sigId].Id, err)
+		result := make(map[string]interface{})
+		for field, zero := range sigFields {
+			result[field] = zero
+		}
+		for k, v := range sig {
+			result[k] = v
 		}
-		log.Debug().Msgf("Discovered signature image \"%s\" for signatory \"%s\"", result[sigId].Signature, result[sigId].Id)
+		return result
+	}
+	result := make(map[string]interface{})
+	for k, v := range params {
+		switch {
+		case k == "signatories", k == paramUnsignedSignatories:
+			if sigs, ok := v.([]interface{}); ok {
+				newSigs := make([]interface{}, len(sigs))
+				for i, sig := range sigs {
+					newSigs[i] = withSigFields(sig)
+				}
+				v = newSigs
+			}
+		case strings.HasPrefix(k, "signatory_"):
+			v = withSigFields(v)
+		}
+		result[k] = v
 	}
 	return result, nil
 }
@@ -814,6 +1087,17 @@ func updateContractSignatories(params map[string]interface{}, signatories []*Sig
 		params[paramKey] = sigArr[i]
 	}
 	params["signatories"] = sigArr
+	// expose the contract's signing status, e.g. for templates to be able to
+	// show a banner while the contract is still a draft
+	unsigned := make([]map[string]interface{}, 0)
+	for i, sig := range signatories {
+		if !sig.IsSigned() {
+			unsigned = append(unsigned, sigArr[i])
+		}
+	}
+	params[paramIsFullySigned] = len(unsigned) == 0
+	params[paramUnsignedSignatories] = unsigned
+	params[paramDraft] = len(unsigned) > 0
 	log.Debug().Msgf("Updated contract signatories: %v", params)
 	return params, nil
 }
@@ -0,0 +1,666 @@
+package themis_contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/philandstuff/dhall-golang/v6/binary"
+	"github.com/philandstuff/dhall-golang/v6/core"
+	"github.com/philandstuff/dhall-golang/v6/imports"
+	"github.com/philandstuff/dhall-golang/v6/parser"
+	"github.com/philandstuff/dhall-golang/v6/term"
+	"github.com/rs/zerolog/log"
+)
+
+// dhallToJSON evaluates the Dhall expression in the given file and returns its
+// JSON representation. Relative imports within the file are resolved against
+// the file's own directory. Evaluation happens in-process, so we never need to
+// change our own process' working directory, which makes it safe to evaluate
+// multiple Dhall files concurrently. Remote imports are retrieved by way of the
+// given cache (if any).
+func dhallToJSON(filename string, cache Cache) ([]byte, error) {
+	log.Debug().Msgf("Converting Dhall file to JSON: %s", filename)
+	v, err := evalDhallFile(filename, cache)
+	if err != nil {
+		return nil, err
+	}
+	j, err := dhallValueToJSON(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert Dhall file %s to JSON: %s", filename, err)
+	}
+	return json.Marshal(j)
+}
+
+// dhallContentToJSON evaluates the given Dhall expression as if it were the
+// content of the file with the given name (which need not exist in its current
+// form, e.g. if the content comes from an older version of the file), and
+// returns its JSON representation.
+func dhallContentToJSON(content []byte, filename string, cache Cache) ([]byte, error) {
+	log.Debug().Msgf("Converting Dhall content of %s to JSON", filename)
+	absFilename, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	_, v, err := evalDhall(content, absFilename, cache)
+	if err != nil {
+		return nil, err
+	}
+	j, err := dhallValueToJSON(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert Dhall file %s to JSON: %s", filename, err)
+	}
+	return json.Marshal(j)
+}
+
+// evalDhallFile parses, resolves the imports of, type checks and evaluates the
+// Dhall expression in the given file.
+func evalDhallFile(filename string, cache Cache) (core.Value, error) {
+	_, v, err := typeCheckDhallFile(filename, cache)
+	return v, err
+}
+
+// typeCheckDhallFile parses, resolves the imports of, type checks and
+// evaluates the Dhall expression in the given file, returning both its type
+// and its value.
+func typeCheckDhallFile(filename string, cache Cache) (core.Value, core.Value, error) {
+	absFilename, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := ioutil.ReadFile(absFilename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read Dhall file %s: %s", filename, err)
+	}
+	return evalDhall(content, absFilename, cache)
+}
+
+// evalDhall parses, resolves the imports of, type checks and evaluates the
+// given Dhall expression, returning both its type and its value. Relative
+// imports are resolved against the directory of `filename`, which must be an
+// absolute path (although the file itself need not exist). Remote imports are
+// retrieved by way of the given cache (if any).
+func evalDhall(content []byte, filename string, cache Cache) (core.Value, core.Value, error) {
+	expr, err := parser.Parse(filename, content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse Dhall file %s: %s", filename, err)
+	}
+	loader, err := newDhallImportLoader(filename, cache)
+	if err != nil {
+		return nil, nil, err
+	}
+	resolved, err := loader.load(expr, term.LocalFile(filename))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve imports in Dhall file %s: %s", filename, err)
+	}
+	typ, err := core.TypeOf(resolved)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to type check Dhall file %s: %s", filename, err)
+	}
+	return typ, core.Eval(resolved), nil
+}
+
+// dhallImportLoader resolves all of the imports in Dhall expressions in the
+// same way as dhall-golang's imports.LoadWith, except that remote imports are
+// retrieved by way of our cache (if any). This way they're cached like any
+// other remote file, and are never fetched when offline. Local imports of
+// files in the cache's content-addressed store, which don't sit alongside the
+// files they import, are resolved from the store by their hashes.
+type dhallImportLoader struct {
+	dhallCache    imports.DhallCache
+	cache         Cache
+	storedImports map[string]string // The hashes of stored local imports, keyed by their absolute paths.
+}
+
+func newDhallImportLoader(filename string, cache Cache) (*dhallImportLoader, error) {
+	dhallCache, err := imports.StandardCache()
+	if err != nil {
+		return nil, err
+	}
+	l := &dhallImportLoader{
+		dhallCache:    dhallCache,
+		cache:         cache,
+		storedImports: make(map[string]string),
+	}
+	if cache == nil {
+		return l, nil
+	}
+	stored, err := cache.StoredImports(filename)
+	if err != nil {
+		return nil, err
+	}
+	for rel, hash := range stored {
+		l.storedImports[path.Join(path.Dir(filename), rel)] = hash
+	}
+	return l, nil
+}
+
+func (l *dhallImportLoader) load(e term.Term, ancestors ...term.Fetchable) (term.Term, error) {
+	switch e := e.(type) {
+	case term.Import:
+		here := e.Fetchable
+		origin := term.NullOrigin
+		if len(ancestors) >= 1 {
+			origin = ancestors[len(ancestors)-1].Origin()
+			var err error
+			if here, err = here.ChainOnto(ancestors[len(ancestors)-1]); err != nil {
+				return nil, err
+			}
+		}
+		if e.ImportMode == term.Location {
+			return here.AsLocation(), nil
+		}
+		for _, ancestor := range ancestors {
+			if ancestor == here {
+				return nil, fmt.Errorf("detected import cycle in %s", ancestor)
+			}
+		}
+		if e.Hash != nil {
+			if expr := l.dhallCache.Fetch(e.Hash); expr != nil {
+				return expr, nil
+			}
+		}
+		content, err := l.fetch(here, origin)
+		if err != nil {
+			return nil, err
+		}
+		var expr term.Term
+		if e.ImportMode == term.RawText {
+			expr = term.PlainText(content)
+		} else {
+			dynamicExpr, err := parser.Parse(here.String(), []byte(content))
+			if err != nil {
+				return nil, err
+			}
+			if expr, err = l.load(dynamicExpr, append(ancestors, here)...); err != nil {
+				return nil, err
+			}
+			if _, err = core.TypeOf(expr); err != nil {
+				return nil, err
+			}
+		}
+		exprVal := core.Eval(expr)
+		if e.Hash != nil {
+			actualHash, err := binary.SemanticHash(exprVal)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(e.Hash, actualHash[:]) {
+				return nil, fmt.Errorf("failed integrity check on %s: expected %x, but got %x", here, e.Hash, actualHash)
+			}
+			l.dhallCache.Save(actualHash, core.QuoteAlphaNormal(exprVal))
+		}
+		return core.Quote(exprVal), nil
+	case term.Op:
+		if e.OpCode == term.ImportAltOp {
+			if resolvedL, err := l.load(e.L, ancestors...); err == nil {
+				return resolvedL, nil
+			}
+			return l.load(e.R, ancestors...)
+		}
+		resolvedL, err := l.load(e.L, ancestors...)
+		if err != nil {
+			return nil, err
+		}
+		resolvedR, err := l.load(e.R, ancestors...)
+		if err != nil {
+			return nil, err
+		}
+		return term.Op{OpCode: e.OpCode, L: resolvedL, R: resolvedR}, nil
+	default:
+		return term.MaybeTransformSubexprs(e, func(t term.Term) (term.Term, error) {
+			return l.load(t, ancestors...)
+		})
+	}
+}
+
+// fetch retrieves the content of the given Dhall import. Remote imports are
+// retrieved from the cache, with the exception of cross-origin imports when
+// online, which are fetched directly so that dhall-golang can perform its CORS
+// checks on them.
+func (l *dhallImportLoader) fetch(here term.Fetchable, origin string) (string, error) {
+	if local, ok := here.(term.LocalFile); ok && origin == term.NullOrigin {
+		if hash, stored := l.storedImports[path.Clean(string(local))]; stored {
+			return l.fetchStored(local, hash)
+		}
+	}
+	remote, ok := here.(term.RemoteFile)
+	if !ok || l.cache == nil {
+		return here.Fetch(origin)
+	}
+	if origin != term.NullOrigin && origin != remote.Origin() {
+		if l.cache.Offline() {
+			return "", fmt.Errorf("cross-origin Dhall import %s (from %s) is never cached, so it is not available offline", remote, origin)
+		}
+		return remote.Fetch(origin)
+	}
+	u, err := url.Parse(remote.String())
+	if err != nil {
+		return "", err
+	}
+	cachedFile, err := l.cache.FromWeb(u)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve Dhall import %s: %s", remote, err)
+	}
+	content, err := ioutil.ReadFile(cachedFile)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// fetchStored retrieves the content of the given local import from the
+// cache's content-addressed store.
+func (l *dhallImportLoader) fetchStored(local term.LocalFile, hash string) (string, error) {
+	storedFile, err := l.cache.FromHash(hash, path.Base(string(local)))
+	if err != nil {
+		return "", err
+	}
+	if len(storedFile) == 0 {
+		return "", fmt.Errorf("Dhall import %s with hash %s is no longer in the cache", local, hash)
+	}
+	content, err := ioutil.ReadFile(storedFile)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// dhallLocalImports finds the relative local imports of the given Dhall file,
+// including those of the files it imports, and returns their absolute paths
+// keyed by their paths relative to the given file's folder.
+func dhallLocalImports(filename string) (map[string]string, error) {
+	absFilename, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	root := path.Dir(absFilename)
+	importFiles := make(map[string]string)
+	var visit func(file string) error
+	visit = func(file string) error {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		expr, err := parser.Parse(file, content)
+		if err != nil {
+			return fmt.Errorf("failed to parse Dhall file %s: %s", file, err)
+		}
+		return forEachDhallImport(expr, func(imp term.Import) error {
+			local, ok := imp.Fetchable.(term.LocalFile)
+			if !ok || local.IsAbs() || local.IsRelativeToHome() || imp.ImportMode == term.Location {
+				return nil
+			}
+			importFile := path.Join(path.Dir(file), string(local))
+			rel, err := filepath.Rel(root, importFile)
+			if err != nil {
+				return err
+			}
+			if _, seen := importFiles[rel]; seen {
+				return nil
+			}
+			importFiles[rel] = importFile
+			if imp.ImportMode == term.RawText {
+				return nil
+			}
+			return visit(importFile)
+		})
+	}
+	if err := visit(absFilename); err != nil {
+		return nil, err
+	}
+	return importFiles, nil
+}
+
+// forEachDhallImport calls fn for each import in the given Dhall expression.
+func forEachDhallImport(e term.Term, fn func(imp term.Import) error) error {
+	if imp, ok := e.(term.Import); ok {
+		return fn(imp)
+	}
+	_, err := term.MaybeTransformSubexprs(e, func(t term.Term) (term.Term, error) {
+		return t, forEachDhallImport(t, fn)
+	})
+	return err
+}
+
+// dhallValueToJSON converts the given normalized Dhall value into a value that
+// can be marshaled to JSON, following the conventions of `dhall-to-json`:
+// record fields whose values are `None` are omitted, union alternatives are
+// converted to their payloads (or their names if they have no payload), and
+// lists of `mapKey`/`mapValue` records are converted to objects.
+func dhallValueToJSON(v core.Value) (interface{}, error) {
+	return dhallTermToJSON(core.Quote(v))
+}
+
+func dhallTermToJSON(t term.Term) (interface{}, error) {
+	switch t := t.(type) {
+	case term.BoolLit:
+		return bool(t), nil
+	case term.NaturalLit:
+		return uint64(t), nil
+	case term.IntegerLit:
+		return int64(t), nil
+	case term.DoubleLit:
+		if math.IsNaN(float64(t)) || math.IsInf(float64(t), 0) {
+			return nil, fmt.Errorf("cannot convert %v to JSON", float64(t))
+		}
+		return float64(t), nil
+	case term.TextLit:
+		if len(t.Chunks) == 0 {
+			return t.Suffix, nil
+		}
+	case term.Some:
+		return dhallTermToJSON(t.Val)
+	case term.App:
+		if t.Fn == term.None {
+			return nil, nil
+		}
+		// a union alternative with a payload
+		if _, ok := t.Fn.(term.Field); ok {
+			return dhallTermToJSON(t.Arg)
+		}
+	case term.Field:
+		// a union alternative without a payload
+		if _, ok := t.Record.(term.UnionType); ok {
+			return t.FieldName, nil
+		}
+	case term.RecordLit:
+		obj := make(map[string]interface{}, len(t))
+		for k, field := range t {
+			if app, ok := field.(term.App); ok && app.Fn == term.None {
+				continue
+			}
+			converted, err := dhallTermToJSON(field)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", k, err)
+			}
+			obj[k] = converted
+		}
+		return obj, nil
+	case term.EmptyList:
+		if list, ok := t.Type.(term.App); ok && list.Fn == term.List && isDhallMapEntryType(list.Arg) {
+			return map[string]interface{}{}, nil
+		}
+		return []interface{}{}, nil
+	case term.NonEmptyList:
+		if obj, ok, err := dhallMapToJSON(t); ok || err != nil {
+			return obj, err
+		}
+		items := make([]interface{}, len(t))
+		for i, item := range t {
+			converted, err := dhallTermToJSON(item)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %s", i, err)
+			}
+			items[i] = converted
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("cannot convert expression of type %T to JSON", t)
+}
+
+// dhallMapToJSON converts a list of `mapKey`/`mapValue` records to an object.
+// Returns false if the list is not such a list.
+func dhallMapToJSON(list term.NonEmptyList) (interface{}, bool, error) {
+	obj := make(map[string]interface{}, len(list))
+	for _, item := range list {
+		entry, ok := item.(term.RecordLit)
+		if !ok || len(entry) != 2 {
+			return nil, false, nil
+		}
+		key, ok := entry["mapKey"].(term.TextLit)
+		if !ok || len(key.Chunks) > 0 {
+			return nil, false, nil
+		}
+		val, ok := entry["mapValue"]
+		if !ok {
+			return nil, false, nil
+		}
+		converted, err := dhallTermToJSON(val)
+		if err != nil {
+			return nil, true, fmt.Errorf("%s: %s", key.Suffix, err)
+		}
+		obj[key.Suffix] = converted
+	}
+	return obj, true, nil
+}
+
+// isDhallMapEntryType checks whether the given type is that of the
+// `mapKey`/`mapValue` records that Dhall uses to represent maps. Types that
+// have been evaluated can be checked by first quoting them.
+func isDhallMapEntryType(t term.Term) bool {
+	rt, ok := t.(term.RecordType)
+	if !ok || len(rt) != 2 {
+		return false
+	}
+	_, hasKey := rt["mapKey"]
+	_, hasValue := rt["mapValue"]
+	return hasKey && hasValue
+}
+
+// applyDhallFunction applies the Dhall function in the given file to the given
+// (JSON-compatible) value, which is first converted to the function's
+// parameter type. Returns the type and value of the result.
+func applyDhallFunction(filename string, arg interface{}, cache Cache) (core.Value, core.Value, error) {
+	fnVal, err := evalDhallFile(filename, cache)
+	if err != nil {
+		return nil, nil, err
+	}
+	fn, ok := fnVal.(core.Callable)
+	if !ok {
+		return nil, nil, fmt.Errorf("expected Dhall file %s to contain a function", filename)
+	}
+	argTerm, err := jsonToDhall(arg, fn.ArgType())
+	if err != nil {
+		return nil, nil, err
+	}
+	app := term.App{Fn: core.Quote(fn), Arg: argTerm}
+	typ, err := core.TypeOf(app)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to apply Dhall function in %s: %s", filename, err)
+	}
+	return typ, core.Eval(app), nil
+}
+
+// jsonToDhall converts the given (JSON-compatible) value into a Dhall term of
+// the given type, along the same lines as `json-to-dhall`. Fields of the value
+// that are not present in the type are ignored, and missing or null values of
+// `Optional` types become `None`. Objects can be converted to lists of
+// `mapKey`/`mapValue` records, and strings to union alternatives.
+func jsonToDhall(v interface{}, typ core.Value) (term.Term, error) {
+	// normalize the value by way of JSON, since YAML and TOML decoding produce
+	// a variety of different types
+	content, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	var normalized interface{}
+	if err := dec.Decode(&normalized); err != nil {
+		return nil, err
+	}
+	return jsonValueToDhall(normalized, typ, rootParamPath)
+}
+
+// rootParamPath is how JSON Schema validation errors refer to the parameters
+// as a whole, so we report Dhall type errors at the top level in the same way.
+const rootParamPath = "(root)"
+
+// joinFieldPath returns the dotted path of the given field of the value at the
+// given path, as in JSON Schema validation errors.
+func joinFieldPath(parent, name string) string {
+	if parent == rootParamPath {
+		return name
+	}
+	return parent + "." + name
+}
+
+func jsonValueToDhall(v interface{}, typ core.Value, fieldPath string) (term.Term, error) {
+	switch typ := typ.(type) {
+	case core.OptionalOf:
+		if v == nil {
+			return term.App{Fn: term.None, Arg: core.Quote(typ.Type)}, nil
+		}
+		val, err := jsonValueToDhall(v, typ.Type, fieldPath)
+		if err != nil {
+			return nil, err
+		}
+		return term.Some{Val: val}, nil
+
+	case core.Builtin:
+		return jsonScalarToDhall(v, typ, fieldPath)
+
+	case core.ListOf:
+		if obj, ok := v.(map[string]interface{}); ok {
+			if rt, ok := typ.Type.(core.RecordType); ok && isDhallMapEntryType(core.Quote(rt)) {
+				return jsonMapToDhall(obj, rt, fieldPath)
+			}
+		}
+		items, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: expected a list, but got %s", fieldPath, describeJSONValue(v))
+		}
+		if len(items) == 0 {
+			return term.EmptyList{Type: term.App{Fn: term.List, Arg: core.Quote(typ.Type)}}, nil
+		}
+		list := make(term.NonEmptyList, len(items))
+		for i, item := range items {
+			converted, err := jsonValueToDhall(item, typ.Type, fmt.Sprintf("%s[%d]", fieldPath, i))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = converted
+		}
+		return list, nil
+
+	case core.RecordType:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s: expected an object, but got %s", fieldPath, describeJSONValue(v))
+		}
+		rec := make(term.RecordLit, len(typ))
+		for k, fieldType := range typ {
+			fieldVal, present := obj[k]
+			if _, optional := fieldType.(core.OptionalOf); !present && !optional {
+				return nil, fmt.Errorf("%s: missing required field \"%s\"", fieldPath, k)
+			}
+			converted, err := jsonValueToDhall(fieldVal, fieldType, joinFieldPath(fieldPath, k))
+			if err != nil {
+				return nil, err
+			}
+			rec[k] = converted
+		}
+		return rec, nil
+
+	case core.UnionType:
+		return jsonUnionToDhall(v, typ, fieldPath)
+	}
+	return nil, fmt.Errorf("%s: cannot convert JSON values to Dhall type %v", fieldPath, core.Quote(typ))
+}
+
+func jsonScalarToDhall(v interface{}, typ core.Builtin, fieldPath string) (term.Term, error) {
+	switch typ {
+	case core.Text:
+		if s, ok := v.(string); ok {
+			return term.TextLit{Suffix: s}, nil
+		}
+	case core.Bool:
+		if b, ok := v.(bool); ok {
+			return term.BoolLit(b), nil
+		}
+	case core.Natural:
+		if n, ok := v.(json.Number); ok {
+			if i, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+				return term.NaturalLit(i), nil
+			}
+		}
+	case core.Integer:
+		if n, ok := v.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				return term.IntegerLit(i), nil
+			}
+		}
+	case core.Double:
+		if n, ok := v.(json.Number); ok {
+			if f, err := n.Float64(); err == nil {
+				return term.DoubleLit(f), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s: expected a %s, but got %s", fieldPath, typ, describeJSONValue(v))
+}
+
+// jsonMapToDhall converts an object into a list of `mapKey`/`mapValue`
+// records, sorted by key.
+func jsonMapToDhall(obj map[string]interface{}, entryType core.RecordType, fieldPath string) (term.Term, error) {
+	if len(obj) == 0 {
+		return term.EmptyList{Type: term.App{Fn: term.List, Arg: core.Quote(entryType)}}, nil
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := make(term.NonEmptyList, len(keys))
+	for i, k := range keys {
+		key, err := jsonValueToDhall(k, entryType["mapKey"], fieldPath)
+		if err != nil {
+			return nil, err
+		}
+		val, err := jsonValueToDhall(obj[k], entryType["mapValue"], joinFieldPath(fieldPath, k))
+		if err != nil {
+			return nil, err
+		}
+		list[i] = term.RecordLit{"mapKey": key, "mapValue": val}
+	}
+	return list, nil
+}
+
+// jsonUnionToDhall converts a value into an alternative of the given union
+// type. Strings match alternatives without payloads by name, otherwise the
+// first alternative (in alphabetical order) whose payload type the value can
+// be converted to is used.
+func jsonUnionToDhall(v interface{}, typ core.UnionType, fieldPath string) (term.Term, error) {
+	unionTerm := core.Quote(typ)
+	if s, ok := v.(string); ok {
+		if payloadType, exists := typ[s]; exists && payloadType == nil {
+			return term.Field{Record: unionTerm, FieldName: s}, nil
+		}
+	}
+	alternatives := make([]string, 0, len(typ))
+	for alt := range typ {
+		alternatives = append(alternatives, alt)
+	}
+	sort.Strings(alternatives)
+	for _, alt := range alternatives {
+		if typ[alt] == nil {
+			continue
+		}
+		if val, err := jsonValueToDhall(v, typ[alt], fieldPath); err == nil {
+			return term.App{Fn: term.Field{Record: unionTerm, FieldName: alt}, Arg: val}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: %s does not match any alternative of the union type %v", fieldPath, describeJSONValue(v), unionTerm)
+}
+
+func describeJSONValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "a list"
+	}
+	return fmt.Sprintf("%v", v)
+}
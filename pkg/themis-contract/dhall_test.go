@@ -0,0 +1,77 @@
+package themis_contract_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+)
+
+func TestDhallToJSON(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory for testing: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	if err := os.Mkdir(path.Join(tempDir, "types"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"types/Currency.dhall": `< USD | EUR | Other : Text >`,
+		"params.dhall": `
+let Currency = ./types/Currency.dhall
+
+in  { client = "Acme Corp"
+    , amount = 1000
+    , currency = Currency.EUR
+    , other = Currency.Other "BTC"
+    , contact = None Text
+    , vat = Some 15
+    , rates = toMap { hourly = 100, daily = 700 }
+    , signatories = [ { id = "alice", email = "alice@somewhere.com" } ]
+    }
+`,
+	}
+	for filename, content := range files {
+		if err := ioutil.WriteFile(path.Join(tempDir, filename), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// relative imports must be resolved against the file's directory, and not
+	// against our current working directory
+	output, err := contract.DhallToJSON(path.Join(tempDir, "params.dhall"), nil)
+	if err != nil {
+		t.Fatalf("failed to convert Dhall file to JSON: %v", err)
+	}
+	var actual map[string]interface{}
+	if err := json.Unmarshal(output, &actual); err != nil {
+		t.Fatalf("failed to parse JSON output: %v\n%s", err, output)
+	}
+	var expected map[string]interface{}
+	if err := json.Unmarshal([]byte(`{
+		"client": "Acme Corp",
+		"amount": 1000,
+		"currency": "EUR",
+		"other": "BTC",
+		"vat": 15,
+		"rates": {"daily": 700, "hourly": 100},
+		"signatories": [{"id": "alice", "email": "alice@somewhere.com"}]
+	}`), &expected); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected JSON output %v, but got %v", expected, actual)
+	}
+
+	if err := ioutil.WriteFile(path.Join(tempDir, "invalid.dhall"), []byte(`{ a = 1 } // 2`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := contract.DhallToJSON(path.Join(tempDir, "invalid.dhall"), nil); err == nil {
+		t.Error("expected Dhall file that does not type check to fail conversion")
+	}
+}
@@ -1,16 +1,134 @@
 package themis_contract
 
 import (
+	"encoding/json"
+	"fmt"
 	"os/exec"
+	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/rs/zerolog/log"
 )
 
+// ParamsChangeKind describes how a parameter differs between two sets of
+// contract parameters.
+type ParamsChangeKind string
+
+const (
+	ParamAdded   ParamsChangeKind = "added"
+	ParamRemoved ParamsChangeKind = "removed"
+	ParamChanged ParamsChangeKind = "changed"
+)
+
 // Diff captures the differences between a contract and its upstream.
 type Diff struct {
-	ParamsDiff   string
-	TemplateDiff string
+	ParamsDiff   ParamsDiff `json:"params"`
+	TemplateDiff string     `json:"template"`
+}
+
+// ParamsChange is a single difference between our parameters and those of
+// another contract (e.g. the upstream).
+type ParamsChange struct {
+	Path  string           `json:"path"`            // The dotted path to the parameter (e.g. "client.address.city" or "signatories[0].name").
+	Kind  ParamsChangeKind `json:"kind"`            // Whether the parameter was added, removed or changed.
+	Ours  interface{}      `json:"ours,omitempty"`  // Our value of the parameter (if any).
+	Other interface{}      `json:"other,omitempty"` // The other contract's value of the parameter (if any).
+}
+
+// ParamsDiff is a list of all of the differences between two sets of
+// parameters, ordered by path.
+type ParamsDiff []*ParamsChange
+
+func (d ParamsDiff) String() string {
+	lines := make([]string, 0, len(d))
+	for _, change := range d {
+		switch change.Kind {
+		case ParamAdded:
+			lines = append(lines, fmt.Sprintf("+ %s: %s", change.Path, formatParamValue(change.Other)))
+		case ParamRemoved:
+			lines = append(lines, fmt.Sprintf("- %s: %s", change.Path, formatParamValue(change.Ours)))
+		case ParamChanged:
+			lines = append(lines, fmt.Sprintf("~ %s: %s -> %s", change.Path, formatParamValue(change.Ours), formatParamValue(change.Other)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// diffParams compares our parameters to another set of parameters, where
+// parameters only present in the other set are considered to have been added.
+// Parameters are compared after normalization, so that it does not matter
+// from which file formats they were read, nor in which order keys appear.
+func diffParams(ours, other map[string]interface{}) (ParamsDiff, error) {
+	normOurs, err := normalizeParams(ours)
+	if err != nil {
+		return nil, err
+	}
+	normOther, err := normalizeParams(other)
+	if err != nil {
+		return nil, err
+	}
+	diff := make(ParamsDiff, 0)
+	diffParamValues("", normOurs, normOther, &diff)
+	return diff, nil
+}
+
+func diffParamValues(paramPath string, ours, other interface{}, diff *ParamsDiff) {
+	oursMap, oursIsMap := ours.(map[string]interface{})
+	otherMap, otherIsMap := other.(map[string]interface{})
+	if oursIsMap && otherIsMap {
+		keys := make([]string, 0, len(oursMap)+len(otherMap))
+		for k := range oursMap {
+			keys = append(keys, k)
+		}
+		for k := range otherMap {
+			if _, ok := oursMap[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			childPath := joinParamPath(paramPath, k, true)
+			oursVal, inOurs := oursMap[k]
+			otherVal, inOther := otherMap[k]
+			switch {
+			case !inOurs:
+				*diff = append(*diff, &ParamsChange{Path: childPath, Kind: ParamAdded, Other: otherVal})
+			case !inOther:
+				*diff = append(*diff, &ParamsChange{Path: childPath, Kind: ParamRemoved, Ours: oursVal})
+			default:
+				diffParamValues(childPath, oursVal, otherVal, diff)
+			}
+		}
+		return
+	}
+	oursList, oursIsList := ours.([]interface{})
+	otherList, otherIsList := other.([]interface{})
+	if oursIsList && otherIsList {
+		for i := 0; i < len(oursList) || i < len(otherList); i++ {
+			childPath := fmt.Sprintf("%s[%d]", paramPath, i)
+			switch {
+			case i >= len(oursList):
+				*diff = append(*diff, &ParamsChange{Path: childPath, Kind: ParamAdded, Other: otherList[i]})
+			case i >= len(otherList):
+				*diff = append(*diff, &ParamsChange{Path: childPath, Kind: ParamRemoved, Ours: oursList[i]})
+			default:
+				diffParamValues(childPath, oursList[i], otherList[i], diff)
+			}
+		}
+		return
+	}
+	if !reflect.DeepEqual(ours, other) {
+		*diff = append(*diff, &ParamsChange{Path: paramPath, Kind: ParamChanged, Ours: ours, Other: other})
+	}
+}
+
+func formatParamValue(v interface{}) string {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(content)
 }
 
 func fileDiff(a, b, diffProg string) (string, error) {
@@ -23,3 +141,22 @@ func fileDiff(a, b, diffProg string) (string, error) {
 	}
 	return strings.Trim(string(output), " \n\r"), nil
 }
+
+// fileMerge performs a three-way merge of the changes between the base and
+// their versions of a file into our version of the file, returning the merged
+// content and the number of conflicts. Conflicts are marked in the merged
+// content in the same way as Git marks them.
+func fileMerge(ours, base, theirs string) ([]byte, int, error) {
+	cmd := exec.Command("git", "merge-file", "-p", "-L", "ours", "-L", "base", "-L", "upstream", ours, base, theirs)
+	output, err := cmd.Output()
+	if err == nil {
+		return output, 0, nil
+	}
+	// a positive exit code is the number of conflicts, whereas a negative one
+	// (which is truncated by the OS) indicates an error
+	exitCode := cmd.ProcessState.ExitCode()
+	if exitCode <= 0 || exitCode > 127 {
+		return nil, 0, fmt.Errorf("git merge-file failed: %s", err)
+	}
+	return output, exitCode, nil
+}
@@ -0,0 +1,77 @@
+package themis_contract_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+)
+
+func TestParamsDiff(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	ours := path.Join(tempDir, "params.json")
+	upstream := path.Join(tempDir, "params.yaml")
+	files := map[string]string{
+		ours: `{
+  "rate": 100,
+  "client": {"name": "Acme Corp", "city": "Cape Town"},
+  "notes": "Our notes",
+  "signatories": [{"id": "alice", "name": "Alice"}]
+}`,
+		upstream: `
+client:
+  city: Berlin
+  name: Acme Corp
+rate: 100
+currency: EUR
+signatories:
+  - id: alice
+    name: Alice Smith
+  - id: bob
+    name: Bob
+`,
+	}
+	for filename, content := range files {
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	diff, err := contract.DiffParamsFiles(ours, upstream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expected = `~ client.city: "Cape Town" -> "Berlin"
++ currency: "EUR"
+- notes: "Our notes"
+~ signatories[0].name: "Alice" -> "Alice Smith"
++ signatories[1]: {"id":"bob","name":"Bob"}`
+	if diff.String() != expected {
+		t.Errorf("expected params diff to be:\n%s\n\nbut got:\n%s", expected, diff)
+	}
+	if diff[1].Kind != contract.ParamAdded || diff[1].Path != "currency" || diff[1].Ours != nil {
+		t.Errorf("unexpected change: %v", diff[1])
+	}
+	// the other side need not be an upstream, so it isn't labelled as one
+	changeJSON, err := json.Marshal(diff[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"path":"currency","kind":"added","other":"EUR"}`; string(changeJSON) != expected {
+		t.Errorf("expected change to be marshaled to JSON as %s, but got %s", expected, changeJSON)
+	}
+
+	same, err := contract.DiffParamsFiles(ours, ours)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(same) != 0 {
+		t.Errorf("expected no differences between identical parameters, but got:\n%s", same)
+	}
+}
@@ -1,5 +1,10 @@
 package themis_contract
 
+import (
+	"io"
+	"os"
+)
+
 func WordWrapString(s string, lineWidth int) string {
 	return wordWrapString(s, lineWidth)
 }
@@ -28,3 +33,116 @@ func NewTestProfileContract(id, url, localPath string) *ProfileContract {
 		localPath: localPath,
 	}
 }
+
+func GPGDetachSign(keyID string, data []byte, outputFile string) (string, error) {
+	return gpgDetachSign(keyID, data, outputFile)
+}
+
+func LoadSignatureManifest(contractDir string) (*SignatureManifest, error) {
+	return loadSignatureManifest(contractDir)
+}
+
+func SigningStatement(signatoryID, email string, hashes *ContractHashes) string {
+	return signingStatement(signatoryID, email, hashes)
+}
+
+func VerifySignatory(signatory *Signatory, record *SignatureRecord, hashes *ContractHashes, allowedSignersFile string) (*SignatoryVerification, error) {
+	return verifySignatory(signatory, record, hashes, allowedSignersFile)
+}
+
+func SSHSign(keyFile string, data []byte) ([]byte, error) {
+	return sshSign(keyFile, data)
+}
+
+func SSHVerify(allowedSignersFile, identity, sigFile string, data []byte) (bool, string, error) {
+	return sshVerify(allowedSignersFile, identity, sigFile, data)
+}
+
+// AddTestSignature adds a signature to the signature database in the given
+// home folder, and reloads the database to make sure it can be loaded again.
+func AddTestSignature(home, name, email, sigImage, keyID, sshKey string) (*Signature, error) {
+	if err := os.MkdirAll(themisContractSignaturesPath(home), 0755); err != nil {
+		return nil, err
+	}
+	db, err := loadSignatureDB(home)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := db.newSignature(name, email, sigImage, keyID, sshKey)
+	if err != nil {
+		return nil, err
+	}
+	if db, err = loadSignatureDB(home); err != nil {
+		return nil, err
+	}
+	return db.sigs[sig.id], nil
+}
+
+func DhallToJSON(filename string, cache Cache) ([]byte, error) {
+	return dhallToJSON(filename, cache)
+}
+
+func RenderDhallTemplate(templateFile string, params map[string]interface{}, w io.Writer) error {
+	return renderDhallTemplate(templateFile, params, w, nil)
+}
+
+func RenderMustacheTemplate(templateFile string, partials map[string]string, params map[string]interface{}, w io.Writer) error {
+	return renderMustacheTemplate(templateFile, partials, params, w)
+}
+
+func RenderGoTemplate(templateFile string, partials map[string]string, params map[string]interface{}, w io.Writer) error {
+	return renderGoTemplate(templateFile, partials, params, w)
+}
+
+func RenderHandlebarsTemplate(templateFile string, partials map[string]string, params map[string]interface{}, w io.Writer) error {
+	return renderHandlebarsTemplate(templateFile, partials, params, w)
+}
+
+func ValidateContractParams(params map[string]interface{}, schemaFile string) error {
+	return validateContractParams(params, schemaFile, nil)
+}
+
+func ReadContractParams(paramsFile string) (map[string]interface{}, error) {
+	return readContractParams(paramsFile, nil)
+}
+
+func OutputFormatForFile(filename string) (OutputFormat, error) {
+	return outputFormatForFile(filename)
+}
+
+func PreprocessBuiltinMarkdown(body string) string {
+	return preprocessBuiltinMarkdown(body)
+}
+
+func RenderBuiltinPDF(input, output string, resourcePaths []string) error {
+	return (&builtinRenderer{}).Render(&RenderJob{
+		Input:         input,
+		Output:        output,
+		Format:        PDFOutput,
+		ResourcePaths: resourcePaths,
+	})
+}
+
+func EmbedPDFProvenance(pdfFile string, p *Provenance) error {
+	return embedPDFProvenance(pdfFile, p)
+}
+
+func DiffParamsFiles(oursFile, otherFile string) (ParamsDiff, error) {
+	ours, err := readContractParams(oursFile, nil)
+	if err != nil {
+		return nil, err
+	}
+	other, err := readContractParams(otherFile, nil)
+	if err != nil {
+		return nil, err
+	}
+	return diffParams(ours, other)
+}
+
+func NormalizeParams(params map[string]interface{}) (map[string]interface{}, error) {
+	return normalizeParams(params)
+}
+
+func RedlineMarkdown(original, revised string) string {
+	return redlineMarkdown(original, revised)
+}
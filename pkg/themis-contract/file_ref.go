@@ -53,9 +53,17 @@ func LocalFileRef(path string) (*FileRef, error) {
 
 // ResolveFileRef will attempt to resolve the file at the given location. If it
 // is a remote file, it will be fetched from its location and cached locally
-// using the given cache.
+// using the given cache. Remote files whose hashes are to be checked are first
+// looked up by their expected hash in the cache, in which case they are not
+// fetched at all.
 func ResolveFileRef(loc, expectedHash string, checkHash bool, ctx *Context) (resolved *FileRef, err error) {
-	switch fileRefType(loc, ctx) {
+	refType := fileRefType(loc, ctx)
+	if checkHash {
+		if resolved, err = resolvePinnedFileRef(loc, expectedHash, refType, ctx.cache); resolved != nil || err != nil {
+			return
+		}
+	}
+	switch refType {
 	case LocalRef:
 		resolved, err = LocalFileRef(loc)
 		log.Debug().Msgf("Resolved location \"%s\" as a local file", loc)
@@ -94,12 +102,17 @@ func ResolveFileRef(loc, expectedHash string, checkHash bool, ctx *Context) (res
 					Msgf("Hash for file has changed: %s", resolved.Location)
 			}
 		}
+		storeFileRef(resolved, ctx.cache)
 	}
 	return
 }
 
 // ResolveRelFileRef attempts to resolve a file reference relative to another
-// one. Specifically, it will attempt to resolve `rel` against `abs`.
+// one. Specifically, it will attempt to resolve `rel` against `abs`. Unlike in
+// ResolveFileRef, files whose hashes are to be checked are only looked up in
+// the cache by their hashes if they cannot be retrieved, since they need to be
+// kept alongside the file they're relative to (e.g. in the same clone of a Git
+// repository).
 // TODO: Implement security check here to prevent user escaping to host file system.
 func ResolveRelFileRef(abs, rel *FileRef, checkHash bool, ctx *Context) (resolved *FileRef, err error) {
 	if !rel.IsRelative() {
@@ -117,8 +130,15 @@ func ResolveRelFileRef(abs, rel *FileRef, checkHash bool, ctx *Context) (resolve
 	}
 	log.Debug().Msgf("Resolved relative file reference: %v", resolved)
 	if err != nil {
+		if checkHash {
+			if pinned, pinnedErr := resolvePinnedFileRef(rel.Location, rel.Hash, abs.Type(), ctx.cache); pinned != nil || pinnedErr != nil {
+				log.Warn().Msgf("Using copy of \"%s\" stored in the cache by its hash %s, since it could not be retrieved: %s", rel.Location, rel.Hash, err)
+				return pinned, pinnedErr
+			}
+		}
 		return nil, err
 	}
+	storeFileRef(resolved, ctx.cache)
 	if resolved.Hash != rel.Hash {
 		if checkHash {
 			log.Error().
@@ -203,14 +223,16 @@ func hashOfFile(path string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	hashBytes := sha256.Sum256(content)
-	hashSlice := make([]byte, len(hashBytes))
-	copy(hashSlice[:], hashBytes[:])
-	hash := hex.EncodeToString(hashSlice)
+	hash := hashOfBytes(content)
 	log.Debug().Msgf("Computed hash of %s as %s", path, hash)
 	return hash, nil
 }
 
+func hashOfBytes(content []byte) string {
+	hashBytes := sha256.Sum256(content)
+	return hex.EncodeToString(hashBytes[:])
+}
+
 func resolveRelLocalFileRef(src, rel string) (*FileRef, error) {
 	absPath, err := filepath.Abs(path.Join(path.Dir(src), rel))
 	if err != nil {
@@ -340,6 +362,84 @@ func resolveGitFileRef(loc string, u *GitURL, cache Cache) (*FileRef, error) {
 	return cachedFileRef(loc, cachedPath, GitRef)
 }
 
+// resolvePinnedFileRef looks up the remote file with the given hash in the
+// cache's content-addressed store, returning nil if it is not stored there
+// under the same file name as at its location. Local files are never looked
+// up.
+func resolvePinnedFileRef(loc, hash string, refType FileRefType, cache Cache) (*FileRef, error) {
+	if len(hash) == 0 || (refType != WebRef && refType != GitRef) {
+		return nil, nil
+	}
+	storedPath, err := cache.FromHash(hash, locationFileName(loc, refType))
+	if err != nil || len(storedPath) == 0 {
+		return nil, err
+	}
+	log.Debug().Msgf("Resolved location \"%s\" from the cache by its hash %s", loc, hash)
+	return &FileRef{
+		Location:    loc,
+		Hash:        hash,
+		localPath:   storedPath,
+		fileRefType: refType,
+	}, nil
+}
+
+// locationFileName extracts the name of the file at the given location,
+// ignoring any Git refs or URL query parameters.
+func locationFileName(loc string, refType FileRefType) string {
+	switch refType {
+	case GitRef:
+		if u, err := ParseGitURL(loc); err == nil {
+			return path.Base(u.Path)
+		}
+	case WebRef:
+		if u, err := url.Parse(loc); err == nil {
+			return path.Base(u.Path)
+		}
+	}
+	return path.Base(loc)
+}
+
+// storeFileRef adds the given remote file to the cache's content-addressed
+// store, so that it can later be resolved by its hash. The relative imports of
+// Dhall files are stored along with them, since stored files no longer sit
+// alongside the files they import.
+func storeFileRef(ref *FileRef, cache Cache) {
+	if ref == nil || (ref.fileRefType != WebRef && ref.fileRefType != GitRef) {
+		return
+	}
+	imports, err := storeFileRefImports(ref, cache)
+	if err != nil {
+		log.Warn().Msgf("Failed to store imports of %s in cache by their hashes: %s", ref.Location, err)
+	}
+	if _, err := cache.StoreByHash(ref.localPath, imports); err != nil {
+		log.Warn().Msgf("Failed to store %s in cache by its hash: %s", ref.Location, err)
+	}
+}
+
+// storeFileRefImports stores the files that the given file imports (directly
+// or indirectly) by their hashes, returning the hashes of the imported files
+// keyed by their paths relative to the given file's folder.
+func storeFileRefImports(ref *FileRef, cache Cache) (map[string]string, error) {
+	if ref.Ext() != ".dhall" {
+		return nil, nil
+	}
+	importFiles, err := dhallLocalImports(ref.localPath)
+	if err != nil {
+		return nil, err
+	}
+	imports := make(map[string]string)
+	for rel, importFile := range importFiles {
+		storedFile, err := cache.StoreByHash(importFile, nil)
+		if err != nil {
+			return nil, err
+		}
+		if imports[rel], err = hashOfFile(storedFile); err != nil {
+			return nil, err
+		}
+	}
+	return imports, nil
+}
+
 func cachedFileRef(loc, cachedPath string, fileRefType FileRefType) (*FileRef, error) {
 	hash, err := hashOfFile(cachedPath)
 	if err != nil {
@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
@@ -164,12 +165,9 @@ func gitFetchAndCheckout(repoURL, localPath, ref string) error {
 		return fmt.Errorf("failed to fetch from origin for Git repository %s: %v", repoURL, err)
 	}
 
-	cmd = exec.Command("git", "checkout", ref)
-	cmd.Dir = localPath
-	output, err = cmd.CombinedOutput()
-	log.Debug().Msgf("git checkout output:\n%s\n", string(output))
+	output, err = gitCheckout(repoURL, localPath, ref)
 	if err != nil {
-		return fmt.Errorf("failed to checkout \"%s\" for Git repository %s: %v", ref, repoURL, err)
+		return err
 	}
 	// For the case where the ref is a branch, we may need to merge.
 	// We cannot always pull because we may want to specify a specific commit
@@ -180,6 +178,44 @@ func gitFetchAndCheckout(repoURL, localPath, ref string) error {
 	return nil
 }
 
+// gitCheckout checks out the given ref in the local copy of a repository
+// without contacting its origin, returning the output of the checkout.
+func gitCheckout(repoURL, localPath, ref string) ([]byte, error) {
+	cmd := exec.Command("git", "checkout", ref)
+	cmd.Dir = localPath
+	output, err := cmd.CombinedOutput()
+	log.Debug().Msgf("git checkout output:\n%s\n", string(output))
+	if err != nil {
+		return nil, fmt.Errorf("failed to checkout \"%s\" for Git repository %s: %v", ref, repoURL, err)
+	}
+	return output, nil
+}
+
+// gitFsck verifies the integrity of all of the objects in the given
+// repository.
+func gitFsck(repoPath string) error {
+	cmd := exec.Command("git", "fsck", "--no-dangling", "--no-progress")
+	cmd.Dir = repoPath
+	output, err := cmd.CombinedOutput()
+	log.Debug().Msgf("git fsck output:\n%s\n", string(output))
+	if err != nil {
+		return fmt.Errorf("integrity check failed for Git repository %s: %s", repoPath, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// gitRemoteURL returns the URL of the given repository's origin, or an empty
+// string if it has none.
+func gitRemoteURL(repoPath string) string {
+	cmd := exec.Command("git", "config", "--get", "remote.origin.url")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
 func isGitRepo(repoPath string) bool {
 	cmd := exec.Command("git", "status")
 	cmd.Dir = repoPath
@@ -314,6 +350,150 @@ func gitPush(repoPath string) error {
 	return nil
 }
 
+// gitLastCommit returns the hash and commit time of the most recent commit in
+// the given repository that touched any of the specified paths. If none of the
+// paths have been committed yet, the hash is empty.
+func gitLastCommit(repoPath string, paths []string) (string, time.Time, error) {
+	cmd := exec.Command("git", append([]string{"log", "-1", "--format=%H %ct", "--"}, paths...)...)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	log.Debug().Msgf("git log output:\n%s\n", string(output))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("git log failed: %s", err)
+	}
+	parts := strings.Fields(string(output))
+	if len(parts) != 2 {
+		return "", time.Time{}, nil
+	}
+	timestamp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to parse commit timestamp \"%s\": %s", parts[1], err)
+	}
+	return parts[0], time.Unix(timestamp, 0).UTC(), nil
+}
+
+// gitHasChanges checks whether any of the given paths in the specified
+// repository have uncommitted (or untracked) changes.
+func gitHasChanges(repoPath string, paths []string) (bool, error) {
+	cmd := exec.Command("git", append([]string{"status", "--porcelain", "--"}, paths...)...)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	log.Debug().Msgf("git status output:\n%s\n", string(output))
+	if err != nil {
+		return false, fmt.Errorf("git status failed: %s", err)
+	}
+	return len(strings.TrimSpace(string(output))) > 0, nil
+}
+
+// gitRepoRoot returns the top-level folder of the Git repository containing the
+// given folder.
+func gitRepoRoot(dir string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	log.Debug().Msgf("git rev-parse output:\n%s\n", string(output))
+	if err != nil {
+		return "", fmt.Errorf("failed to find root of Git repository containing %s: %s", dir, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// gitShowFile returns the content of the file at the given path (relative to
+// the root of the repository) as of the specified commit.
+func gitShowFile(repoPath, commit, relPath string) ([]byte, error) {
+	cmd := exec.Command("git", "show", commit+":"+relPath)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s at commit %s: %s", relPath, commit, err)
+	}
+	return output, nil
+}
+
+// gitFindFileVersion searches the history of the current branch of the given
+// repository for the most recent commit at which the file at the specified
+// path (relative to the root of the repository) had the given SHA256 hash. If
+// no such commit can be found, the returned commit is empty.
+func gitFindFileVersion(repoPath, relPath, hash string) (string, error) {
+	cmd := exec.Command("git", "log", "--format=%H", "--", relPath)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	log.Debug().Msgf("git log output:\n%s\n", string(output))
+	if err != nil {
+		return "", fmt.Errorf("git log failed: %s", err)
+	}
+	// we only need to look at the commits that changed the file, since its
+	// content stays the same in between them
+	for _, commit := range strings.Fields(string(output)) {
+		content, err := gitShowFile(repoPath, commit, relPath)
+		if err != nil {
+			// the file may have been deleted in this commit
+			log.Debug().Msgf("Skipping commit %s: %s", commit, err)
+			continue
+		}
+		if hashOfBytes(content) == hash {
+			return commit, nil
+		}
+	}
+	return "", nil
+}
+
+// gitLogEntry describes a single commit in a repository's history.
+type gitLogEntry struct {
+	hash    string
+	time    time.Time
+	subject string
+	tags    []string
+}
+
+// gitLog lists the commits in the given range (e.g. "abc123..HEAD") that
+// touched any of the specified paths, most recent first.
+func gitLog(repoPath, revRange string, paths []string) ([]*gitLogEntry, error) {
+	cmd := exec.Command("git", append([]string{"log", "--format=%H%x1f%ct%x1f%s%x1f%D", revRange, "--"}, paths...)...)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	log.Debug().Msgf("git log output:\n%s\n", string(output))
+	if err != nil {
+		return nil, fmt.Errorf("git log failed: %s", err)
+	}
+	entries := make([]*gitLogEntry, 0)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) != 4 {
+			continue
+		}
+		timestamp, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse commit timestamp \"%s\": %s", fields[1], err)
+		}
+		entry := &gitLogEntry{
+			hash:    fields[0],
+			time:    time.Unix(timestamp, 0).UTC(),
+			subject: fields[2],
+		}
+		for _, ref := range strings.Split(fields[3], ", ") {
+			if strings.HasPrefix(ref, "tag: ") {
+				entry.tags = append(entry.tags, strings.TrimPrefix(ref, "tag: "))
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// gitTagsSince lists the tags reachable from the current HEAD of the given
+// repository, but not from the specified commit, in version order.
+func gitTagsSince(repoPath, commit string) ([]string, error) {
+	cmd := exec.Command("git", "tag", "--merged", "HEAD", "--no-merged", commit, "--sort=v:refname")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	log.Debug().Msgf("git tag output:\n%s\n", string(output))
+	if err != nil {
+		return nil, fmt.Errorf("git tag failed: %s", err)
+	}
+	return strings.Fields(string(output)), nil
+}
+
 func gitPullAndPush(repoPath string) error {
 	if err := gitPull(repoPath); err != nil {
 		return err
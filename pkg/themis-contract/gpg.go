@@ -0,0 +1,115 @@
+package themis_contract
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// gpgDetachSign currently wraps simple calls to the `gpg` executable on the
+// local system to produce an ASCII-armored detached signature of the given
+// data, using the key identified by `keyID`. On success, returns the
+// fingerprint of the key that actually produced the signature.
+func gpgDetachSign(keyID string, data []byte, outputFile string) (string, error) {
+	cmd := exec.Command(
+		"gpg",
+		"--batch",
+		"--yes",
+		"--armor",
+		"--status-fd", "1",
+		"--local-user", keyID,
+		"--output", outputFile,
+		"--detach-sign",
+	)
+	cmd.Stdin = bytes.NewReader(data)
+	output, err := cmd.CombinedOutput()
+	log.Debug().Msgf("gpg --detach-sign output:\n%s\n", string(output))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign with GPG key \"%s\": %v", keyID, err)
+	}
+	// [GNUPG:] SIG_CREATED <type> <pk_algo> <hash_algo> <class> <timestamp> <fingerprint>
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 8 && fields[0] == "[GNUPG:]" && fields[1] == "SIG_CREATED" {
+			return fields[7], nil
+		}
+	}
+	return "", fmt.Errorf("failed to determine the fingerprint of GPG key \"%s\" after signing", keyID)
+}
+
+// gpgVerification captures the outcome of verifying a detached signature.
+type gpgVerification struct {
+	good        bool   // Did the signature verify successfully?
+	trusted     bool   // Is the signing key fully or ultimately trusted in the local keyring?
+	keyID       string // The ID of the key that produced the signature (if known).
+	fingerprint string // The fingerprint of the key that produced the signature (if the signature is valid).
+	userID      string // The primary user ID of the key that produced the signature (if known).
+	reason      string // If the signature is not good, a short description of why.
+}
+
+// gpgVerifyDetached uses the `gpg` executable to verify the given detached
+// signature over the specified data. An error is only returned if we could not
+// execute `gpg` - a bad signature is reported through the returned
+// verification.
+//
+// User IDs are self-asserted, so a good signature alone does not tell us who
+// produced it. We therefore also report the fingerprint of the signing key and
+// whether the local keyring fully or ultimately trusts it.
+func gpgVerifyDetached(sigFile string, data []byte) (*gpgVerification, error) {
+	cmd := exec.Command("gpg", "--batch", "--status-fd", "1", "--verify", sigFile, "-")
+	cmd.Stdin = bytes.NewReader(data)
+	output, err := cmd.CombinedOutput()
+	log.Debug().Msgf("gpg --verify output:\n%s\n", string(output))
+	v := &gpgVerification{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if !strings.HasPrefix(line, "[GNUPG:] ") {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(line, "[GNUPG:] "), " ", 3)
+		switch parts[0] {
+		case "GOODSIG":
+			v.good = true
+			if len(parts) == 3 {
+				v.keyID, v.userID = parts[1], parts[2]
+			}
+		case "VALIDSIG":
+			if len(parts) > 1 {
+				v.fingerprint = parts[1]
+			}
+		case "TRUST_FULLY", "TRUST_ULTIMATE":
+			v.trusted = true
+		case "BADSIG":
+			v.reason = "bad signature"
+			if len(parts) == 3 {
+				v.keyID, v.userID = parts[1], parts[2]
+			}
+		case "NO_PUBKEY":
+			v.reason = fmt.Sprintf("public key %s not found in keyring", parts[1])
+		case "EXPKEYSIG", "REVKEYSIG":
+			v.reason = "signing key has expired or has been revoked"
+		case "ERRSIG":
+			if len(v.reason) == 0 {
+				v.reason = "signature could not be checked"
+			}
+		}
+	}
+	// a non-zero exit code means the signature cannot be trusted, regardless
+	// of what the status output says
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return nil, fmt.Errorf("failed to execute gpg: %v", err)
+		}
+		v.good = false
+	}
+	if v.good && len(v.fingerprint) == 0 {
+		v.good = false
+		v.reason = "signature is missing the signing key's fingerprint"
+	}
+	if !v.good && len(v.reason) == 0 {
+		v.reason = "signature could not be verified"
+	}
+	return v, nil
+}
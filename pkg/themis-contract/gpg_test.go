@@ -0,0 +1,87 @@
+package themis_contract_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+)
+
+func TestGPGDetachSign(t *testing.T) {
+	tempDir := setupTestGPG(t)
+	generateTestGPGKey(t, "Michael Anderson <manderson@somewhere.com>")
+
+	statement := []byte("Some statement to sign\n")
+	sigFile := path.Join(tempDir, "statement.txt.asc")
+	fingerprint, err := contract.GPGDetachSign("manderson@somewhere.com", statement, sigFile)
+	if err != nil {
+		t.Fatalf("failed to sign statement: %v", err)
+	}
+	output, err := exec.Command("gpg", "--batch", "--with-colons", "--list-keys", "manderson@somewhere.com").Output()
+	if err != nil {
+		t.Fatalf("failed to list GPG keys: %v", err)
+	}
+	if !strings.Contains(string(output), "fpr:::::::::"+fingerprint+":") {
+		t.Errorf("expected signing key fingerprint %s to be listed in:\n%s", fingerprint, output)
+	}
+
+	inputFile := path.Join(tempDir, "statement.txt")
+	if err := ioutil.WriteFile(inputFile, statement, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := exec.Command("gpg", "--batch", "--verify", sigFile, inputFile).CombinedOutput(); err != nil {
+		t.Errorf("expected detached signature to verify, but got: %v\n%s", err, output)
+	}
+	if err := ioutil.WriteFile(inputFile, []byte("Some other statement\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.Command("gpg", "--batch", "--verify", sigFile, inputFile).Run(); err == nil {
+		t.Error("expected detached signature not to verify once the statement changed")
+	}
+	if _, err := contract.GPGDetachSign("nobody@somewhere.com", statement, sigFile); err == nil {
+		t.Error("expected signing with an unknown key to fail")
+	}
+}
+
+// setupTestGPG skips the test if GPG is not available, and otherwise points
+// GPG at an empty, temporary home folder for the duration of the test. Returns
+// a temporary folder for the test to use, which is removed once the test is
+// done.
+func setupTestGPG(t *testing.T) string {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not available")
+	}
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory for testing: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tempDir) })
+	gnupgHome := path.Join(tempDir, "gnupg")
+	if err := os.Mkdir(gnupgHome, 0700); err != nil {
+		t.Fatal(err)
+	}
+	prevGnupgHome, hadGnupgHome := os.LookupEnv("GNUPGHOME")
+	os.Setenv("GNUPGHOME", gnupgHome)
+	t.Cleanup(func() {
+		_ = exec.Command("gpgconf", "--kill", "gpg-agent").Run()
+		if hadGnupgHome {
+			os.Setenv("GNUPGHOME", prevGnupgHome)
+		} else {
+			os.Unsetenv("GNUPGHOME")
+		}
+	})
+	return tempDir
+}
+
+// generateTestGPGKey generates a signing key without a passphrase for the
+// given user ID in the current GPG home folder.
+func generateTestGPGKey(t *testing.T, userID string) {
+	output, err := exec.Command("gpg", "--batch", "--passphrase", "", "--quick-gen-key", userID, "ed25519", "sign", "0").CombinedOutput()
+	if err != nil {
+		t.Fatalf("failed to generate GPG key for %s: %v\n%s", userID, err, output)
+	}
+}
@@ -0,0 +1,321 @@
+package themis_contract
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// LintIssueKind is a string-based enumeration type.
+type LintIssueKind string
+
+const (
+	UndefinedParam   LintIssueKind = "undefined parameter"
+	UnusedParam      LintIssueKind = "unused parameter"
+	UndefinedPartial LintIssueKind = "undefined partial"
+)
+
+// LintIssue describes a single problem found while linting a contract's
+// template.
+type LintIssue struct {
+	Kind LintIssueKind
+	Name string // The parameter or partial name (dotted, for nested parameters).
+	File string // The template file in which the issue was found (empty for unused parameters).
+	Line int    // The line in the template file on which the issue was found (if any).
+}
+
+func (i *LintIssue) String() string {
+	if len(i.File) == 0 {
+		return fmt.Sprintf("%s: %s", i.Kind, i.Name)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s", i.File, i.Line, i.Kind, i.Name)
+}
+
+// IsError indicates whether this issue would prevent the contract from
+// rendering as intended (as opposed to just being a warning).
+func (i *LintIssue) IsError() bool {
+	return i.Kind != UnusedParam
+}
+
+// Lint checks the contract's template (and its partials) against its
+// parameters. It reports all references in the template to parameters that do
+// not exist, as well as all parameters that are never referenced by the
+// template. Only Mustache templates can currently be linted.
+func (c *Contract) Lint() ([]*LintIssue, error) {
+	if c.Template.Format != Mustache && c.Template.Format != "" {
+		return nil, fmt.Errorf("linting is currently only supported for Mustache templates, not %s", c.Template.Format)
+	}
+	l := &mustacheLinter{
+		partials: c.Template.partialFiles(),
+		used:     make(map[string]bool),
+		issues:   make([]*LintIssue, 0),
+		visiting: make(map[string]bool),
+	}
+	params, err := withAllSignatoryFields(c.params)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.lintFile(c.Template.File.localPath, []*lintFrame{{value: params}}); err != nil {
+		return nil, err
+	}
+	l.findUnused(params, "")
+	return l.issues, nil
+}
+
+// isSyntheticParam checks whether the parameter at the given path is one that
+// is synthesized or required by Themis Contract, as opposed to one that only
+// exists for the template's sake.
+func isSyntheticParam(paramPath string) bool {
+	switch paramPath {
+	case "signatories", paramIsFullySigned, paramUnsignedSignatories, paramDraft:
+		return true
+	}
+	return strings.HasPrefix(paramPath, "signatory_")
+}
+
+// lintFrame is a single entry in the Mustache context stack.
+type lintFrame struct {
+	value    interface{}
+	path     string // The dotted path to this value in the parameters (empty if not tracked, e.g. within lists).
+	wildcard bool   // If true, any name resolves successfully against this frame.
+}
+
+type mustacheLinter struct {
+	partials map[string]string
+	used     map[string]bool
+	issues   []*LintIssue
+	visiting map[string]bool // Files currently being linted (to prevent infinite recursion via partials).
+}
+
+func (l *mustacheLinter) lintFile(filename string, stack []*lintFrame) error {
+	if l.visiting[filename] {
+		return nil
+	}
+	l.visiting[filename] = true
+	defer delete(l.visiting, filename)
+
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	tags, err := scanMustacheTags(string(content))
+	if err != nil {
+		return fmt.Errorf("failed to parse template %s: %s", filename, err)
+	}
+	log.Debug().Msgf("Found %d tags in template %s", len(tags), filename)
+	_, err = l.lintTags(filename, tags, stack, "")
+	return err
+}
+
+// lintTags walks the given tags until it reaches the end of the section with
+// the given name (or the end of the tags if the name is empty). It returns the
+// number of tags consumed.
+func (l *mustacheLinter) lintTags(filename string, tags []*mustacheTag, stack []*lintFrame, section string) (int, error) {
+	for i := 0; i < len(tags); i++ {
+		tag := tags[i]
+		switch tag.kind {
+		case '/':
+			if tag.name != section {
+				return 0, fmt.Errorf("line %d: unexpected closing tag for section \"%s\"", tag.line, tag.name)
+			}
+			return i + 1, nil
+
+		case '>':
+			partialFile, ok := l.partials[tag.name]
+			if !ok {
+				l.issues = append(l.issues, &LintIssue{Kind: UndefinedPartial, Name: tag.name, File: filename, Line: tag.line})
+				continue
+			}
+			if err := l.lintFile(partialFile, stack); err != nil {
+				return 0, err
+			}
+
+		case '#', '^':
+			value, found := l.resolve(tag.name, stack)
+			if !found {
+				l.issues = append(l.issues, &LintIssue{Kind: UndefinedParam, Name: tag.name, File: filename, Line: tag.line})
+			}
+			sectionStack := stack
+			// inverted sections are only rendered when their value is empty,
+			// so they don't introduce a new context
+			if tag.kind == '#' {
+				sectionStack = append(stack[:len(stack):len(stack)], sectionFrame(value, found))
+			}
+			consumed, err := l.lintTags(filename, tags[i+1:], sectionStack, tag.name)
+			if err != nil {
+				return 0, err
+			}
+			i += consumed
+
+		default:
+			if _, found := l.resolve(tag.name, stack); !found {
+				l.issues = append(l.issues, &LintIssue{Kind: UndefinedParam, Name: tag.name, File: filename, Line: tag.line})
+			}
+		}
+	}
+	if len(section) > 0 {
+		return 0, fmt.Errorf("section \"%s\" is never closed", section)
+	}
+	return len(tags), nil
+}
+
+// sectionFrame produces the context frame introduced by a section with the
+// given value. For lists, this is an amalgamation of all of the items in the
+// list.
+func sectionFrame(value *lintFrame, found bool) *lintFrame {
+	if !found {
+		return &lintFrame{wildcard: true}
+	}
+	list, ok := value.value.([]interface{})
+	if !ok {
+		return value
+	}
+	merged := make(map[string]interface{})
+	for _, item := range list {
+		if m, ok := item.(map[string]interface{}); ok {
+			for k, v := range m {
+				merged[k] = v
+			}
+		}
+	}
+	return &lintFrame{value: merged}
+}
+
+// resolve looks up the given (possibly dotted) name in the context stack the
+// same way Mustache does, marking all parameters along the way as used.
+func (l *mustacheLinter) resolve(name string, stack []*lintFrame) (*lintFrame, bool) {
+	if name == "." {
+		return stack[len(stack)-1], true
+	}
+	parts := strings.Split(name, ".")
+	for i := len(stack) - 1; i >= 0; i-- {
+		frame := stack[i]
+		if frame.wildcard {
+			return frame, true
+		}
+		m, ok := frame.value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, exists := m[parts[0]]; !exists {
+			continue
+		}
+		// once the first part of the name has been found, the remainder must be
+		// found within it
+		for _, part := range parts {
+			m, ok := frame.value.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			v, exists := m[part]
+			if !exists {
+				return nil, false
+			}
+			frame = &lintFrame{value: v, path: joinParamPath(frame.path, part, i == 0 || len(frame.path) > 0)}
+			if len(frame.path) > 0 {
+				l.used[frame.path] = true
+			}
+		}
+		return frame, true
+	}
+	return nil, false
+}
+
+func joinParamPath(parent, name string, tracked bool) string {
+	if !tracked {
+		return ""
+	}
+	if len(parent) == 0 {
+		return name
+	}
+	return parent + "." + name
+}
+
+// findUnused reports all parameters (recursing into nested objects) that have
+// not been used. If an object is used as a whole (e.g. `{{client}}`) but none
+// of its fields are, its fields are still reported.
+func (l *mustacheLinter) findUnused(params map[string]interface{}, prefix string) {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		paramPath := joinParamPath(prefix, k, true)
+		if isSyntheticParam(paramPath) {
+			continue
+		}
+		if nested, ok := params[k].(map[string]interface{}); ok && len(nested) > 0 {
+			l.findUnused(nested, paramPath)
+			continue
+		}
+		if !l.used[paramPath] {
+			l.issues = append(l.issues, &LintIssue{Kind: UnusedParam, Name: paramPath})
+		}
+	}
+}
+
+// mustacheTag is a single tag in a Mustache template.
+type mustacheTag struct {
+	kind rune // One of '#', '^', '/', '>', or 0 for variables.
+	name string
+	line int
+}
+
+// scanMustacheTags extracts all of the variable, section and partial tags from
+// the given Mustache template content, ignoring comments and handling changes
+// of delimiters.
+func scanMustacheTags(content string) ([]*mustacheTag, error) {
+	tags := make([]*mustacheTag, 0)
+	open, close := "{{", "}}"
+	pos := 0
+	for {
+		start := strings.Index(content[pos:], open)
+		if start < 0 {
+			return tags, nil
+		}
+		start += pos
+		line := strings.Count(content[:start], "\n") + 1
+		inner := start + len(open)
+		closing := close
+		// triple mustaches (unescaped variables) only apply when using the
+		// default delimiters
+		if open == "{{" && strings.HasPrefix(content[inner:], "{") {
+			inner++
+			closing = "}" + close
+		}
+		end := strings.Index(content[inner:], closing)
+		if end < 0 {
+			return nil, fmt.Errorf("line %d: unclosed tag", line)
+		}
+		body := strings.TrimSpace(content[inner : inner+end])
+		pos = inner + end + len(closing)
+		if len(body) == 0 {
+			return nil, fmt.Errorf("line %d: empty tag", line)
+		}
+
+		switch body[0] {
+		case '!':
+			// comment
+
+		case '=':
+			delims := strings.Fields(strings.TrimSuffix(strings.TrimPrefix(body, "="), "="))
+			if len(delims) != 2 {
+				return nil, fmt.Errorf("line %d: invalid delimiter change: %s", line, body)
+			}
+			open, close = delims[0], delims[1]
+
+		case '#', '^', '/', '>':
+			tags = append(tags, &mustacheTag{kind: rune(body[0]), name: strings.TrimSpace(body[1:]), line: line})
+
+		case '&':
+			tags = append(tags, &mustacheTag{name: strings.TrimSpace(body[1:]), line: line})
+
+		default:
+			tags = append(tags, &mustacheTag{name: body, line: line})
+		}
+	}
+}
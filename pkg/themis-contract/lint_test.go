@@ -0,0 +1,81 @@
+package themis_contract_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+)
+
+const lintTestParams = `{
+	"client": {"name": "Acme Corp", "address": "1 Main Street"},
+	"jurisdiction": "Switzerland",
+	"unused": "value",
+	"signatories": [
+		{"id": "alice", "name": "Alice", "email": "alice@somewhere.com"}
+	]
+}`
+
+const lintTestTemplate = `# Agreement with {{client.name}}
+
+{{#client}}Located at {{adress}}.{{/client}}
+
+{{> governing_law}}
+{{> confidentiality}}
+
+{{#signatories}}
+![Signature]({{signature}}) {{name}} ({{signed_date}})
+{{/signatories}}
+
+{{signatory_alice.name}} {{signatory_bob.name}}
+`
+
+func TestMustacheTemplateLinting(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	files := map[string]string{
+		"params.json":      lintTestParams,
+		"contract.md":      lintTestTemplate,
+		"governing-law.md": "Governed by the laws of {{jurisdiction}}.\n",
+		"contract.json":    `{"params": {"location": "./params.json"}, "template": {"format": "Mustache", "file": {"location": "./contract.md"}, "partials": [{"name": "governing_law", "file": {"location": "./governing-law.md"}}]}}`,
+	}
+	for filename, content := range files {
+		if err := ioutil.WriteFile(path.Join(tempDir, filename), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := contract.NewTestContext(nil, contract.NewTestProfile("test", "", nil))
+	if err := contract.Update(path.Join(tempDir, "contract.json"), ctx); err != nil {
+		t.Fatal(err)
+	}
+	c, err := contract.Load(path.Join(tempDir, "contract.json"), ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := c.Lint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	templateFile := path.Join(tempDir, "contract.md")
+	expected := []string{
+		fmt.Sprintf("%s:3: undefined parameter: adress", templateFile),
+		fmt.Sprintf("%s:6: undefined partial: confidentiality", templateFile),
+		fmt.Sprintf("%s:12: undefined parameter: signatory_bob.name", templateFile),
+		"unused parameter: client.address",
+		"unused parameter: unused",
+	}
+	if len(issues) != len(expected) {
+		t.Fatalf("expected %d issues, but got %d: %v", len(expected), len(issues), issues)
+	}
+	for i, issue := range issues {
+		if issue.String() != expected[i] {
+			t.Errorf("expected issue %d to be \"%s\", but got \"%s\"", i, expected[i], issue.String())
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package themis_contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const signatureManifestFilename = "signatures.json"
+
+// SignatureManifest keeps track of all of the signatures that have been
+// applied to a contract, binding each signature to the specific version of the
+// contract that was signed. It is stored alongside the contract.
+type SignatureManifest struct {
+	Signatures []*SignatureRecord `json:"signatures"`
+
+	path string // The local filesystem path to the manifest file.
+}
+
+// SignatureRecord captures the details of a single signatory's signature at the
+// time of signing.
+type SignatureRecord struct {
+	SignatoryID       string          `json:"signatory_id"`                 // The ID of the signatory on behalf of whom the contract was signed.
+	Email             string          `json:"email"`                        // The signatory's e-mail address at the time of signing.
+	Image             string          `json:"image,omitempty"`              // The file name of the signature image, relative to the contract.
+	ImageHash         string          `json:"image_hash,omitempty"`         // The SHA256 hash of the signature image.
+	KeyID             string          `json:"key_id,omitempty"`             // The fingerprint of the GPG key used to sign (if any).
+	DetachedSignature string          `json:"detached_signature,omitempty"` // The file name of the detached signature, relative to the contract.
+	SSHPublicKey      string          `json:"ssh_public_key,omitempty"`     // The public key of the SSH key used to sign (if any).
+	SSHSignature      string          `json:"ssh_signature,omitempty"`      // The file name of the SSH signature, relative to the contract.
+	Timestamp         time.Time       `json:"timestamp"`                    // When the contract was signed (UTC).
+	Hashes            *ContractHashes `json:"hashes"`                       // The hashes of the contract's components at the time of signing.
+}
+
+// loadSignatureManifest will attempt to load the signature manifest from the
+// given contract directory. If no manifest exists yet, an empty one is
+// returned.
+func loadSignatureManifest(contractDir string) (*SignatureManifest, error) {
+	manifestPath := path.Join(contractDir, signatureManifestFilename)
+	manifest := &SignatureManifest{
+		Signatures: make([]*SignatureRecord, 0),
+		path:       manifestPath,
+	}
+	content, err := ioutil.ReadFile(manifestPath)
+	if os.IsNotExist(err) {
+		log.Debug().Msgf("No signature manifest present at %s", manifestPath)
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signature manifest %s: %s", manifestPath, err)
+	}
+	if err := json.Unmarshal(content, manifest); err != nil {
+		return nil, fmt.Errorf("failed to interpret signature manifest %s: %s", manifestPath, err)
+	}
+	for _, r := range manifest.Signatures {
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("invalid signature record in %s: %s", manifestPath, err)
+		}
+	}
+	log.Debug().Msgf("Loaded signature manifest: %v", manifest)
+	return manifest, nil
+}
+
+// Save writes the manifest to its local path.
+func (m *SignatureManifest) Save() error {
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to convert signature manifest to JSON: %s", err)
+	}
+	log.Debug().Msgf("Writing signature manifest: %s", m.path)
+	return ioutil.WriteFile(m.path, content, 0644)
+}
+
+// Record adds the given signature record to the manifest, replacing any prior
+// record for the same signatory.
+func (m *SignatureManifest) Record(r *SignatureRecord) {
+	for i, existing := range m.Signatures {
+		if existing.SignatoryID == r.SignatoryID {
+			m.Signatures[i] = r
+			return
+		}
+	}
+	m.Signatures = append(m.Signatures, r)
+}
+
+// ForSignatory returns the signature record for the signatory with the given
+// ID, or nil if that signatory has not signed yet.
+func (m *SignatureManifest) ForSignatory(id string) *SignatureRecord {
+	for _, r := range m.Signatures {
+		if r.SignatoryID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// Stale returns the records of all signatures that were applied to a version
+// of the contract other than the one described by the given hashes.
+func (m *SignatureManifest) Stale(hashes *ContractHashes) []*SignatureRecord {
+	stale := make([]*SignatureRecord, 0)
+	for _, r := range m.Signatures {
+		if r.IsStale(hashes) {
+			stale = append(stale, r)
+		}
+	}
+	return stale
+}
+
+// Path returns the local filesystem path to the manifest file.
+func (m *SignatureManifest) Path() string {
+	return m.path
+}
+
+// validate ensures that the files referenced by the record are located in the
+// contract's folder itself, so that a manifest cannot point us at arbitrary
+// files elsewhere on the filesystem.
+func (r *SignatureRecord) validate() error {
+	for _, filename := range []string{r.Image, r.DetachedSignature, r.SSHSignature} {
+		if len(filename) == 0 {
+			continue
+		}
+		if filename != path.Base(filename) || filename == "." || filename == ".." || strings.Contains(filename, "\\") {
+			return fmt.Errorf("signatory \"%s\" refers to file \"%s\", which is not a file name in the contract's folder", r.SignatoryID, filename)
+		}
+	}
+	if r.Hashes == nil {
+		return fmt.Errorf("signatory \"%s\" is missing the contract hashes at the time of signing", r.SignatoryID)
+	}
+	return nil
+}
+
+func (m *SignatureManifest) String() string {
+	return fmt.Sprintf("SignatureManifest{Signatures: %v, path: \"%s\"}", m.Signatures, m.path)
+}
+
+// IsStale checks whether this signature was applied to a version of the
+// contract other than the one described by the given hashes.
+func (r *SignatureRecord) IsStale(hashes *ContractHashes) bool {
+	return len(changedComponents(r.Hashes, hashes)) > 0
+}
+
+func (r *SignatureRecord) String() string {
+	return fmt.Sprintf(
+		"SignatureRecord{SignatoryID: \"%s\", Email: \"%s\", Image: \"%s\", KeyID: \"%s\", DetachedSignature: \"%s\", SSHSignature: \"%s\", Timestamp: \"%s\", Hashes: %v}",
+		r.SignatoryID,
+		r.Email,
+		r.Image,
+		r.KeyID,
+		r.DetachedSignature,
+		r.SSHSignature,
+		r.Timestamp.Format(time.RFC3339),
+		r.Hashes,
+	)
+}
@@ -0,0 +1,170 @@
+package themis_contract_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+)
+
+func TestSignatureManifestPersistence(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory for testing: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	manifest, err := contract.LoadSignatureManifest(tempDir)
+	if err != nil {
+		t.Fatalf("expected to be able to load missing manifest, but got error: %v", err)
+	}
+	if len(manifest.Signatures) != 0 {
+		t.Fatalf("expected new manifest to be empty, but got %d signature(s)", len(manifest.Signatures))
+	}
+
+	signedAt := time.Date(2020, 9, 1, 12, 0, 0, 0, time.UTC)
+	manifest.Record(&contract.SignatureRecord{
+		SignatoryID: "manderson",
+		Email:       "manderson@somewhere.com",
+		Timestamp:   signedAt,
+		Hashes:      &contract.ContractHashes{Contract: "a", Params: "b", Template: "c"},
+	})
+	manifest.Record(&contract.SignatureRecord{
+		SignatoryID: "bsavvy",
+		Email:       "bronwyn@savvy.com",
+		Timestamp:   signedAt,
+		Hashes:      &contract.ContractHashes{Contract: "a", Params: "b", Template: "c"},
+	})
+	// signing again must replace the signatory's previous record
+	manifest.Record(&contract.SignatureRecord{
+		SignatoryID: "manderson",
+		Email:       "manderson@somewhere.com",
+		Timestamp:   signedAt.Add(time.Hour),
+		Hashes:      &contract.ContractHashes{Contract: "d", Params: "e", Template: "f"},
+	})
+	if err := manifest.Save(); err != nil {
+		t.Fatalf("failed to save manifest: %v", err)
+	}
+
+	loaded, err := contract.LoadSignatureManifest(tempDir)
+	if err != nil {
+		t.Fatalf("failed to load saved manifest: %v", err)
+	}
+	if len(loaded.Signatures) != 2 {
+		t.Fatalf("expected 2 signatures in manifest, but got %d", len(loaded.Signatures))
+	}
+	r := loaded.ForSignatory("manderson")
+	if r == nil {
+		t.Fatalf("expected to find signature record for \"manderson\"")
+	}
+	if !r.Timestamp.Equal(signedAt.Add(time.Hour)) || r.Hashes.Contract != "d" {
+		t.Errorf("expected latest signature record for \"manderson\", but got %v", r)
+	}
+	if loaded.ForSignatory("nobody") != nil {
+		t.Errorf("expected no signature record for unknown signatory")
+	}
+}
+
+func TestSignatureManifestRejectsPathsOutsideContract(t *testing.T) {
+	testCases := []string{
+		`{"signatures": [{"signatory_id": "manderson", "image": "../../secret.png", "timestamp": "2020-09-01T12:00:00Z", "hashes": {}}]}`,
+		`{"signatures": [{"signatory_id": "manderson", "detached_signature": "/etc/passwd", "timestamp": "2020-09-01T12:00:00Z", "hashes": {}}]}`,
+		`{"signatures": [{"signatory_id": "manderson", "detached_signature": "..", "timestamp": "2020-09-01T12:00:00Z", "hashes": {}}]}`,
+		`{"signatures": [{"signatory_id": "manderson", "image": "sig--manderson.png", "timestamp": "2020-09-01T12:00:00Z"}]}`,
+	}
+	for i, tc := range testCases {
+		tempDir, err := ioutil.TempDir("", "")
+		if err != nil {
+			t.Fatalf("failed to create temporary directory for testing: %v", err)
+		}
+		defer os.RemoveAll(tempDir)
+		if err := ioutil.WriteFile(path.Join(tempDir, "signatures.json"), []byte(tc), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := contract.LoadSignatureManifest(tempDir); err == nil {
+			t.Errorf("case %d: expected manifest to be rejected", i)
+		}
+	}
+}
+
+func TestSignatureGoesStale(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory for testing: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	ctx := contract.NewTestContext(nil, contract.NewTestProfile("test", "", nil))
+	files := map[string]string{
+		"params.json":   `{"signatories": [{"id": "alice", "name": "Alice", "email": "alice@somewhere.com"}]}`,
+		"contract.md":   "Payment is due within 30 days.\n",
+		"contract.json": `{"params": {"location": "./params.json"}, "template": {"format": "Mustache", "file": {"location": "./contract.md"}}}`,
+	}
+	for filename, content := range files {
+		if err := ioutil.WriteFile(path.Join(tempDir, filename), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	contractFile := path.Join(tempDir, "contract.json")
+	if err := contract.Update(contractFile, ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := contract.Load(contractFile, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedHashes := c.Hashes()
+	manifest, err := contract.LoadSignatureManifest(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest.Record(&contract.SignatureRecord{
+		SignatoryID: "alice",
+		Email:       "alice@somewhere.com",
+		Timestamp:   time.Now().UTC(),
+		Hashes:      signedHashes,
+	})
+	if err := manifest.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if c, err = contract.Load(contractFile, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if alice := c.FindSignatoryById("alice"); alice.Stale {
+		t.Errorf("expected signature over the current contract not to be stale, but got: %v", alice)
+	}
+	if stale := manifest.Stale(c.Hashes()); len(stale) != 0 {
+		t.Errorf("expected no stale signatures, but got: %v", stale)
+	}
+
+	if err := ioutil.WriteFile(path.Join(tempDir, "contract.md"), []byte("Payment is due within 14 days.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := contract.Update(contractFile, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c, err = contract.Load(contractFile, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if alice := c.FindSignatoryById("alice"); !alice.Stale {
+		t.Errorf("expected signature to be stale after the template changed, but got: %v", alice)
+	}
+	stale := manifest.Stale(c.Hashes())
+	if len(stale) != 1 || stale[0].SignatoryID != "alice" {
+		t.Errorf("expected alice's signature to be stale, but got: %v", stale)
+	}
+
+	// compiling must refuse to render stale signatures unless explicitly
+	// allowed to
+	output := path.Join(tempDir, "contract.pdf")
+	if err := c.Compile(output, ctx); err == nil || !strings.Contains(err.Error(), "older version of the contract") {
+		t.Errorf("expected compilation to fail because of a stale signature, but got: %v", err)
+	}
+	if err := c.Compile(output, ctx.WithStaleSignatures(true)); err != nil && strings.Contains(err.Error(), "older version of the contract") {
+		t.Errorf("expected stale signatures to be allowed, but got: %v", err)
+	}
+}
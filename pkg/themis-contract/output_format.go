@@ -0,0 +1,82 @@
+package themis_contract
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// OutputFormat is a string-based enumeration type indicating the kind of
+// document to which a contract is compiled.
+type OutputFormat string
+
+const (
+	PDFOutput  OutputFormat = "pdf"
+	DOCXOutput OutputFormat = "docx"
+	ODTOutput  OutputFormat = "odt"
+	HTMLOutput OutputFormat = "html"
+	TextOutput OutputFormat = "txt"
+)
+
+// OutputFormats lists all of the output formats we support.
+var OutputFormats = []OutputFormat{PDFOutput, DOCXOutput, ODTOutput, HTMLOutput, TextOutput}
+
+// ParseOutputFormat interprets the given string as an output format.
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	for _, f := range OutputFormats {
+		if strings.ToLower(s) == string(f) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported output format \"%s\" (must be one of: %s)", s, outputFormatList())
+}
+
+// outputFormatForFile attempts to infer the output format from the extension
+// of the given output file name.
+func outputFormatForFile(filename string) (OutputFormat, error) {
+	ext := strings.TrimPrefix(path.Ext(filename), ".")
+	if ext == "htm" {
+		return HTMLOutput, nil
+	}
+	return ParseOutputFormat(ext)
+}
+
+// Ext returns the conventional file extension (including the leading ".") for
+// files of this format.
+func (f OutputFormat) Ext() string {
+	return "." + string(f)
+}
+
+// pandocDefaultsFile returns the name of the pandoc defaults file for this
+// output format. For backwards compatibility, PDF output uses the original
+// `pandoc-defaults.yaml` file.
+func (f OutputFormat) pandocDefaultsFile() string {
+	if f == PDFOutput {
+		return "pandoc-defaults.yaml"
+	}
+	return fmt.Sprintf("pandoc-defaults.%s.yaml", f)
+}
+
+// pandocArgs returns the format-specific arguments to pass to pandoc when
+// compiling a contract using the given profile.
+func (f OutputFormat) pandocArgs(profile *Profile) []string {
+	args := []string{"--defaults", path.Join(profile.Path(), f.pandocDefaultsFile())}
+	switch f {
+	case DOCXOutput, ODTOutput:
+		// style the document using the profile's reference document, if any
+		referenceDoc := path.Join(profile.Path(), "reference"+f.Ext())
+		if _, err := os.Stat(referenceDoc); err == nil {
+			args = append(args, "--reference-doc", referenceDoc)
+		}
+	}
+	return args
+}
+
+func outputFormatList() string {
+	formats := make([]string, len(OutputFormats))
+	for i, f := range OutputFormats {
+		formats[i] = string(f)
+	}
+	return strings.Join(formats, ", ")
+}
@@ -0,0 +1,40 @@
+package themis_contract_test
+
+import (
+	"testing"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+)
+
+func TestOutputFormatForFile(t *testing.T) {
+	testCases := []struct {
+		filename string
+		expected contract.OutputFormat
+		err      bool
+	}{
+		{"contract.pdf", contract.PDFOutput, false},
+		{"/path/to/contract.DOCX", contract.DOCXOutput, false},
+		{"contract.odt", contract.ODTOutput, false},
+		{"contract.htm", contract.HTMLOutput, false},
+		{"contract.html", contract.HTMLOutput, false},
+		{"contract.txt", contract.TextOutput, false},
+		{"contract.rtf", "", true},
+		{"contract", "", true},
+	}
+	for _, tc := range testCases {
+		actual, err := contract.OutputFormatForFile(tc.filename)
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected an error for %s, but got format %s", tc.filename, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %s: %s", tc.filename, err)
+			continue
+		}
+		if actual != tc.expected {
+			t.Errorf("expected format %s for %s, but got %s", tc.expected, tc.filename, actual)
+		}
+	}
+}
@@ -16,8 +16,12 @@ import (
 type ProfileParameter string
 
 const (
-	ProfileSignatureID   ProfileParameter = "signature-id"
-	ProfileContractsRepo ProfileParameter = "contracts-repo"
+	ProfileSignatureID       ProfileParameter = "signature-id"
+	ProfileContractsRepo     ProfileParameter = "contracts-repo"
+	ProfileSSHKey            ProfileParameter = "ssh-key"
+	ProfileSSHAllowedSigners ProfileParameter = "ssh-allowed-signers"
+	ProfileRenderer          ProfileParameter = "renderer"
+	ProfileRendererOpt       ProfileParameter = "renderer-option"
 
 	profileContractsSkipPrefixes string = ".,example"
 )
@@ -35,10 +39,14 @@ type ProfileDB struct {
 // Profile is a way of naming and differentiating between rendering
 // configurations used when rendering contracts.
 type Profile struct {
-	Name          string             `json:"name"`                   // A short, descriptive name for the profile.
-	ContractsRepo string             `json:"contracts_repo"`         // The default contracts repository for this profile.
-	Contracts     []*ProfileContract `json:"contracts,omitempty"`    // A cached list of contracts we've discovered in our contracts repo.
-	SignatureID   string             `json:"signature_id,omitempty"` // The ID of the signature to use when signing using this profile.
+	Name              string             `json:"name"`                          // A short, descriptive name for the profile.
+	ContractsRepo     string             `json:"contracts_repo"`                // The default contracts repository for this profile.
+	Contracts         []*ProfileContract `json:"contracts,omitempty"`           // A cached list of contracts we've discovered in our contracts repo.
+	SignatureID       string             `json:"signature_id,omitempty"`        // The ID of the signature to use when signing using this profile.
+	SSHKey            string             `json:"ssh_key,omitempty"`             // The path to the SSH private key with which to sign contracts, unless the signature has its own (optional).
+	SSHAllowedSigners string             `json:"ssh_allowed_signers,omitempty"` // The path to the SSH allowed signers file listing the keys we trust when verifying signatures (optional).
+	Renderer          string             `json:"renderer,omitempty"`            // The name of the renderer to use when compiling contracts (pandoc by default).
+	RendererOpts      map[string]string  `json:"renderer_options,omitempty"`    // Options to pass through to renderers when compiling contracts, keyed by "renderer.name".
 
 	id                 string  // A unique ID for this profile.
 	path               string  // The local filesystem path to this profile's folder.
@@ -280,6 +288,10 @@ func ValidProfileParamNames() []string {
 	return []string{
 		string(ProfileSignatureID),
 		string(ProfileContractsRepo),
+		string(ProfileSSHKey),
+		string(ProfileSSHAllowedSigners),
+		string(ProfileRenderer),
+		string(ProfileRendererOpt),
 	}
 }
 
@@ -326,7 +338,22 @@ func (p *Profile) Display() string {
 	if len(p.ContractsRepo) > 0 {
 		repoDisplay = fmt.Sprintf(", contracts repo: %s", p.ContractsRepo)
 	}
-	return fmt.Sprintf("%s (ID: %s%s%s)", p.Name, p.id, sigDisplay, repoDisplay)
+	sshKeyDisplay := ""
+	if len(p.SSHKey) > 0 {
+		sshKeyDisplay = fmt.Sprintf(", SSH key: %s", p.SSHKey)
+	}
+	allowedSignersDisplay := ""
+	if len(p.SSHAllowedSigners) > 0 {
+		allowedSignersDisplay = fmt.Sprintf(", SSH allowed signers: %s", p.SSHAllowedSigners)
+	}
+	rendererDisplay := ""
+	if len(p.Renderer) > 0 {
+		rendererDisplay = fmt.Sprintf(", renderer: %s", p.Renderer)
+	}
+	for _, name := range sortedKeys(p.RendererOpts) {
+		rendererDisplay += fmt.Sprintf(", renderer option: %s=%s", name, p.RendererOpts[name])
+	}
+	return fmt.Sprintf("%s (ID: %s%s%s%s%s%s)", p.Name, p.id, sigDisplay, repoDisplay, sshKeyDisplay, allowedSignersDisplay, rendererDisplay)
 }
 
 func (p *Profile) ID() string {
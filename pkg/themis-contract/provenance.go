@@ -0,0 +1,409 @@
+package themis_contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
+// The key in a compiled PDF's document information dictionary that refers to
+// the embedded provenance metadata.
+const pdfProvenanceKey = "ThemisContractProvenance"
+
+var (
+	pdfStartXRef       = regexp.MustCompile(`startxref\s+(\d+)`)
+	pdfTrailerRoot     = regexp.MustCompile(`/Root\s+(\d+\s+\d+\s+R)`)
+	pdfTrailerInfo     = regexp.MustCompile(`/Info\s+(\d+)\s+(\d+)\s+R`)
+	pdfTrailerSize     = regexp.MustCompile(`/Size\s+(\d+)`)
+	pdfTrailerID       = regexp.MustCompile(`/ID\s*(\[[^\]]*\])`)
+	pdfStreamLength    = regexp.MustCompile(`/Length\s+(\d+)`)
+	pdfProvenanceEntry = regexp.MustCompile(`/` + pdfProvenanceKey + `\s+(\d+)\s+(\d+)\s+R`)
+)
+
+// Provenance links a compiled contract back to the sources from which it was
+// produced. It is embedded into compiled PDFs.
+type Provenance struct {
+	ToolVersion string                 `json:"tool_version"`       // The version of Themis Contract that compiled the contract.
+	Hashes      *ContractHashes        `json:"hashes"`             // The hashes of the contract's components.
+	Upstream    *FileRef               `json:"upstream,omitempty"` // The upstream contract from which the contract was derived (if any).
+	Signatories []*ProvenanceSignatory `json:"signatories"`        // All of the contract's signatories.
+}
+
+// ProvenanceSignatory captures a single signatory's signature status at the
+// time of compilation.
+type ProvenanceSignatory struct {
+	ID                    string `json:"id"`
+	Name                  string `json:"name"`
+	Email                 string `json:"email"`
+	SignedDate            string `json:"signed_date,omitempty"`             // When the signatory signed (empty if they haven't).
+	Stale                 bool   `json:"stale,omitempty"`                   // Was the signature applied to an older version of the contract?
+	ImageHash             string `json:"image_hash,omitempty"`              // The SHA256 hash of the signatory's signature image.
+	DetachedSignatureHash string `json:"detached_signature_hash,omitempty"` // The SHA256 hash of the signatory's detached OpenPGP signature.
+	SSHSignatureHash      string `json:"ssh_signature_hash,omitempty"`      // The SHA256 hash of the signatory's SSH signature.
+}
+
+// Provenance computes the provenance metadata for the contract in its current
+// state.
+func (c *Contract) Provenance(ctx *Context) (*Provenance, error) {
+	p := &Provenance{
+		ToolVersion: ctx.toolVersion,
+		Hashes:      c.Hashes(),
+		Signatories: make([]*ProvenanceSignatory, 0, len(c.signatories)),
+	}
+	if c.Upstream != nil {
+		p.Upstream = &FileRef{Location: c.Upstream.Location, Hash: c.Upstream.Hash}
+	}
+	for _, signatory := range c.signatories {
+		ps := &ProvenanceSignatory{
+			ID:         signatory.Id,
+			Name:       signatory.Name,
+			Email:      signatory.Email,
+			SignedDate: signatory.SignedDate,
+			Stale:      signatory.Stale,
+		}
+		var err error
+		if ps.ImageHash, err = hashOfOptionalFile(signatory.Signature); err != nil {
+			return nil, err
+		}
+		if ps.DetachedSignatureHash, err = hashOfOptionalFile(signatory.DetachedSignature); err != nil {
+			return nil, err
+		}
+		if ps.SSHSignatureHash, err = hashOfOptionalFile(signatory.SSHSignature); err != nil {
+			return nil, err
+		}
+		p.Signatories = append(p.Signatories, ps)
+	}
+	return p, nil
+}
+
+func hashOfOptionalFile(filename string) (string, error) {
+	if len(filename) == 0 {
+		return "", nil
+	}
+	return hashOfFile(filename)
+}
+
+// Discrepancies compares this provenance (from a compiled contract) with the
+// given one (from the contract's sources), returning a human-readable
+// description of each difference. Tool versions are not compared.
+func (p *Provenance) Discrepancies(other *Provenance) []string {
+	result := make([]string, 0)
+	differs := func(what, a, b string) {
+		if a != b {
+			result = append(result, fmt.Sprintf("%s differs (\"%s\" vs \"%s\")", what, a, b))
+		}
+	}
+	differs("contract hash", p.Hashes.Contract, other.Hashes.Contract)
+	differs("params hash", p.Hashes.Params, other.Hashes.Params)
+	differs("template hash", p.Hashes.Template, other.Hashes.Template)
+	var upstream, otherUpstream FileRef
+	if p.Upstream != nil {
+		upstream = *p.Upstream
+	}
+	if other.Upstream != nil {
+		otherUpstream = *other.Upstream
+	}
+	differs("upstream location", upstream.Location, otherUpstream.Location)
+	differs("upstream hash", upstream.Hash, otherUpstream.Hash)
+
+	otherSigs := make(map[string]*ProvenanceSignatory)
+	for _, sig := range other.Signatories {
+		otherSigs[sig.ID] = sig
+	}
+	for _, sig := range p.Signatories {
+		otherSig, ok := otherSigs[sig.ID]
+		if !ok {
+			result = append(result, fmt.Sprintf("signatory \"%s\" is not present in the contract", sig.ID))
+			continue
+		}
+		delete(otherSigs, sig.ID)
+		prefix := fmt.Sprintf("signatory \"%s\" ", sig.ID)
+		differs(prefix+"e-mail address", sig.Email, otherSig.Email)
+		differs(prefix+"signed date", sig.SignedDate, otherSig.SignedDate)
+		differs(prefix+"signature image hash", sig.ImageHash, otherSig.ImageHash)
+		differs(prefix+"detached signature hash", sig.DetachedSignatureHash, otherSig.DetachedSignatureHash)
+		differs(prefix+"SSH signature hash", sig.SSHSignatureHash, otherSig.SSHSignatureHash)
+	}
+	for _, sig := range other.Signatories {
+		if _, ok := otherSigs[sig.ID]; ok {
+			result = append(result, fmt.Sprintf("signatory \"%s\" is not present in the compiled contract", sig.ID))
+		}
+	}
+	return result
+}
+
+// ReadPDFProvenance extracts the provenance metadata embedded in the given
+// PDF file by Themis Contract.
+func ReadPDFProvenance(pdfFile string) (*Provenance, error) {
+	content, err := ioutil.ReadFile(pdfFile)
+	if err != nil {
+		return nil, err
+	}
+	trailer, err := parsePDFTrailer(content)
+	if err != nil {
+		return nil, err
+	}
+	if trailer.info == nil {
+		return nil, fmt.Errorf("no provenance metadata found in %s", pdfFile)
+	}
+	infoDict, ok := pdfObjectDict(content, trailer.info.num, trailer.info.gen)
+	if !ok {
+		return nil, fmt.Errorf("no provenance metadata found in %s", pdfFile)
+	}
+	m := pdfProvenanceEntry.FindSubmatch(infoDict)
+	if m == nil {
+		return nil, fmt.Errorf("no provenance metadata found in %s", pdfFile)
+	}
+	num, _ := strconv.Atoi(string(m[1]))
+	gen, _ := strconv.Atoi(string(m[2]))
+	stream, err := pdfObjectStream(content, num, gen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provenance metadata from %s: %s", pdfFile, err)
+	}
+	var p Provenance
+	if err := json.Unmarshal(stream, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse provenance metadata from %s: %s", pdfFile, err)
+	}
+	if p.Hashes == nil {
+		return nil, fmt.Errorf("provenance metadata in %s is missing contract hashes", pdfFile)
+	}
+	return &p, nil
+}
+
+// embedPDFProvenance appends the given provenance metadata to the specified
+// PDF file by way of an incremental update. This works regardless of which
+// renderer produced the PDF. The metadata is stored as a JSON stream, which is
+// referenced from the document information dictionary (all of the existing
+// document information, such as the title, is preserved where possible).
+func embedPDFProvenance(pdfFile string, p *Provenance) error {
+	content, err := ioutil.ReadFile(pdfFile)
+	if err != nil {
+		return err
+	}
+	trailer, err := parsePDFTrailer(content)
+	if err != nil {
+		return fmt.Errorf("failed to parse PDF file %s: %s", pdfFile, err)
+	}
+	provenanceJSON, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(content)
+	if !bytes.HasSuffix(content, []byte("\n")) {
+		buf.WriteString("\n")
+	}
+	streamNum, infoNum := trailer.size, trailer.size+1
+	offsets := make([]int, 0, 3)
+
+	offsets = append(offsets, buf.Len())
+	fmt.Fprintf(&buf, "%d 0 obj\n<< /Type /EmbeddedFile /Subtype /application#2Fjson /Length %d >>\nstream\n", streamNum, len(provenanceJSON))
+	buf.Write(provenanceJSON)
+	buf.WriteString("\nendstream\nendobj\n")
+
+	// we carry over the existing document information, if we can find it
+	// (it may be compressed, in which case we can't)
+	var existingInfo []byte
+	if trailer.info != nil {
+		if dict, ok := pdfObjectDict(content, trailer.info.num, trailer.info.gen); ok {
+			existingInfo = pdfProvenanceEntry.ReplaceAll(bytes.TrimSpace(dict[2:len(dict)-2]), nil)
+		} else {
+			log.Warn().Msgf("Unable to preserve existing document information in %s", pdfFile)
+		}
+	}
+	offsets = append(offsets, buf.Len())
+	fmt.Fprintf(&buf, "%d 0 obj\n<< %s /%s %d 0 R >>\nendobj\n", infoNum, bytes.TrimSpace(existingInfo), pdfProvenanceKey, streamNum)
+
+	trailerEntries := fmt.Sprintf("/Root %s /Info %d 0 R /Prev %d", trailer.root, infoNum, trailer.prevXRef)
+	if len(trailer.id) > 0 {
+		trailerEntries += " /ID " + trailer.id
+	}
+	xrefOffset := buf.Len()
+	if trailer.xrefStream {
+		// PDFs that use cross-reference streams must be updated using
+		// cross-reference streams
+		xrefNum := trailer.size + 2
+		offsets = append(offsets, xrefOffset)
+		var entries bytes.Buffer
+		for _, offset := range offsets {
+			entries.Write([]byte{1, byte(offset >> 24), byte(offset >> 16), byte(offset >> 8), byte(offset), 0, 0})
+		}
+		fmt.Fprintf(&buf, "%d 0 obj\n<< /Type /XRef /Size %d /Index [%d 3] /W [1 4 2] %s /Length %d >>\nstream\n", xrefNum, xrefNum+1, streamNum, trailerEntries, entries.Len())
+		buf.Write(entries.Bytes())
+		buf.WriteString("\nendstream\nendobj\n")
+	} else {
+		fmt.Fprintf(&buf, "xref\n%d %d\n", streamNum, len(offsets))
+		for _, offset := range offsets {
+			fmt.Fprintf(&buf, "%010d 00000 n\r\n", offset)
+		}
+		fmt.Fprintf(&buf, "trailer\n<< /Size %d %s >>\n", infoNum+1, trailerEntries)
+	}
+	fmt.Fprintf(&buf, "startxref\n%d\n%%%%EOF\n", xrefOffset)
+
+	log.Debug().Msgf("Embedding provenance metadata in %s: %s", pdfFile, provenanceJSON)
+	tempFile := path.Join(path.Dir(pdfFile), "."+path.Base(pdfFile)+".tmp")
+	if err := ioutil.WriteFile(tempFile, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempFile, pdfFile)
+}
+
+type pdfObjectRef struct {
+	num, gen int
+}
+
+// pdfTrailer contains the relevant parts of the trailer of a PDF file (i.e.
+// the last trailer, in the case of incrementally updated files).
+type pdfTrailer struct {
+	prevXRef   int           // The offset of the last cross-reference section.
+	xrefStream bool          // Does the file use cross-reference streams (as opposed to tables)?
+	root       string        // The reference to the document catalog.
+	info       *pdfObjectRef // The reference to the document information dictionary (if any).
+	size       int           // The number of objects in the file.
+	id         string        // The file identifier (if any).
+}
+
+func parsePDFTrailer(content []byte) (*pdfTrailer, error) {
+	matches := pdfStartXRef.FindAllSubmatch(content, -1)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("cannot find cross-reference section")
+	}
+	prevXRef, err := strconv.Atoi(string(matches[len(matches)-1][1]))
+	if err != nil || prevXRef >= len(content) {
+		return nil, fmt.Errorf("invalid cross-reference section offset")
+	}
+	t := &pdfTrailer{prevXRef: prevXRef}
+	var dict []byte
+	section := content[prevXRef:]
+	if bytes.HasPrefix(section, []byte("xref")) {
+		start := bytes.Index(section, []byte("trailer"))
+		end := bytes.Index(section, []byte("startxref"))
+		if start < 0 || end < start {
+			return nil, fmt.Errorf("cannot find trailer")
+		}
+		dict = section[start:end]
+	} else {
+		end := bytes.Index(section, []byte("stream"))
+		if end < 0 {
+			return nil, fmt.Errorf("cannot find cross-reference stream")
+		}
+		dict = section[:end]
+		t.xrefStream = true
+	}
+	if bytes.Contains(dict, []byte("/Encrypt")) {
+		return nil, fmt.Errorf("encrypted PDF files are not supported")
+	}
+	root := pdfTrailerRoot.FindSubmatch(dict)
+	size := pdfTrailerSize.FindSubmatch(dict)
+	if root == nil || size == nil {
+		return nil, fmt.Errorf("trailer is missing required entries")
+	}
+	t.root = string(root[1])
+	t.size, _ = strconv.Atoi(string(size[1]))
+	if info := pdfTrailerInfo.FindSubmatch(dict); info != nil {
+		num, _ := strconv.Atoi(string(info[1]))
+		gen, _ := strconv.Atoi(string(info[2]))
+		t.info = &pdfObjectRef{num: num, gen: gen}
+	}
+	if id := pdfTrailerID.FindSubmatch(dict); id != nil {
+		t.id = string(id[1])
+	}
+	return t, nil
+}
+
+// pdfObjectOffset finds the offset of the start of the content of the last
+// definition of the given (uncompressed) object.
+func pdfObjectOffset(content []byte, num, gen int) (int, bool) {
+	re := regexp.MustCompile(fmt.Sprintf(`(?:^|\s)%d\s+%d\s+obj\b`, num, gen))
+	locs := re.FindAllIndex(content, -1)
+	if len(locs) == 0 {
+		return 0, false
+	}
+	return locs[len(locs)-1][1], true
+}
+
+// pdfObjectDict returns the dictionary (including its delimiters) that
+// constitutes (or starts) the given object.
+func pdfObjectDict(content []byte, num, gen int) ([]byte, bool) {
+	offset, ok := pdfObjectOffset(content, num, gen)
+	if !ok {
+		return nil, false
+	}
+	rest := bytes.TrimLeft(content[offset:], " \t\r\n")
+	if !bytes.HasPrefix(rest, []byte("<<")) {
+		return nil, false
+	}
+	depth := 0
+	for i := 0; i < len(rest)-1; i++ {
+		switch {
+		case rest[i] == '(':
+			// skip over string literals, which may contain delimiters
+			i = skipPDFString(rest, i)
+		case rest[i] == '<' && rest[i+1] == '<':
+			depth++
+			i++
+		case rest[i] == '>' && rest[i+1] == '>':
+			depth--
+			i++
+			if depth == 0 {
+				return rest[:i+1], true
+			}
+		}
+	}
+	return nil, false
+}
+
+func skipPDFString(s []byte, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+// pdfObjectStream returns the (uncompressed) content of the given stream
+// object.
+func pdfObjectStream(content []byte, num, gen int) ([]byte, error) {
+	dict, ok := pdfObjectDict(content, num, gen)
+	if !ok {
+		return nil, fmt.Errorf("cannot find object %d %d", num, gen)
+	}
+	length := pdfStreamLength.FindSubmatch(dict)
+	if length == nil {
+		return nil, fmt.Errorf("object %d %d is not a stream", num, gen)
+	}
+	n, _ := strconv.Atoi(string(length[1]))
+	offset, _ := pdfObjectOffset(content, num, gen)
+	start := bytes.Index(content[offset:], []byte("stream"))
+	if start < 0 {
+		return nil, fmt.Errorf("object %d %d is not a stream", num, gen)
+	}
+	start += offset + len("stream")
+	if bytes.HasPrefix(content[start:], []byte("\r\n")) {
+		start += 2
+	} else if bytes.HasPrefix(content[start:], []byte("\n")) {
+		start++
+	}
+	if start+n > len(content) {
+		return nil, fmt.Errorf("stream in object %d %d is truncated", num, gen)
+	}
+	return content[start : start+n], nil
+}
@@ -0,0 +1,96 @@
+package themis_contract_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+	"github.com/jung-kurt/gofpdf"
+)
+
+func TestPDFProvenance(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// a PDF with a cross-reference table
+	tablePDF := path.Join(tempDir, "table.pdf")
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Test Contract", false)
+	pdf.AddPage()
+	if err := pdf.OutputFileAndClose(tablePDF); err != nil {
+		t.Fatal(err)
+	}
+	// a (skeletal) PDF with a cross-reference stream
+	streamPDF := path.Join(tempDir, "stream.pdf")
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.5\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n2 0 obj\n<< /Type /Pages /Kids [] /Count 0 >>\nendobj\n3 0 obj\n<< /Title (Test Contract) >>\nendobj\n")
+	xrefOffset := buf.Len()
+	buf.WriteString("4 0 obj\n<< /Type /XRef /Size 5 /Root 1 0 R /Info 3 0 R /W [1 4 2] /Length 0 >>\nstream\n\nendstream\nendobj\n")
+	fmt.Fprintf(&buf, "startxref\n%d\n%%%%EOF\n", xrefOffset)
+	if err := ioutil.WriteFile(streamPDF, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pdfFile := range []string{tablePDF, streamPDF} {
+		// embedding twice should result in the latest provenance being read
+		for _, hash := range []string{"first", "second"} {
+			p := &contract.Provenance{
+				ToolVersion: "v0.0.0-test",
+				Hashes:      &contract.ContractHashes{Contract: hash, Params: "params", Template: "template"},
+				Upstream:    &contract.FileRef{Location: "https://somewhere.com/contract.dhall", Hash: "upstream"},
+				Signatories: []*contract.ProvenanceSignatory{
+					{ID: "alice", Name: "Alice", Email: "alice@somewhere.com", SignedDate: "1 July 2020", ImageHash: "image"},
+				},
+			}
+			if err := contract.EmbedPDFProvenance(pdfFile, p); err != nil {
+				t.Fatalf("failed to embed provenance in %s: %s", pdfFile, err)
+			}
+			actual, err := contract.ReadPDFProvenance(pdfFile)
+			if err != nil {
+				t.Fatalf("failed to read provenance from %s: %s", pdfFile, err)
+			}
+			if d := p.Discrepancies(actual); len(d) > 0 {
+				t.Errorf("expected provenance read from %s to match embedded provenance, but got: %v", pdfFile, d)
+			}
+		}
+		content, err := ioutil.ReadFile(pdfFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Count(content, []byte("/Title (")) < 3 {
+			t.Errorf("expected document title to be preserved in %s", pdfFile)
+		}
+	}
+
+	other := &contract.Provenance{
+		Hashes:      &contract.ContractHashes{Contract: "other", Params: "params", Template: "template"},
+		Signatories: []*contract.ProvenanceSignatory{{ID: "bob", Email: "bob@somewhere.com"}},
+	}
+	actual, err := contract.ReadPDFProvenance(tablePDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`contract hash differs ("second" vs "other")`,
+		`upstream location differs ("https://somewhere.com/contract.dhall" vs "")`,
+		`upstream hash differs ("upstream" vs "")`,
+		`signatory "alice" is not present in the contract`,
+		`signatory "bob" is not present in the compiled contract`,
+	}
+	d := actual.Discrepancies(other)
+	if len(d) != len(expected) {
+		t.Fatalf("expected %d discrepancies, but got %d: %v", len(expected), len(d), d)
+	}
+	for i := range expected {
+		if d[i] != expected[i] {
+			t.Errorf("expected discrepancy %d to be \"%s\", but got \"%s\"", i, expected[i], d[i])
+		}
+	}
+}
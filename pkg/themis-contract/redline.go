@@ -0,0 +1,256 @@
+package themis_contract
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/rs/zerolog/log"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const (
+	// The file extension of redlines that are to be written as Markdown
+	// instead of being compiled.
+	redlineMarkdownExt = ".md"
+
+	// How deleted and inserted text is marked up in a redline. Both are
+	// supported by pandoc's Markdown, as well as by the built-in renderer.
+	redlineDeletionStart  = "~~"
+	redlineDeletionEnd    = "~~"
+	redlineInsertionStart = "["
+	redlineInsertionEnd   = "]{.underline}"
+)
+
+// Tokens that give Markdown text its structure when they appear at the start
+// of a line (headings, list items and block quotes), which must not be marked
+// up as insertions or deletions lest the structure be lost.
+var redlineBlockMarker = regexp.MustCompile(`^(#{1,6}|[-*+]|\d+[.)]|>+)$`)
+
+// Redline compiles a redline of the revised contract against this contract to
+// the given output file, in which the words only present in this contract are
+// struck through and the words only present in the revised contract are
+// underlined. Both contracts are rendered with their parameters prior to
+// being compared. If the output file has a ".md" extension, the redline is
+// written as Markdown instead of being compiled.
+func (c *Contract) Redline(revised *Contract, output string, ctx *Context) error {
+	activeProfile := ctx.ActiveProfile()
+	if activeProfile == nil {
+		return fmt.Errorf("no profile currently active (use \"themis-contract use\" to select one)")
+	}
+	tempDir, err := ioutil.TempDir("", "themis-contract-redline")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+	original, err := c.renderText(path.Join(tempDir, "original-"+c.Template.renderedFilename()), ctx)
+	if err != nil {
+		return err
+	}
+	revisedText, err := revised.renderText(path.Join(tempDir, "revised-"+revised.Template.renderedFilename()), ctx)
+	if err != nil {
+		return err
+	}
+	redline := redlineMarkdown(original, revisedText)
+
+	if path.Ext(output) == redlineMarkdownExt {
+		log.Info().Msgf("Writing redline: %s", output)
+		return ioutil.WriteFile(output, []byte(redline), 0644)
+	}
+	redlineFile := path.Join(tempDir, "redline.md")
+	if err := ioutil.WriteFile(redlineFile, []byte(redline), 0644); err != nil {
+		return err
+	}
+	_, err = renderDocument(&RenderJob{
+		Input:         redlineFile,
+		Output:        output,
+		ResourcePaths: []string{".", path.Dir(revised.path.localPath), path.Dir(c.path.localPath), activeProfile.Path()},
+	}, ctx)
+	return err
+}
+
+// renderText renders the contract with its parameters to the given file and
+// returns the rendered text.
+func (c *Contract) renderText(output string, ctx *Context) (string, error) {
+	if err := c.Render(output, ctx); err != nil {
+		return "", err
+	}
+	content, err := ioutil.ReadFile(output)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// redlineMarkdown performs a word-level comparison of the two given Markdown
+// documents and produces a Markdown document in which deleted words are struck
+// through and inserted ones are underlined. The revised document's metadata
+// block (if any) is used as-is.
+func redlineMarkdown(original, revised string) string {
+	_, originalBody := splitFrontMatter(original)
+	frontMatter, revisedBody := splitFrontMatter(revised)
+
+	// we map each distinct token to a rune so we can use the diff algorithm
+	// for strings to compare sequences of tokens
+	tokens := make([]string, 0)
+	tokenRunes := make(map[string]rune)
+	toRunes := func(text string) []rune {
+		var runes []rune
+		for _, token := range tokenizeRedlineText(text) {
+			r, ok := tokenRunes[token]
+			if !ok {
+				r = redlineTokenRune(len(tokens))
+				tokens = append(tokens, token)
+				tokenRunes[token] = r
+			}
+			runes = append(runes, r)
+		}
+		return runes
+	}
+	originalRunes, revisedRunes := toRunes(originalBody), toRunes(revisedBody)
+	dmp := diffmatchpatch.New()
+	diffs := dmp.DiffCleanupSemantic(dmp.DiffMainRunes(originalRunes, revisedRunes, false))
+
+	w := &redlineWriter{atLineStart: true}
+	w.buf.WriteString(frontMatter)
+	for _, d := range diffs {
+		var diffTokens []string
+		for _, r := range d.Text {
+			diffTokens = append(diffTokens, tokens[redlineRuneToken(r)])
+		}
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			w.write(diffTokens, "", "")
+		case diffmatchpatch.DiffDelete:
+			w.write(diffTokens, redlineDeletionStart, redlineDeletionEnd)
+		case diffmatchpatch.DiffInsert:
+			w.write(diffTokens, redlineInsertionStart, redlineInsertionEnd)
+		}
+	}
+	return w.buf.String()
+}
+
+// redlineTokenRune maps the given token index to a rune, skipping the range of
+// runes reserved for UTF-16 surrogates (which cannot be represented in Go
+// strings).
+func redlineTokenRune(i int) rune {
+	if i >= 0xD800 {
+		return rune(i + 0x800)
+	}
+	return rune(i)
+}
+
+func redlineRuneToken(r rune) int {
+	if r >= 0xE000 {
+		return int(r) - 0x800
+	}
+	return int(r)
+}
+
+// tokenizeRedlineText splits the given text into alternating runs of
+// whitespace and non-whitespace characters.
+func tokenizeRedlineText(text string) []string {
+	tokens := make([]string, 0)
+	start, prevSpace := 0, false
+	for i, r := range text {
+		isSpace := unicode.IsSpace(r)
+		if i > start && isSpace != prevSpace {
+			tokens = append(tokens, text[start:i])
+			start = i
+		}
+		prevSpace = isSpace
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+// redlineWriter writes tokens to a redline, marking up runs of changed words.
+type redlineWriter struct {
+	buf         strings.Builder
+	atLineStart bool     // Are we at the start of a line (ignoring indentation)?
+	pending     []string // Tokens of the current run of marked up words.
+}
+
+// write writes the given tokens to the redline, enclosing runs of words in the
+// given start and end markers (if any). Runs are broken at line breaks and
+// Markdown structural elements, which are never marked up.
+func (w *redlineWriter) write(tokens []string, start, end string) {
+	for _, token := range tokens {
+		isSpace := len(strings.TrimSpace(token)) == 0
+		switch {
+		case len(start) == 0:
+			w.buf.WriteString(token)
+		case isSpace && !strings.Contains(token, "\n"):
+			if len(w.pending) > 0 {
+				w.pending = append(w.pending, token)
+			} else {
+				w.buf.WriteString(token)
+			}
+			continue
+		case isSpace || strings.HasPrefix(token, "|") || (w.atLineStart && redlineBlockMarker.MatchString(token)):
+			w.flush(start, end)
+			w.buf.WriteString(token)
+		default:
+			w.pending = append(w.pending, token)
+		}
+		if isSpace {
+			w.atLineStart = w.atLineStart || strings.Contains(token, "\n")
+		} else {
+			w.atLineStart = false
+		}
+	}
+	w.flush(start, end)
+}
+
+// flush writes out the current run of words enclosed in the given markers,
+// leaving any trailing whitespace outside of the markers.
+func (w *redlineWriter) flush(start, end string) {
+	if len(w.pending) == 0 {
+		return
+	}
+	trailing := ""
+	if last := w.pending[len(w.pending)-1]; len(strings.TrimSpace(last)) == 0 {
+		trailing = last
+		w.pending = w.pending[:len(w.pending)-1]
+	}
+	w.buf.WriteString(start + strings.Join(w.pending, "") + end + trailing)
+	w.pending = nil
+}
+
+// splitFrontMatter splits the YAML metadata block at the start of the given
+// Markdown text (if any) from the rest of the text. The metadata block
+// includes its delimiters.
+func splitFrontMatter(text string) (string, string) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if !strings.HasPrefix(text, "---\n") {
+		return "", text
+	}
+	lines := strings.SplitAfter(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		if l := strings.TrimRight(lines[i], "\n"); l == "---" || l == "..." {
+			return strings.Join(lines[:i+1], ""), strings.Join(lines[i+1:], "")
+		}
+	}
+	return "", text
+}
+
+// UpstreamRedline compiles a redline of this contract against its upstream to
+// the given output file, showing the changes made to the upstream's text (see
+// Redline).
+func (c *Contract) UpstreamRedline(output string, ctx *Context) error {
+	if c.Upstream == nil {
+		return fmt.Errorf("contract has no upstream")
+	}
+	log.Info().Msgf("Loading upstream contract: %s", c.Upstream.Location)
+	upstream, err := Load(c.Upstream.Location, ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load upstream contract: %s", err)
+	}
+	return upstream.Redline(c, output, ctx)
+}
@@ -0,0 +1,52 @@
+package themis_contract_test
+
+import (
+	"testing"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+)
+
+func TestRedlineMarkdown(t *testing.T) {
+	const original = `---
+title: Services Agreement
+---
+
+## Payment
+
+Invoices are payable within 30 days of receipt.
+
+## Termination
+
+- Either party may terminate this agreement.
+`
+	const revised = `---
+title: Services Agreement (Revised)
+---
+
+## Payment Terms
+
+Invoices are payable within 14 days of receipt.
+
+## Termination
+
+- Either party may terminate this agreement with notice.
+- The provider may suspend services.
+`
+	const expected = `---
+title: Services Agreement (Revised)
+---
+
+## Payment [Terms]{.underline}
+
+Invoices are payable within ~~30~~[14]{.underline} days of receipt.
+
+## Termination
+
+- Either party may terminate this ~~agreement.~~[agreement with notice.]{.underline}
+- [The provider may suspend services.]{.underline}
+`
+	actual := contract.RedlineMarkdown(original, revised)
+	if actual != expected {
+		t.Errorf("expected redline to be:\n%s\n\nbut got:\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,222 @@
+package themis_contract
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	PandocRendererName  = "pandoc"
+	BuiltinRendererName = "builtin"
+
+	defaultRendererName = PandocRendererName
+)
+
+// Renderer converts a contract's rendered Markdown text into its final output
+// document (e.g. a PDF). Additional renderers can be made available by way of
+// RegisterRenderer.
+type Renderer interface {
+	// Render produces the output document described by the given job.
+	Render(job *RenderJob) error
+}
+
+// RenderJob describes a single invocation of a Renderer.
+type RenderJob struct {
+	Input         string            // The path to the rendered Markdown version of the contract.
+	Output        string            // Where to write the output document.
+	Format        OutputFormat      // The format of the output document.
+	ResourcePaths []string          // Where to look for resources (e.g. signature images) referenced by the contract.
+	Profile       *Profile          // The profile with which the contract is being compiled.
+	Options       map[string]string // The options for this renderer (from the profile and the context), without their renderer prefix.
+	SourceDate    time.Time         // The date to embed in the output (e.g. as its creation date), so that output is reproducible.
+	Watermark     string            // Text with which to watermark each page of the output (if any).
+}
+
+var (
+	renderersMtx sync.RWMutex
+	renderers    = map[string]Renderer{
+		PandocRendererName:  &pandocRenderer{},
+		BuiltinRendererName: &builtinRenderer{},
+	}
+)
+
+// RegisterRenderer makes the given renderer available under the given name,
+// so that it can be selected in profiles or via the context. Renderer names
+// must be unique.
+func RegisterRenderer(name string, renderer Renderer) error {
+	if len(name) == 0 {
+		return fmt.Errorf("renderer name must not be empty")
+	}
+	// renderer options are prefixed with their renderer's name and a period
+	if strings.Contains(name, ".") {
+		return fmt.Errorf("renderer name must not contain periods: %s", name)
+	}
+	renderersMtx.Lock()
+	defer renderersMtx.Unlock()
+	if _, exists := renderers[name]; exists {
+		return fmt.Errorf("a renderer named \"%s\" has already been registered", name)
+	}
+	renderers[name] = renderer
+	return nil
+}
+
+// rendererByName returns the renderer with the given name. If no name is
+// given, the default renderer is returned.
+func rendererByName(name string) (Renderer, error) {
+	if len(name) == 0 {
+		name = defaultRendererName
+	}
+	renderersMtx.RLock()
+	renderer, exists := renderers[name]
+	renderersMtx.RUnlock()
+	if exists {
+		return renderer, nil
+	}
+	return nil, fmt.Errorf("unrecognized renderer \"%s\" (must be one of: %s)", name, strings.Join(RendererNames(), ", "))
+}
+
+// RendererNames returns the names of all of the available renderers, in
+// alphabetical order.
+func RendererNames() []string {
+	renderersMtx.RLock()
+	defer renderersMtx.RUnlock()
+	names := make([]string, 0, len(renderers))
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// renderDocument converts the job's input document to its output file using the
+// renderer configured in the context or, failing that, in the active profile.
+// The job's format (if the context doesn't specify one, it's inferred from the
+// output file name), profile and renderer options are filled in from the
+// context. Returns the name of the renderer used.
+func renderDocument(job *RenderJob, ctx *Context) (string, error) {
+	activeProfile := ctx.ActiveProfile()
+	job.Format = ctx.outputFormat
+	if len(job.Format) == 0 {
+		var err error
+		if job.Format, err = outputFormatForFile(job.Output); err != nil {
+			return "", fmt.Errorf("cannot determine output format from file name (use an explicit format instead): %s", err)
+		}
+	}
+	rendererName := ctx.renderer
+	if len(rendererName) == 0 {
+		rendererName = activeProfile.Renderer
+	}
+	if len(rendererName) == 0 {
+		rendererName = defaultRendererName
+	}
+	renderer, err := rendererByName(rendererName)
+	if err != nil {
+		return "", err
+	}
+	// make sure the profile has the pandoc defaults for this format (profiles
+	// created by older versions of Themis Contract may not)
+	if _, ok := renderer.(*pandocRenderer); ok {
+		if err := ctx.ensureProfileResources(activeProfile); err != nil {
+			return "", err
+		}
+	}
+	job.Profile = activeProfile
+	if job.Options, err = ctx.rendererOptions(activeProfile, rendererName); err != nil {
+		return "", err
+	}
+	log.Info().Msgf("Compiling to %s: %s", job.Format, job.Output)
+	return rendererName, renderer.Render(job)
+}
+
+// pandocRenderer uses pandoc (and, for PDFs, LaTeX) to produce output
+// documents, configured by way of the pandoc defaults files in the profile.
+// Its renderer options are passed through to pandoc as long options, e.g. the
+// option `pandoc.pdf-engine=xelatex` becomes `--pdf-engine=xelatex`.
+type pandocRenderer struct{}
+
+var _ Renderer = &pandocRenderer{}
+
+func (r *pandocRenderer) Render(job *RenderJob) error {
+	pandocArgs := append([]string{
+		job.Input,
+		"-o",
+		job.Output,
+		"--resource-path",
+		strings.Join(job.ResourcePaths, ":"),
+	}, job.Format.pandocArgs(job.Profile)...)
+	for _, name := range sortedKeys(job.Options) {
+		pandocArgs = append(pandocArgs, fmt.Sprintf("--%s=%s", name, job.Options[name]))
+	}
+	if job.Format == PDFOutput && job.Profile != nil {
+		pandocArgs = append(pandocArgs, "--include-in-header", path.Join(job.Profile.Path(), "reproducible.tex"))
+	}
+	if len(job.Watermark) > 0 {
+		if job.Format != PDFOutput {
+			log.Warn().Msgf("Watermarks are only supported for PDF output, not %s", job.Format)
+		} else {
+			watermarkFile := path.Join(path.Dir(job.Input), "watermark.tex")
+			if err := ioutil.WriteFile(watermarkFile, []byte(latexWatermark(job.Watermark)), 0644); err != nil {
+				return err
+			}
+			pandocArgs = append(pandocArgs, "--include-in-header", watermarkFile)
+		}
+	}
+	log.Debug().Msgf("Using pandoc arguments: %s", strings.Join(pandocArgs, " "))
+	cmd := exec.Command("pandoc", pandocArgs...)
+	if !job.SourceDate.IsZero() {
+		// both pandoc and LaTeX use this to determine the dates embedded in
+		// their output
+		cmd.Env = append(os.Environ(),
+			fmt.Sprintf("SOURCE_DATE_EPOCH=%d", job.SourceDate.Unix()),
+			"FORCE_SOURCE_DATE=1",
+		)
+	}
+	pandocOutput, err := cmd.CombinedOutput()
+	log.Debug().Msgf("pandoc execution output:\n%s\n", pandocOutput)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// latexWatermark produces the LaTeX header that watermarks each page of a PDF
+// with the given text. It uses the LaTeX kernel's hooks where available
+// (since October 2020), and falls back to the draftwatermark package.
+func latexWatermark(text string) string {
+	escaped := latexEscaper.Replace(text)
+	return fmt.Sprintf(`\usepackage{graphicx}
+\usepackage{xcolor}
+\makeatletter
+\@ifundefined{AddToHook}{%%
+  \usepackage{draftwatermark}
+  \SetWatermarkText{%[1]s}
+}{%%
+  \AddToHook{shipout/background}{%%
+    \put(0,0){\parbox[t][\paperheight]{\paperwidth}{\vfill\centering
+      \rotatebox{45}{\scalebox{9}{\textcolor[gray]{0.88}{\sffamily\bfseries %[1]s}}}\vfill}}}
+}
+\makeatother
+`, escaped)
+}
+
+var latexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`$`, `\$`,
+	`&`, `\&`,
+	`#`, `\#`,
+	`%`, `\%`,
+	`_`, `\_`,
+	`^`, `\textasciicircum{}`,
+	`~`, `\textasciitilde{}`,
+)
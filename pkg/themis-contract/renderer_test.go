@@ -0,0 +1,185 @@
+package themis_contract_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+)
+
+type testRenderer struct {
+	jobs   []*contract.RenderJob
+	inputs []string
+}
+
+func (r *testRenderer) Render(job *contract.RenderJob) error {
+	input, err := ioutil.ReadFile(job.Input)
+	if err != nil {
+		return err
+	}
+	r.jobs = append(r.jobs, job)
+	r.inputs = append(r.inputs, string(input))
+	return ioutil.WriteFile(job.Output, []byte("rendered"), 0644)
+}
+
+func TestCustomRenderer(t *testing.T) {
+	renderer := &testRenderer{}
+	if err := contract.RegisterRenderer("test", renderer); err != nil {
+		t.Fatal(err)
+	}
+	if err := contract.RegisterRenderer("test", renderer); err == nil {
+		t.Error("expected an error when registering a renderer with the same name twice")
+	}
+
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	files := map[string]string{
+		"params.json":   `{"client": "Acme Corp", "signatories": [{"id": "alice", "name": "Alice", "email": "alice@somewhere.com"}]}`,
+		"contract.md":   "# Agreement with {{client}}\n{{#draft}}Awaiting signatures from:{{#unsigned_signatories}} {{name}}{{/unsigned_signatories}}{{/draft}}\n",
+		"contract.json": `{"params": {"location": "./params.json"}, "template": {"format": "Mustache", "file": {"location": "./contract.md"}}}`,
+	}
+	for filename, content := range files {
+		if err := ioutil.WriteFile(path.Join(tempDir, filename), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	profile := contract.NewTestProfile("test", "", nil)
+	profile.Renderer = "test"
+	profile.RendererOpts = map[string]string{"test.paper": "a4", "test.colour": "blue", "pandoc.pdf-engine": "xelatex"}
+	ctx := contract.NewTestContext(nil, profile)
+	if err := contract.Update(path.Join(tempDir, "contract.json"), ctx); err != nil {
+		t.Fatal(err)
+	}
+	c, err := contract.Load(path.Join(tempDir, "contract.json"), ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := path.Join(tempDir, "contract.html")
+	if err := c.Compile(output, ctx.WithRendererOptions(map[string]string{"test.colour": "red"}).WithDraftWatermark(true)); err != nil {
+		t.Fatal(err)
+	}
+	if len(renderer.jobs) != 1 {
+		t.Fatalf("expected the test renderer to be invoked once, but it was invoked %d time(s)", len(renderer.jobs))
+	}
+	job := renderer.jobs[0]
+	if job.Output != output {
+		t.Errorf("expected output file to be %s, but got %s", output, job.Output)
+	}
+	if job.Format != contract.HTMLOutput {
+		t.Errorf("expected output format to be %s, but got %s", contract.HTMLOutput, job.Format)
+	}
+	expectedOpts := map[string]string{"paper": "a4", "colour": "red"}
+	if len(job.Options) != len(expectedOpts) {
+		t.Errorf("expected renderer options %v, but got %v", expectedOpts, job.Options)
+	}
+	for name, val := range expectedOpts {
+		if job.Options[name] != val {
+			t.Errorf("expected renderer option %s to be \"%s\", but got \"%s\"", name, val, job.Options[name])
+		}
+	}
+
+	if job.Watermark != "DRAFT" {
+		t.Errorf("expected unsigned contract to be watermarked as a draft, but got watermark \"%s\"", job.Watermark)
+	}
+	expectedInput := "# Agreement with Acme Corp\nAwaiting signatures from: Alice\n"
+	if renderer.inputs[0] != expectedInput {
+		t.Errorf("expected rendered contract to be:\n%s\nbut got:\n%s", expectedInput, renderer.inputs[0])
+	}
+	if err := c.Compile(output, ctx.WithDraftWatermark(false)); err != nil {
+		t.Fatal(err)
+	}
+	if len(renderer.jobs[1].Watermark) > 0 {
+		t.Errorf("expected watermark to be suppressed, but got \"%s\"", renderer.jobs[1].Watermark)
+	}
+
+	// renderer options must name the renderer to which they apply
+	if err := c.Compile(output, ctx.WithRendererOptions(map[string]string{"colour": "red"})); err == nil {
+		t.Error("expected an error when compiling with a renderer option without a renderer prefix")
+	}
+
+	// the context's renderer takes precedence over the profile's one
+	if err := c.Compile(output, ctx.WithRenderer("nonexistent")); err == nil {
+		t.Error("expected an error when compiling with a nonexistent renderer")
+	}
+}
+
+func TestDraftStatusUntilFullySigned(t *testing.T) {
+	renderer := &testRenderer{}
+	if err := contract.RegisterRenderer("draft-test", renderer); err != nil {
+		t.Fatal(err)
+	}
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	files := map[string]string{
+		"params.json":   `{"signatories": [{"id": "alice", "name": "Alice", "email": "alice@somewhere.com"}, {"id": "bob", "name": "Bob", "email": "bob@somewhere.com"}]}`,
+		"contract.md":   "{{#draft}}DRAFT:{{#unsigned_signatories}} {{id}}{{/unsigned_signatories}}{{/draft}}{{#is_fully_signed}}SIGNED{{/is_fully_signed}}\n",
+		"contract.json": `{"params": {"location": "./params.json"}, "template": {"format": "Mustache", "file": {"location": "./contract.md"}}}`,
+	}
+	for filename, content := range files {
+		if err := ioutil.WriteFile(path.Join(tempDir, filename), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	profile := contract.NewTestProfile("test", "", nil)
+	profile.Renderer = "draft-test"
+	ctx := contract.NewTestContext(nil, profile).WithDraftWatermark(true)
+	contractFile := path.Join(tempDir, "contract.json")
+	if err := contract.Update(contractFile, ctx); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := contract.LoadSignatureManifest(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		signer            string
+		expectedInput     string
+		expectedWatermark string
+	}{
+		{"", "DRAFT: alice bob\n", "DRAFT"},
+		{"alice", "DRAFT: bob\n", "DRAFT"},
+		{"bob", "SIGNED\n", ""},
+	}
+	for i, tc := range testCases {
+		c, err := contract.Load(contractFile, ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tc.signer) > 0 {
+			manifest.Record(&contract.SignatureRecord{
+				SignatoryID: tc.signer,
+				Email:       tc.signer + "@somewhere.com",
+				Timestamp:   time.Now().UTC(),
+				Hashes:      c.Hashes(),
+			})
+			if err := manifest.Save(); err != nil {
+				t.Fatal(err)
+			}
+			if c, err = contract.Load(contractFile, ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := c.Compile(path.Join(tempDir, "contract.html"), ctx); err != nil {
+			t.Fatal(err)
+		}
+		if renderer.inputs[i] != tc.expectedInput {
+			t.Errorf("test case %d: expected rendered contract to be \"%s\", but got \"%s\"", i, tc.expectedInput, renderer.inputs[i])
+		}
+		if renderer.jobs[i].Watermark != tc.expectedWatermark {
+			t.Errorf("test case %d: expected watermark \"%s\", but got \"%s\"", i, tc.expectedWatermark, renderer.jobs[i].Watermark)
+		}
+		if c.IsFullySigned() != (len(tc.expectedWatermark) == 0) {
+			t.Errorf("test case %d: unexpected signing status: fully signed = %t", i, c.IsFullySigned())
+		}
+	}
+}
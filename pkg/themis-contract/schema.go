@@ -0,0 +1,89 @@
+package themis_contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// validateContractParams checks the given parameters against the schema in
+// the specified file. The schema can either be a JSON Schema (in a JSON or
+// YAML file), or a Dhall type. Remote imports in Dhall files are retrieved by
+// way of the given cache.
+func validateContractParams(params map[string]interface{}, schemaFile string, cache Cache) error {
+	log.Debug().Msgf("Validating contract parameters against schema: %s", schemaFile)
+	switch path.Ext(schemaFile) {
+	case ".json", ".yml", ".yaml":
+		return validateParamsWithJSONSchema(params, schemaFile)
+	case ".dhall":
+		return validateParamsWithDhallType(params, schemaFile, cache)
+	}
+	return fmt.Errorf("unrecognized file format for schema file: %s", path.Ext(schemaFile))
+}
+
+func validateParamsWithJSONSchema(params map[string]interface{}, schemaFile string) error {
+	content, err := ioutil.ReadFile(schemaFile)
+	if err != nil {
+		return err
+	}
+	var schemaLoader gojsonschema.JSONLoader
+	if path.Ext(schemaFile) == ".json" {
+		schemaLoader = gojsonschema.NewBytesLoader(content)
+	} else {
+		var schema interface{}
+		if err := yaml.Unmarshal(content, &schema); err != nil {
+			return fmt.Errorf("failed to parse schema file %s: %s", schemaFile, err)
+		}
+		schemaLoader = gojsonschema.NewGoLoader(schema)
+	}
+	// we go via JSON to normalize the types produced by the different
+	// parameters file decoders
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	result, err := gojsonschema.Validate(schemaLoader, gojsonschema.NewBytesLoader(paramsJSON))
+	if err != nil {
+		return fmt.Errorf("failed to validate parameters against schema %s: %s", schemaFile, err)
+	}
+	if result.Valid() {
+		return nil
+	}
+	errs := make([]string, 0, len(result.Errors()))
+	for _, e := range result.Errors() {
+		errs = append(errs, fmt.Sprintf("%s: %s", e.Field(), e.Description()))
+	}
+	return &ParamsValidationError{Errors: errs}
+}
+
+// validateParamsWithDhallType type-checks the parameters against the Dhall
+// type in the given schema file. Parameters read from files of all formats
+// (including Dhall ones, which are evaluated when read) are converted to Dhall
+// using the type, so that fields that don't conform to it are reported by
+// their paths.
+func validateParamsWithDhallType(params map[string]interface{}, schemaFile string, cache Cache) error {
+	schema, err := evalDhallFile(schemaFile, cache)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate Dhall schema %s: %s", schemaFile, err)
+	}
+	if _, err := jsonToDhall(params, schema); err != nil {
+		return &ParamsValidationError{Errors: []string{err.Error()}}
+	}
+	return nil
+}
+
+// ParamsValidationError is returned when a contract's parameters do not
+// conform to its schema.
+type ParamsValidationError struct {
+	Errors []string
+}
+
+func (e *ParamsValidationError) Error() string {
+	return fmt.Sprintf("contract parameters do not match schema:\n  %s", strings.Join(e.Errors, "\n  "))
+}
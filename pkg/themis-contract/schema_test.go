@@ -0,0 +1,130 @@
+package themis_contract_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+)
+
+const testParamsSchema = `{
+	"type": "object",
+	"required": ["supplier"],
+	"properties": {
+		"supplier": {
+			"type": "object",
+			"required": ["name", "hourlyRate"],
+			"properties": {
+				"name": {"type": "string"},
+				"hourlyRate": {"type": "number"}
+			}
+		}
+	}
+}`
+
+func TestJSONSchemaValidation(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	schemaFile := path.Join(tempDir, "schema.json")
+	if err := ioutil.WriteFile(schemaFile, []byte(testParamsSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := map[string]interface{}{
+		"supplier": map[string]interface{}{
+			"name":       "Acme Corp",
+			"hourlyRate": 100,
+		},
+	}
+	if err := contract.ValidateContractParams(valid, schemaFile); err != nil {
+		t.Errorf("expected parameters to be valid, but got error: %s", err)
+	}
+
+	invalid := map[string]interface{}{
+		"supplier": map[string]interface{}{
+			"nmae":       "Acme Corp",
+			"hourlyRate": "100",
+		},
+	}
+	err = contract.ValidateContractParams(invalid, schemaFile)
+	if err == nil {
+		t.Fatal("expected parameters to be invalid, but got no error")
+	}
+	for _, expected := range []string{"supplier: name is required", "supplier.hourlyRate: Invalid type. Expected: number, given: string"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error to contain \"%s\", but got: %s", expected, err)
+		}
+	}
+}
+
+func TestDhallTypeValidation(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	files := map[string]string{
+		"schema.dhall":         "{ supplier : { name : Text, hourlyRate : Natural, nickname : Optional Text } }",
+		"params.dhall":         "{ supplier = { name = \"Acme Corp\", hourlyRate = 100, nickname = None Text } }",
+		"invalid-params.dhall": "{ supplier = { name = \"Acme Corp\", hourlyRate = \"100\", nickname = None Text } }",
+		"extra-params.dhall":   "{ supplier = { name = \"Acme Corp\", hourlyRate = 100, nickname = None Text, notes = \"Preferred\" } }",
+		"params.json":          `{"supplier": {"name": "Acme Corp", "hourlyRate": 100}}`,
+		"invalid-params.json":  `{"supplier": {"name": "Acme Corp", "hourlyRate": -1}}`,
+		"missing-params.json":  `{"client": "Acme Corp"}`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(path.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	schemaFile := path.Join(tempDir, "schema.dhall")
+	validate := func(paramsFile string) error {
+		params, err := contract.ReadContractParams(path.Join(tempDir, paramsFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return contract.ValidateContractParams(params, schemaFile)
+	}
+	// extra fields are accepted, regardless of the parameters file's format
+	for _, paramsFile := range []string{"params.dhall", "extra-params.dhall", "params.json"} {
+		if err := validate(paramsFile); err != nil {
+			t.Errorf("expected parameters in %s to be valid, but got error: %s", paramsFile, err)
+		}
+	}
+	// errors are reported by the same paths as JSON Schema validation errors
+	testCases := map[string]string{
+		"invalid-params.dhall": "\n  supplier.hourlyRate: expected a Natural, but got \"100\"",
+		"invalid-params.json":  "\n  supplier.hourlyRate: expected a Natural, but got -1",
+		"missing-params.json":  "\n  (root): missing required field \"supplier\"",
+	}
+	for paramsFile, expected := range testCases {
+		err := validate(paramsFile)
+		if err == nil {
+			t.Errorf("expected parameters in %s to be invalid, but got no error", paramsFile)
+			continue
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error for %s to contain \"%s\", but got: %s", paramsFile, expected, err)
+		}
+	}
+	// objects can be converted to Dhall maps
+	mapSchemaFile := path.Join(tempDir, "map-schema.dhall")
+	if err := ioutil.WriteFile(mapSchemaFile, []byte("{ rates : List { mapKey : Text, mapValue : Natural } }"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rates := map[string]interface{}{"hourly": 100, "daily": 700}
+	if err := contract.ValidateContractParams(map[string]interface{}{"rates": rates}, mapSchemaFile); err != nil {
+		t.Errorf("expected rates map to be valid, but got error: %s", err)
+	}
+	rates["daily"] = -1
+	err = contract.ValidateContractParams(map[string]interface{}{"rates": rates}, mapSchemaFile)
+	if err == nil || !strings.Contains(err.Error(), "\n  rates.daily: expected a Natural, but got -1") {
+		t.Errorf("expected invalid rate to be reported, but got: %v", err)
+	}
+}
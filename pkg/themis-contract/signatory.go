@@ -9,10 +9,21 @@ type Signatory struct {
 	Name  string `json:"name" yaml:"name" toml:"name"`
 	Email string `json:"email" yaml:"email" toml:"email"`
 
-	Signature  string `json:"signature,omitempty" yaml:"signature,omitempty" toml:"signature,omitempty"`       // The path to the image to use for this person's signature.
-	SignedDate string `json:"signed_date,omitempty" yaml:"signed_date,omitempty" toml:"signed_date,omitempty"` // The date on which the signature was created.
+	SSHPublicKey string `json:"ssh_public_key,omitempty" yaml:"ssh_public_key,omitempty" toml:"ssh_public_key,omitempty"` // The SSH public key with which this person is expected to sign (optional).
+
+	Signature         string `json:"signature,omitempty" yaml:"signature,omitempty" toml:"signature,omitempty"`                            // The path to the image to use for this person's signature.
+	DetachedSignature string `json:"detached_signature,omitempty" yaml:"detached_signature,omitempty" toml:"detached_signature,omitempty"` // The path to this person's detached OpenPGP signature over the contract's hashes.
+	SSHSignature      string `json:"ssh_signature,omitempty" yaml:"ssh_signature,omitempty" toml:"ssh_signature,omitempty"`                // The path to this person's SSH signature over the contract's hashes.
+	SignedDate        string `json:"signed_date,omitempty" yaml:"signed_date,omitempty" toml:"signed_date,omitempty"`                      // The date on which the signature was created.
+	Stale             bool   `json:"stale,omitempty" yaml:"stale,omitempty" toml:"stale,omitempty"`                                        // Was the signature applied to an older version of the contract (i.e. must this person sign again)?
+}
+
+// IsSigned checks whether this signatory has signed the current version of the
+// contract.
+func (s *Signatory) IsSigned() bool {
+	return len(s.SignedDate) > 0 && !s.Stale
 }
 
 func (s *Signatory) String() string {
-	return fmt.Sprintf("Signatory{Id: \"%s\", Name: \"%s\", Email: \"%s\", Signature: \"%s\", SignedDate: \"%s\"}", s.Id, s.Name, s.Email, s.Signature, s.SignedDate)
+	return fmt.Sprintf("Signatory{Id: \"%s\", Name: \"%s\", Email: \"%s\", Signature: \"%s\", DetachedSignature: \"%s\", SSHSignature: \"%s\", SignedDate: \"%s\", Stale: %t}", s.Id, s.Name, s.Email, s.Signature, s.DetachedSignature, s.SSHSignature, s.SignedDate, s.Stale)
 }
@@ -8,6 +8,7 @@ import (
 	"path"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
@@ -15,18 +16,24 @@ import (
 type SignatureParameter string
 
 const (
-	SignatureEmail SignatureParameter = "email"
-	SignatureImage SignatureParameter = "image"
+	SignatureEmail  SignatureParameter = "email"
+	SignatureImage  SignatureParameter = "image"
+	SignatureKeyID  SignatureParameter = "key-id"
+	SignatureSSHKey SignatureParameter = "ssh-key"
 
 	SignatureTimestampFormat = "2 January 2006"
 )
 
-// Signature is what we apply to a contract to sign it.
-// TODO: Investigate GPG-based signing.
+// Signature is what we apply to a contract to sign it. A signature can consist
+// of an image (which is rendered into the compiled contract) and/or one or more
+// signing keys (an OpenPGP key and/or an SSH key, with which we
+// cryptographically sign the contract's hashes).
 type Signature struct {
-	Name      string `json:"name"`  // A short, descriptive name for the signature.
-	Email     string `json:"email"` // The e-mail address associated with a specific signature.
-	ImagePath string `json:"image"` // The filesystem path to the image constituting the image-based signature.
+	Name      string `json:"name"`              // A short, descriptive name for the signature.
+	Email     string `json:"email"`             // The e-mail address associated with a specific signature.
+	ImagePath string `json:"image"`             // The filesystem path to the image constituting the image-based signature.
+	KeyID     string `json:"key_id,omitempty"`  // The ID or fingerprint of the OpenPGP key to use when signing (optional).
+	SSHKey    string `json:"ssh_key,omitempty"` // The path to the SSH private key to use when signing (optional).
 
 	id   string // A unique ID associated with this signature (derived from the filesystem path).
 	path string // The filesystem path to the signature's information.
@@ -74,11 +81,19 @@ func loadSignatureDB(home string) (*SignatureDB, error) {
 	return db, nil
 }
 
-func (db *SignatureDB) newSignature(name, email, sigImage string) (*Signature, error) {
+func (db *SignatureDB) newSignature(name, email, sigImage, keyID, sshKey string) (*Signature, error) {
 	id, err := slugify(name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate ID from signature name \"%s\": %s", name, err)
 	}
+	if len(sigImage) == 0 && len(keyID) == 0 && len(sshKey) == 0 {
+		return nil, fmt.Errorf("a signature requires an image and/or a signing key (a GPG key ID or an SSH key)")
+	}
+	if len(sshKey) > 0 {
+		if sshKey, err = sshKeyPath(sshKey); err != nil {
+			return nil, err
+		}
+	}
 	if _, exists := db.sigs[id]; exists {
 		return nil, fmt.Errorf("signature with ID \"%s\" (derived from name \"%s\") already exists", id, name)
 	}
@@ -87,18 +102,22 @@ func (db *SignatureDB) newSignature(name, email, sigImage string) (*Signature, e
 		return nil, fmt.Errorf("failed to create folder for new signature \"%s\": %s", sigPath, err)
 	}
 	log.Debug().Msgf("Created new folder for signature: %s", sigPath)
-	newSigImagePath := path.Join(sigPath, path.Base(sigImage))
-	// try copying the image
-	if err := copyFile(sigImage, newSigImagePath); err != nil {
-		return nil, fmt.Errorf("failed to copy supplied signature image \"%s\" for new signature: %s", sigImage, err)
-	}
-	log.Debug().Msgf("Copied signature image from %s to %s", sigImage, newSigImagePath)
 	sig := &Signature{
-		Name:      name,
-		Email:     email,
-		ImagePath: path.Base(sigImage),
-		id:        id,
-		path:      sigPath,
+		Name:   name,
+		Email:  email,
+		KeyID:  keyID,
+		SSHKey: sshKey,
+		id:     id,
+		path:   sigPath,
+	}
+	if len(sigImage) > 0 {
+		newSigImagePath := path.Join(sigPath, path.Base(sigImage))
+		// try copying the image
+		if err := copyFile(sigImage, newSigImagePath); err != nil {
+			return nil, fmt.Errorf("failed to copy supplied signature image \"%s\" for new signature: %s", sigImage, err)
+		}
+		log.Debug().Msgf("Copied signature image from %s to %s", sigImage, newSigImagePath)
+		sig.ImagePath = path.Base(sigImage)
 	}
 	if err := sig.Save(); err != nil {
 		return nil, fmt.Errorf("failed to save signature: %s", err)
@@ -156,6 +175,8 @@ func ValidSignatureParamNames() []string {
 	return []string{
 		string(SignatureEmail),
 		string(SignatureImage),
+		string(SignatureKeyID),
+		string(SignatureSSHKey),
 	}
 }
 
@@ -173,12 +194,14 @@ func loadSignature(sigPath string) (*Signature, error) {
 	sig.id = path.Base(sigPath)
 	sig.path = sigPath
 
-	if len(sig.ImagePath) == 0 {
-		return nil, fmt.Errorf("signature \"%s\" is missing an image", sig.id)
+	if !sig.hasImageOrKey() {
+		return nil, fmt.Errorf("signature \"%s\" has neither an image nor a signing key", sig.id)
 	}
-	sigImagePath := path.Join(sigPath, sig.ImagePath)
-	if _, err = os.Stat(sigImagePath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("cannot find image file for signature \"%s\": %s", sig.id, sigImagePath)
+	if len(sig.ImagePath) > 0 {
+		sigImagePath := path.Join(sigPath, sig.ImagePath)
+		if _, err = os.Stat(sigImagePath); os.IsNotExist(err) {
+			return nil, fmt.Errorf("cannot find image file for signature \"%s\": %s", sig.id, sigImagePath)
+		}
 	}
 	log.Debug().Msgf("Loaded signature: %v", &sig)
 	return &sig, nil
@@ -195,22 +218,83 @@ func (s *Signature) Save() error {
 	return nil
 }
 
-// applyTo will attempt to apply this signature to the contract in the specified
-// path (assuming it's the full path to the contract file) on behalf of the
-// specified signatory ID. On success, returns the path to the image we've just
-// copied across.
-func (s *Signature) applyTo(contractPath string, sigId string) (string, error) {
-	sigImageSrcPath := path.Join(s.path, s.ImagePath)
-	sigImageDestPath := path.Join(path.Dir(contractPath), sigImageFilename(sigId))
-	log.Debug().Msgf("Copying signature file from %s to %s", sigImageSrcPath, sigImageDestPath)
-	if err := copyFile(sigImageSrcPath, sigImageDestPath); err != nil {
-		return "", fmt.Errorf("failed to copy signature from %s to %s: %s", sigImageSrcPath, sigImageDestPath, err)
-	}
-	return sigImageDestPath, nil
+// hasImageOrKey checks whether this signature has anything with which to sign
+// a contract.
+func (s *Signature) hasImageOrKey() bool {
+	return len(s.ImagePath) > 0 || len(s.KeyID) > 0 || len(s.SSHKey) > 0
+}
+
+// applyTo will attempt to apply this signature to the given contract on behalf
+// of the specified signatory. If the signature has an image, it is copied
+// alongside the contract. If the signature has a GPG key, a detached OpenPGP
+// signature over the contract's hashes is generated alongside the contract.
+// Similarly, if the signature has an SSH key (or, failing that, if an SSH
+// private key file is supplied), an SSH signature over the contract's hashes
+// is generated. On success, returns a record of the
+// signature for the contract's signature manifest, as well as the paths to all
+// of the files we've just generated.
+func (s *Signature) applyTo(c *Contract, signatory *Signatory, sshKeyFile string) (*SignatureRecord, []string, error) {
+	contractDir := path.Dir(c.path.localPath)
+	record := &SignatureRecord{
+		SignatoryID: signatory.Id,
+		Email:       signatory.Email,
+		Timestamp:   time.Now().UTC(),
+		Hashes:      c.Hashes(),
+	}
+	if len(s.SSHKey) > 0 {
+		sshKeyFile = s.SSHKey
+	}
+	sigFiles := make([]string, 0)
+	if len(s.ImagePath) > 0 {
+		sigImageSrcPath := path.Join(s.path, s.ImagePath)
+		sigImageDestPath := path.Join(contractDir, sigImageFilename(signatory.Id))
+		log.Debug().Msgf("Copying signature file from %s to %s", sigImageSrcPath, sigImageDestPath)
+		if err := copyFile(sigImageSrcPath, sigImageDestPath); err != nil {
+			return nil, nil, fmt.Errorf("failed to copy signature from %s to %s: %s", sigImageSrcPath, sigImageDestPath, err)
+		}
+		imageHash, err := hashOfFile(sigImageDestPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		record.Image = path.Base(sigImageDestPath)
+		record.ImageHash = imageHash
+		sigFiles = append(sigFiles, sigImageDestPath)
+	}
+	if len(s.KeyID) > 0 {
+		statement := signingStatement(record.SignatoryID, record.Email, record.Hashes)
+		detachedSigPath := path.Join(contractDir, sigDetachedFilename(signatory.Id))
+		log.Debug().Msgf("Signing statement with GPG key \"%s\":\n%s", s.KeyID, statement)
+		fingerprint, err := gpgDetachSign(s.KeyID, []byte(statement), detachedSigPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		record.KeyID = fingerprint
+		record.DetachedSignature = path.Base(detachedSigPath)
+		sigFiles = append(sigFiles, detachedSigPath)
+	}
+	if len(sshKeyFile) > 0 {
+		statement := signingStatement(record.SignatoryID, record.Email, record.Hashes)
+		sshSigPath := path.Join(contractDir, sigSSHFilename(signatory.Id))
+		log.Debug().Msgf("Signing statement with SSH key %s:\n%s", sshKeyFile, statement)
+		sshSig, err := sshSign(sshKeyFile, []byte(statement))
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := ioutil.WriteFile(sshSigPath, sshSig, 0644); err != nil {
+			return nil, nil, fmt.Errorf("failed to write SSH signature to %s: %s", sshSigPath, err)
+		}
+		record.SSHPublicKey, err = sshPublicKey(sshKeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		record.SSHSignature = path.Base(sshSigPath)
+		sigFiles = append(sigFiles, sshSigPath)
+	}
+	return record, sigFiles, nil
 }
 
 func (s *Signature) String() string {
-	return fmt.Sprintf("Signature{Name: \"%s\", Email: \"%s\", ImagePath: \"%s\"}", s.Name, s.Email, s.ImagePath)
+	return fmt.Sprintf("Signature{Name: \"%s\", Email: \"%s\", ImagePath: \"%s\", KeyID: \"%s\", SSHKey: \"%s\"}", s.Name, s.Email, s.ImagePath, s.KeyID, s.SSHKey)
 }
 
 func (s *Signature) Display() string {
@@ -218,7 +302,17 @@ func (s *Signature) Display() string {
 	if len(s.ImagePath) > 0 {
 		sigImagePath = path.Join(s.path, s.ImagePath)
 	}
-	return fmt.Sprintf("%s (ID: %s, e-mail: %s, image: %s)", s.Name, s.id, s.Email, sigImagePath)
+	keys := make([]string, 0)
+	if len(s.KeyID) > 0 {
+		keys = append(keys, fmt.Sprintf("GPG %s", s.KeyID))
+	}
+	if len(s.SSHKey) > 0 {
+		keys = append(keys, fmt.Sprintf("SSH %s", s.SSHKey))
+	}
+	if len(keys) == 0 {
+		keys = append(keys, "(none)")
+	}
+	return fmt.Sprintf("%s (ID: %s, e-mail: %s, image: %s, signing keys: %s)", s.Name, s.id, s.Email, sigImagePath, strings.Join(keys, ", "))
 }
 
 //------------------------------------------------------------------------------
@@ -247,6 +341,28 @@ func sigImageFilename(sigId string) string {
 	return fmt.Sprintf("sig--%s.png", sigId)
 }
 
+func sigDetachedFilename(sigId string) string {
+	return fmt.Sprintf("sig--%s.asc", sigId)
+}
+
+func sigSSHFilename(sigId string) string {
+	return fmt.Sprintf("sig--%s.sig", sigId)
+}
+
+// signingStatement generates the canonical text that a signatory
+// cryptographically signs. It binds the signatory to the specific versions of
+// the contract, parameters and template files.
+func signingStatement(signatoryID, email string, hashes *ContractHashes) string {
+	return fmt.Sprintf(
+		"Themis Contract signature\nSignatory: %s <%s>\nContract: %s\nParams: %s\nTemplate: %s\n",
+		signatoryID,
+		email,
+		hashes.Contract,
+		hashes.Params,
+		hashes.Template,
+	)
+}
+
 func themisContractSignaturesPath(home string) string {
 	return path.Join(home, "signatures")
 }
@@ -254,12 +370,3 @@ func themisContractSignaturesPath(home string) string {
 func initSignatures(home string) error {
 	return os.MkdirAll(themisContractSignaturesPath(home), 0755)
 }
-
-// TODO: Use Git to extract the signed date instead of just checking the timestamp of the file.
-func getLatestSignedDate(sigFile string) (string, error) {
-	fi, err := os.Stat(sigFile)
-	if err != nil {
-		return "", err
-	}
-	return fi.ModTime().Format(SignatureTimestampFormat), nil
-}
@@ -0,0 +1,68 @@
+package themis_contract_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+)
+
+func TestSigningStatementRoundTrip(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory for testing: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	hashes := &contract.ContractHashes{
+		Contract: "4cbd373af2669e5c5fc5ffc7ecd02abc16aa8fc0855f1de712a7940bb2245aee",
+		Params:   "6212e73deb62a698f2cf6178ab48cdd5a5615504253d5c0d06fa058ca369d1d0",
+		Template: "016b3829eaee279f2ce7a740a974f1ac75758893c42d220865a487c35ff9a890",
+	}
+	statement := contract.SigningStatement("manderson", "manderson@somewhere.com", hashes)
+	// the statement's format must remain stable, since existing signatures
+	// are verified against reconstructed statements
+	const expected = `Themis Contract signature
+Signatory: manderson <manderson@somewhere.com>
+Contract: 4cbd373af2669e5c5fc5ffc7ecd02abc16aa8fc0855f1de712a7940bb2245aee
+Params: 6212e73deb62a698f2cf6178ab48cdd5a5615504253d5c0d06fa058ca369d1d0
+Template: 016b3829eaee279f2ce7a740a974f1ac75758893c42d220865a487c35ff9a890
+`
+	if statement != expected {
+		t.Errorf("expected signing statement:\n%s\nbut got:\n%s", expected, statement)
+	}
+
+	// the statement must be reconstructable from the signature manifest
+	manifest, err := contract.LoadSignatureManifest(tempDir)
+	if err != nil {
+		t.Fatalf("failed to load signature manifest: %v", err)
+	}
+	manifest.Record(&contract.SignatureRecord{
+		SignatoryID: "manderson",
+		Email:       "manderson@somewhere.com",
+		Timestamp:   time.Now().UTC(),
+		Hashes:      hashes,
+	})
+	if err := manifest.Save(); err != nil {
+		t.Fatalf("failed to save signature manifest: %v", err)
+	}
+	loaded, err := contract.LoadSignatureManifest(tempDir)
+	if err != nil {
+		t.Fatalf("failed to load saved signature manifest: %v", err)
+	}
+	r := loaded.ForSignatory("manderson")
+	if r == nil {
+		t.Fatalf("expected to find signature record for \"manderson\"")
+	}
+	if reconstructed := contract.SigningStatement(r.SignatoryID, r.Email, r.Hashes); reconstructed != statement {
+		t.Errorf("expected reconstructed signing statement:\n%s\nbut got:\n%s", statement, reconstructed)
+	}
+
+	changed := *hashes
+	changed.Template = "cafc51206871f47e56cff9cb9000d4cd73637149864b72d990e0d12fcafc78be"
+	if contract.SigningStatement("manderson", "manderson@somewhere.com", &changed) == statement {
+		t.Errorf("expected signing statement to change when the template's hash changes")
+	}
+}
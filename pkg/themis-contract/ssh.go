@@ -0,0 +1,112 @@
+package themis_contract
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// The namespace in which all of our SSH signatures are made, which prevents
+// signatures made for other purposes (e.g. Git commits) from being accepted
+// as contract signatures.
+const sshSignatureNamespace = "themis-contract"
+
+// sshSign uses `ssh-keygen -Y sign` to produce an SSHSIG-formatted signature of
+// the given data using the private key in the specified file. On success,
+// returns the armored signature.
+func sshSign(keyFile string, data []byte) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("ssh-keygen", "-Y", "sign", "-f", keyFile, "-n", sshSignatureNamespace)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	log.Debug().Msgf("ssh-keygen -Y sign output:\n%s\n", stderr.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign with SSH key %s: %v", keyFile, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// sshVerify uses `ssh-keygen -Y verify` to check that the signature in the
+// given file was made over the specified data by a key that the allowed
+// signers file associates with the given identity (e-mail address). An error
+// is only returned if we could not execute `ssh-keygen` - a bad signature is
+// reported by way of the returned boolean and reason.
+func sshVerify(allowedSignersFile, identity, sigFile string, data []byte) (bool, string, error) {
+	cmd := exec.Command(
+		"ssh-keygen",
+		"-Y", "verify",
+		"-f", allowedSignersFile,
+		"-I", identity,
+		"-n", sshSignatureNamespace,
+		"-s", sigFile,
+	)
+	cmd.Stdin = bytes.NewReader(data)
+	output, err := cmd.CombinedOutput()
+	log.Debug().Msgf("ssh-keygen -Y verify output:\n%s\n", string(output))
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return false, "", fmt.Errorf("failed to execute ssh-keygen: %v", err)
+		}
+		reason := strings.Split(strings.TrimSpace(string(output)), "\n")[0]
+		if len(reason) == 0 {
+			reason = "SSH signature could not be verified"
+		}
+		return false, reason, nil
+	}
+	return true, "", nil
+}
+
+// writeSSHAllowedSigners writes a temporary allowed signers file that only
+// trusts the given public key for the given identity (e-mail address), within
+// our signature namespace. The caller is responsible for removing the file.
+func writeSSHAllowedSigners(identity, publicKey string) (string, error) {
+	f, err := ioutil.TempFile("", "themis-contract-allowed-signers")
+	if err != nil {
+		return "", fmt.Errorf("failed to create allowed signers file: %v", err)
+	}
+	defer f.Close()
+	line := fmt.Sprintf("%s namespaces=\"%s\" %s\n", identity, sshSignatureNamespace, strings.TrimSpace(publicKey))
+	if _, err := f.WriteString(line); err != nil {
+		return "", fmt.Errorf("failed to write allowed signers file %s: %v", f.Name(), err)
+	}
+	return f.Name(), nil
+}
+
+// sshKeyPath returns the absolute path to the given SSH private key file,
+// making sure that we can actually use the key for signing.
+func sshKeyPath(keyFile string) (string, error) {
+	absKeyFile, err := filepath.Abs(keyFile)
+	if err != nil {
+		return "", err
+	}
+	if _, err := sshPublicKey(absKeyFile); err != nil {
+		return "", err
+	}
+	return absKeyFile, nil
+}
+
+// sshPublicKey derives the public key for the private key in the given file.
+func sshPublicKey(keyFile string) (string, error) {
+	output, err := exec.Command("ssh-keygen", "-y", "-f", keyFile).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to derive public key from SSH key %s: %v", keyFile, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// sshPublicKeysEqual checks whether the two given public keys (in the format
+// of OpenSSH's public key files) are the same key, ignoring their comments.
+func sshPublicKeysEqual(a, b string) bool {
+	aParts, bParts := strings.Fields(a), strings.Fields(b)
+	if len(aParts) < 2 || len(bParts) < 2 {
+		return false
+	}
+	return aParts[0] == bParts[0] && aParts[1] == bParts[1]
+}
@@ -0,0 +1,95 @@
+package themis_contract_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+)
+
+func TestSSHSignatureVerification(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not available")
+	}
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory for testing: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	keyFile := path.Join(tempDir, "id_ed25519")
+	if output, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", keyFile).CombinedOutput(); err != nil {
+		t.Fatalf("failed to generate SSH key: %v\n%s", err, output)
+	}
+	pubKey, err := ioutil.ReadFile(keyFile + ".pub")
+	if err != nil {
+		t.Fatalf("failed to read SSH public key: %v", err)
+	}
+	allowedSigners := "manderson@somewhere.com namespaces=\"themis-contract\" " + strings.TrimSpace(string(pubKey)) + "\n"
+	allowedSignersFile := path.Join(tempDir, "allowed_signers")
+	if err := ioutil.WriteFile(allowedSignersFile, []byte(allowedSigners), 0644); err != nil {
+		t.Fatalf("failed to write allowed signers file: %v", err)
+	}
+
+	data := []byte("Some statement to sign\n")
+	sig, err := contract.SSHSign(keyFile, data)
+	if err != nil {
+		t.Fatalf("failed to sign data: %v", err)
+	}
+	sigFile := path.Join(tempDir, "data.sig")
+	if err := ioutil.WriteFile(sigFile, sig, 0644); err != nil {
+		t.Fatalf("failed to write signature file: %v", err)
+	}
+
+	testCases := []struct {
+		identity string
+		data     []byte
+		expected bool
+	}{
+		{"manderson@somewhere.com", data, true},
+		{"manderson@somewhere.com", []byte("Some other statement\n"), false},
+		{"bronwyn@savvy.com", data, false},
+	}
+	for i, tc := range testCases {
+		good, reason, err := contract.SSHVerify(allowedSignersFile, tc.identity, sigFile, tc.data)
+		if err != nil {
+			t.Fatalf("case %d: failed to verify signature: %v", i, err)
+		}
+		if good != tc.expected {
+			t.Errorf("case %d: expected verification result %t, but got %t (%s)", i, tc.expected, good, reason)
+		}
+	}
+}
+
+func TestSSHOnlySignature(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not available")
+	}
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory for testing: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if _, err := contract.AddTestSignature(tempDir, "Nothing", "nothing@somewhere.com", "", "", ""); err == nil {
+		t.Error("expected a signature without an image or any signing keys to be rejected")
+	}
+	keyFile := path.Join(tempDir, "id_ed25519")
+	if output, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", keyFile).CombinedOutput(); err != nil {
+		t.Fatalf("failed to generate SSH key: %v\n%s", err, output)
+	}
+	sig, err := contract.AddTestSignature(tempDir, "Michael Anderson", "manderson@somewhere.com", "", "", keyFile)
+	if err != nil {
+		t.Fatalf("expected SSH key-only signature to be accepted, but got: %v", err)
+	}
+	if sig.SSHKey != keyFile {
+		t.Errorf("expected signature's SSH key to be %s, but got %s", keyFile, sig.SSHKey)
+	}
+	if display := sig.Display(); !strings.Contains(display, "signing keys: SSH "+keyFile) {
+		t.Errorf("expected signature display to include its SSH key, but got: %s", display)
+	}
+}
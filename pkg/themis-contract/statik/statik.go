@@ -8,7 +8,7 @@ import (
 
 
 func init() {
-	data := "PK\x03\x04\x14\x00\x08\x00\x08\x00%g\x89R\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x1a\x00	\x00pandoc/header-includes.texUT\x05\x00\x01'Op`L\xccK\x0e\xc20\x10\x03\xd0}O\xe1M\xc4E8F7\x0dq\xcbH\xd3L\x95\x99\xf0\x11\xe2\xee\x08\x85\x05\xcbg[N	g\xab\xa7\xc0R\nn\xe2\x92\x95h]	\x95JG\x18b\xc9J\x9fR\xc2\x95z\xac]\xb1Z\x83\xc7S\xa5np\xa9\x17\xe2Nt\xe7o:z\xd9\xea\x12\xbd\x11\xd9\x1e\xf4i.\\\xe7\xb0\xe3\xfb\xfdz\x0ff\x8b\xb0\xfd?\xd9\xa5\x0c~\x02\x00\x00\xff\xffPK\x07\x08\xa2$\xe3\xafr\x00\x00\x00\x98\x00\x00\x00PK\x03\x04\x14\x00\x08\x00\x08\x00%g\x89R\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x19\x00	\x00pandoc/include-before.texUT\x05\x00\x01'Op`\x1c\x8d1\x0e\xc20\x10\x04\xfb\xbc\xe2\x1a7HT\xfc\x84:\xcda/\x89\xa5\xf8lqkP\x14\xe5\xef(nwg4!\xc8\x13\xa9G\xb8p\x85h\xa9\xdd(\xf5-\xde4B^\xe0\x0f\xb0\xf11s\x83\xac\xd0\x94mqQK\xd7<\x85 N\xfd\x0c\xe9\xc2R\x8d\xbd\xc0(\xb1\x1aa\x9cf\xae\xd9\x9b.p\xee\x1b\x0e\x94\xc6\xfd\x9c\xe6\xef(\xdc\x8e\xfb\x03\xe5\xfc\x07\x00\x00\xff\xffPK\x07\x08\xed\xe6\x9aPq\x00\x00\x00\x87\x00\x00\x00PK\x03\x04\x14\x00\x08\x00\x08\x00%g\x89R\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x1b\x00	\x00pandoc/pandoc-defaults.yamlUT\x05\x00\x01'Op`\\R\xcdn\xdb<\x10\xbc\xf3)\x16\xd21\x1fc\xe4\xaa\xdb\x87\xa2\xd7\xa2h\xd2S\x11$+rh\x11\xa6H\x81\xbbr\xed\xb7/\xf4c\x1b\xedI\xe2\xce\xccrv\xb8\xa1\x96\xb1\xa3\x91\xeb\xc9\x97\xdf\xf9) ;\xf8\x0f\x1f\xcf\xf2\xd4Wv'(\xfc\x87L\x9c\xc5\x98\x96~\n(\x06\x1a^h\x00\xfb\x98\x8fB2\x949y\xcaE\xa9\x07\xe5y\xecQ\xe1I\xe04\x96,\xa6%\x19bP\xbb\x0bl\xc2\x19Il\x7f\xed\xc8\xbe\x18#\xca\xd9s*\x19\x1di\x9da\x04)XW\xb2r\xcc\xf0{\xd1\xb4\xf45s\x9f \x84\xcb\x94\xa2\x8bz\xbf\x81\x16\xb3\xd4\xb3\xc0S\xc9T\x91X\xe3\x197\x874\x15\x89\x9b\x95]a\x17\xc1\xa3\xf1\xb7\xd5\xf2\xc3\xf06\x82\xbd\x9d\x1f\xc4/\xb3h\x19)\xb1\xe2B\xa2\xd7\xc5\x8d\x16b\xef\x97\x8f\x0e \xc58-\xb0i	\xec\x06\x8a\x8a\x91b^\xb1\x14Eoa\xf5 \xa6\x10\x13hb\x1dL\xcc.\xcd\x1e6\xe65%\xd4\x8e~5\xdb\x9f\xdd1yV\\\x9a\xf7;\xb5G(\x15\xb6/\xfe\xba\x90\xff.\xef\\\xd3\xd2[\xe5,\xa1\xd4\x91\xb7\xa8\xca\x19uu3q\xf6\xc5\xd1\xff\xafo&\xc4\xa4\xa8\xd2\x19\xa2\x96^\x01\x1aT'\xe9\x0e\x87c\xd4a\xee\x9f]\x19\x0f)\xa2z>\xc5t\xd8\x84\xd6\xd5\"R\x11\x0c\x91\xa5\x7fkf\x84\xb2g\xe5\xad\xe7\x0f\x04\xd4e\xaf\xd6\xb8>[\x81\xebB)\x9f\xa4|\x14\xe2\n\xaa\xc8~\xdd\x1a\x16j^\xb7\xe0\xe9\xa51\xb4\xbc\xca\xf7\x8a\x10/\xcb\x94;\xd2\xfcw'I\xf3n\xfe\x04\x00\x00\xff\xffPK\x07\x08\x1c\x98L\xa8\x94\x01\x00\x00\xc2\x02\x00\x00PK\x03\x04\x14\x00\x08\x00\x08\x00%g\x89R\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x1d\x00	\x00templates/contract.dhall.tmplUT\x05\x00\x01'Op`\x84\x92\xddn\xdb0\x0c\x85\xef\xfd\x14D\xaf\x179u~l\x07\xc8\xc5\xb0\xa1\xd8\xc50\x0c[\xf7\x00\x8cL\xdb\xc2\xf4\x13H\xcc\nC\xd0\xbb\x0fu\x9cduW\xf4\xce\xa6\x8e>\x1d\x1e2.2\x00\x80\xcf\x0e\xacc0\xaeQ\xed\x00\xdc\xab\x00\xad\xd2\x04\x0bP\x0c*\x00\x9e\xd8\x19d%Q\xeb\x01:\xb2\xe4\x91\xa9\x01\xb4\x0d\x18\xb4\xd8Q\x03\x87aD=\xf6dT\x80O\xce\xb2G\xc9\x02>\xda\x01d\x8f\xb6\xa3\x00\x06\x078\xd0\x0c\xe7\xfe\x90\x7f\xf2\x8a\x99\xac\xc8\x16)\xcb4\xf1\x84\xb9P`\x0f=\xf31\xec\xf2\xdc\xe3\x93\xe8\x14\xf7\xa7\xc3)\x90\x97\xce2Y\x16\xd2\x99\\\xd9\xd6y\x83:\x0c\x81\xc9\x84\x9cG\xc4BN\x8c\xdc``\xf2\xb9t\xb6U]~D\xf9\x1b;\x12M\x8fZ\x8f\xceC\x8f\xc5f\xbb[\xdeo\x0f\xab\xaa\xa8	\x89\x8a\xb2n\x0bI%\x96\xeb%\xd6\xe5\xba\xbdGYn\xcaMU\xd5+\xb9.\x9a\xa2XV\xdb\x0d\xae\xabR\xae6m[cU/\xcf\xfe/\xaf\xc2n\xd6\x8a\xb8\xf54\xbe\x1a\xe1\x88\x1eM\x98~\xcf%\xed$\xb2r\x16\xf6p\x17\xa3\xf8>*\x1e\x94&\xf1u:I\xe9\xee\xaa\xff\x00=\x86\xfe\xb5\xf6\x0b\x86\xfe_]\xca\xce\xea\xd31\xb0'4\xb0\x87\x18U\x0b\xe2\xd7TH\xe9\xa73\xf4\xb6\x8f\x8b\xee}\x17W\xe5+\x0f1\x92\x0e\x94\xd27gi\x1e\xcd\xb3\xeb\x1f\xd4\xc6H\xb6I\x17\xb7L\xe6\xa8\x91\xe9E@\xe3\xa4\x9f\xd7bFx\x9c\xc4\x0f\xe3\xb9\x88\xf1Z\x11\xe7\xd2\x84=\xa3\xc7\x1d\xbfa\xff\xdb\xf3\xed\xfe[\xf1\xc3\xbc\xf9\x97W\xe6	\xc0u\x12\xb7\xaf\x94e\xca^w\xe6o\x00\x00\x00\xff\xffPK\x07\x08\\\xa9G\xb6\xab\x01\x00\x00\x95\x03\x00\x00PK\x01\x02\x14\x03\x14\x00\x08\x00\x08\x00%g\x89R\xa2$\xe3\xafr\x00\x00\x00\x98\x00\x00\x00\x1a\x00	\x00\x00\x00\x00\x00\x00\x00\x00\x00\xb4\x81\x00\x00\x00\x00pandoc/header-includes.texUT\x05\x00\x01'Op`PK\x01\x02\x14\x03\x14\x00\x08\x00\x08\x00%g\x89R\xed\xe6\x9aPq\x00\x00\x00\x87\x00\x00\x00\x19\x00	\x00\x00\x00\x00\x00\x00\x00\x00\x00\xb4\x81\xc3\x00\x00\x00pandoc/include-before.texUT\x05\x00\x01'Op`PK\x01\x02\x14\x03\x14\x00\x08\x00\x08\x00%g\x89R\x1c\x98L\xa8\x94\x01\x00\x00\xc2\x02\x00\x00\x1b\x00	\x00\x00\x00\x00\x00\x00\x00\x00\x00\xb4\x81\x84\x01\x00\x00pandoc/pandoc-defaults.yamlUT\x05\x00\x01'Op`PK\x01\x02\x14\x03\x14\x00\x08\x00\x08\x00%g\x89R\\\xa9G\xb6\xab\x01\x00\x00\x95\x03\x00\x00\x1d\x00	\x00\x00\x00\x00\x00\x00\x00\x00\x00\xb4\x81j\x03\x00\x00templates/contract.dhall.tmplUT\x05\x00\x01'Op`PK\x05\x06\x00\x00\x00\x00\x04\x00\x04\x00G\x01\x00\x00i\x05\x00\x00\x00\x00"
+	data := "PK\x03\x04\x14\x00\x08\x00\x08\x00\x8d\x8a&W\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x1a\x00	\x00pandoc/header-includes.texUT\x05\x00\x01\xda\xb4\xf8dL\x8c;\x0e\x830\x10D{N1\x8d\x95\x8b\xe4\x1848\x1e\xc8J\x8b\x17y\xd7\xf9\x08q\xf7(\"E\xca\x99\xf7\xf4R\xc2\xd5\xea%0\x95\x82\x87\xb8d%ZWB\xa5\xd2\x11\x86\x98\xb2\xd2\x87\x94p\xa7nsW\xcc\xd6\xe0\xf1V\xa9\x0b\\\xea\x8dx\x12\xdd\xf9SO.K\x9d\xa27\"\xdb\x8b>\x8c\x85\xf3\x18\xb6}\xdb\xfbq\xcel\x11\xb6\xfe?\xab\x94\xd6\x95\xfb\xf1\x19\x00PK\x07\x08\xa2$\xe3\xafq\x00\x00\x00\x98\x00\x00\x00PK\x03\x04\x14\x00\x08\x00\x08\x00\x8d\x8a&W\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x19\x00	\x00pandoc/include-before.texUT\x05\x00\x01\xda\xb4\xf8d\x1c\x8d1\x0e\xc20\x10\x04\xfb\xbc\xe2\x1a7HT\xfc\x84:\xcda/\x89\xa5\xf8lqkP\x14\xe5\xef(nwg4!\xc8\x13\xa9G\xb8p\x85h\xa9\xdd(\xf5-\xde4B^\xe0\x0f\xb0\xf11s\x83\xac\xd0\x94mqQK\xd7<\x85 N\xfd\x0c\xe9\xc2R\x8d\xbd\xc0(\xb1\x1aa\x9cf\xae\xd9\x9b.p\xee\x1b\x0e\x94\xc6\xfd\x9c\xe6\xef(\xdc\x8e\xfb\x03\xe5\xfc\x0f\x00PK\x07\x08\xed\xe6\x9aPn\x00\x00\x00\x87\x00\x00\x00PK\x03\x04\x14\x00\x08\x00\x08\x00V	Q]\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00 \x00	\x00pandoc/pandoc-defaults.docx.yamlUT\x05\x00\x01\x14\xcb\xd2jl\x91\xc1n\xdc:\x0cE\xf7\xfa\x8a\x8b\xf1\xe2\xbd\"\xe3|\x80\xb7\xdd\x17\x05\x9aMQ\x14	#Q\x19!\xb2h\x90T3\xee\xd7\x17v'3h\xd1\x9dH^\x1c\x1eBYe\x9e0\x93\xbe&ykw\x99[\xe4\xf4\x98\xca\x0f\xbb{V\x8a\xaf\xec\x9c\x1em\xa1f\xc1eB\x92x\x0e\xc1\x9cZ\xa2*\x8d'\xb8v\x0ea\xc0\xa7>?\xb3\xc28z\x91f\xa1\xed\xf5\xf8^\xdf\x82\x0f\x82\xd8\xcde.?\x19~b\x98\xaf\x95\x0d\xdd8\xa1\xb4\xbd\xf5\xc2\x8d\x95\x9c\xd3\xb6\xb0\xcf\xdc\xfc\x88\xa5Rd\x10r\xa9\x8cH\xb5r\n\x03\x9e\x943\xebf}\xbf\xb9=m\x84U\xbabQ\xd9\x93Yjb\xc5\xff\x0f'\x9e\x8b\xe1\xa34W\x8a\x8e\xb7R+\xa8\xbb\xcc\xe4e\xc3\xada\xd8\x1cP\xfc\xc3\x11\xa2\xb0\x85c\xc9+\x08\xd7\x15W\x1b\xf0y\xa9%\x16\xaf+N\xac|\x8f\xaf\xd2\x11\xa9]\xcd\xc3\x00B\x94e\x85d,\xd4\x92\xc4\xff\x0c\x893\xf5\xea\xff\"v+\xede\n\x03p\x89c\x94[n?\x0e\xe3\xb8hi>^0c\"\xa7q\xbf\xf2\xcf`\x18n\x8d1I\x9c\xfe\x9e\x87\\\xaa\xb3\xda\x14\x80\xf1\xb2o\x8c*f\xca9\x84\x99\x9d6\xf666\x8e\x9f\x95s9O\xf8v\xf8\xf2\xfb7\x0fG\xbc?\xed\xf0=\xfc\x1a\x00PK\x07\x08\xdem1\xf3M\x01\x00\x00D\x02\x00\x00PK\x03\x04\x14\x00\x08\x00\x08\x00V	Q]\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00 \x00	\x00pandoc/pandoc-defaults.html.yamlUT\x05\x00\x01\x14\xcb\xd2j4\x8e\xb1J\x03A\x14E\xfb\xf9\x8aK\xd2(q\xd3\xd9l/X(\x08\xda\x89\x84\xb73wv\x87\xcc\xbe	\xf3^\xd4\xcf\x97`\xd2]\xce\x81\xcb\xc9\xbd\xad#V\xe9\xc7\xd4~t\x97\xa9\x91\xe9\x90\xca\xb7\xed\xa6.\xf1Hg:\xd8I\xd4\x82\xb7\x11\x8b\xaf\xf51\x04s\xd1$\xb5)Gx?3\x84-\x9e\xd6\x89\xc9 \xb5\xa2\xac2\xd3p\xc7\xfd\xbc\x87\x95Y\xc5\xcf\x9dv\x8f\xa2\xde\xe0\x0b\xf1\xfc\xf1\xfa\x82\\*Q\xdcX3\xac\xc1\x17q\x14G\x14\x0d[L\x84-\xd2\x99 \x06\x81\x15\x9d+\x91Ke0\xd6<\xc4\xa6.E\x99n\x05\xc6\xe8\xa5\xe9pi\xbf2=\xaf\x13\xfbp57\x1ar\xa9\xcenc\x00\x06\x9cDS\x8bC\xec\xcd\xac3\x87\xb0\xd2%\x89\xcbE\x1b\xe3[g.\xbf#>7\xef\xff7\x9b\x07\xdc\xa6m\xbe\xc2\xdf\x00PK\x07\x08P\x14/@\xdc\x00\x00\x00A\x01\x00\x00PK\x03\x04\x14\x00\x08\x00\x08\x00V	Q]\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x1f\x00	\x00pandoc/pandoc-defaults.odt.yamlUT\x05\x00\x01\x14\xcb\xd2jl\x91\xc1n\xdc:\x0cE\xf7\xfa\x8a\x8bx\xf1^\x91Q>\xc0\xdb\xee\x8b\x02\xcd\xa6(\x8a\x84\x91\xa8\x8c\x10Y4H\xaa\x89\xfb\xf5\x85\xa7\x93\x19\xb4\xe8\xce\xd7<8\xbc\x84\x8a\xca2c!}\xc9\xf2\xdao\x0b\xf7\xc4\xf9!\xd7\x1fv\xfb\xa4\x94^\xd89?\xd8J\xdd\x82\xcb\x0c\xc9\x1e\x829\xf5LM:\xcfp\x1d\x1c\xc2\x84Ocyb\x85q\xf2*\xddB?\xe5\xf8\x9e\xaf\xe0\xbd \x0dsY\xeaO\x86\x1f\x19\xe6[c\xc30\xce\xa8\xfd\xf4\xeb\x99;+9gdIc\xe1\xee\x07\xac\x8d\x12\x83Pjc$j\x8ds\x98\xf0\xa8\\X\xf7\xd2w\x92\xfdq\x17l2\x14\xab\xca	,\xd22+\xfe\xbf?\xf2R\x0d\x1f\xa5\xbbRr\xbc\xd6\xd6@\xc3e!\xaf\xbbm\x0b\xd3^\x01\xd5?\x1c \n[9\xd5\xb2\x81p\xd9p)\x03~[[M\xd5\xdb\x86#+\xdf\xe1\xab\x0c$\xea\x97\xe2a\x02!\xc9\xbaA\nV\xeaY\xd2\x7f\x86\xcc\x85F\xf3\x7f\x19\x87\xd5\xfe<\x87	8\xe3\x88r\xe5\xf6\xdb\x10\xe3\xaa\xb5{<[b&\xa7x:\xf2\x0f.L\xd7\x1c\xb3\xa4\xf9\xafq(\xb59\xab\xcd\x01\x88\xe7e1\xa9\x98)\x97\x10\x16v\xda\xcd\xfb\xd88}V.\xf5m\xc6\xb7\x9b/\xbf_\xf2\xe6\x80\xf7O\xbb\xf9\x1e~\x0d\x00PK\x07\x08q\xf9\x8bTL\x01\x00\x00?\x02\x00\x00PK\x03\x04\x14\x00\x08\x00\x08\x00V	Q]\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x1f\x00	\x00pandoc/pandoc-defaults.txt.yamlUT\x05\x00\x01\x14\xcb\xd2j<\x8fA\x8e\xe2@\x0cE\xf7>\x85'l@@\x0e\x90\xdd\xdc\x00\x89%B`\xaa~2%\x12\x17\xb2\x1d\x86\xbe}+\x82\xee\x9d\xf5\xfc\xfc$\xf7V\xa7\x8e'\xb1{\xae\xffu\xdbC\x13\xf2%\x97\xa7oo&\xe9\x8e@\xbe\xf8C\xd4)j\xc7\x8fQ\x8a\x12y\x88f\x19\xab\xa2\xe3\xb0\x19\xa4\xf3t\x83\xed\x1d)JU\xffPZ\xf1a9\xe0\xc0+8\x89j\x0dNUcae\x92\x01\xbec\xaf\xeceP\x89\xd9\xf0\x81,\x066h\x86!\xf3\xecE\x07Zq\xfcC1\x961`*Q\x9exg\xd7h\x87\x96\xaf\x7fN\xc72(2\xdf\xbe\xf8\xefX\x12\xce\xeb\xb6m7\xd7O\xc7Y\x9c\x9b_\x85Vo\xa9\xd9\xb4D}Y\x9a\xde\x11\xf3\x9e\x1f\xa2\xb9\xa6}\xb2\xean\xe8\x89&\x84d	Y\xd6\x8et0\xf4\xe5\xd5\xf1\xa99\xbe\x9fmv\xfc3zs\xa6\xef\x01\x00PK\x07\x08\xe3G\x01\x9b\xe4\x00\x00\x00Q\x01\x00\x00PK\x03\x04\x14\x00\x08\x00\x08\x00\x8d\x8a&W\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x1b\x00	\x00pandoc/pandoc-defaults.yamlUT\x05\x00\x01\xda\xb4\xf8d\\R\xc1n\xdb0\x0c\xbd\xeb+\x1e\xecc\xa7\x06\xb9\xfa6\x0c\xbb\x0e\xc3\xd2\x9d\x86\xa2\xa5%*\x16\"K\x86Hg\xc9\xdf\x0fv\x9c\x14\xeb\xc9&\xdf{\xf4\xe33C-c\x87\x91\xea\xc9\x97\xbf\xf9)pv\xec\xdf|<\xcbS_\xc9\x9dX\xd9\xbf\xc9DY\x8ci\xf1[\x181`\xd8c`\xf21\x1f\x052\x949y\xe4\xa2\xe8\x19y\x1e{\xae\xec!\xec4\x96,\xa6\x85\x0c1\xa8\xdd\x046\xf1\x99\x93\xd8\xfe\xda\xc1\xee\x8d\x11\xa5\xec)\x95\xcc\x1d\xb4\xcel\x84S\xb0\xaed\xa5\x98\xd9oM\xd3\xe2{\xa6>\xb1\x80/S\x8a.\xea\xe3\x0bX\xcc\xa2'a\x8f\x92Q9\x91\xc63\xdf\x1db*\x12oV6\x85]\x04\x1f\x83\x7f\xac\x96\x1f\xe3L^k{\xaf?\x88\xdff\xd12\"\x91\xf2\x05\xa2\xd7\xc5\x8d\x16\x90\xf7\xcbC\x07\x86\xf28-\xb0i\xc1\xe4\x06D\xe5\x111\xafX\x8a\xa2\xf7\xb0z\x06!\xc4\xc4\x98H\x07\x13\xb3K\xb3g\x1b\xf3\x9a\x12\xd7\x0e\x7f\x9a\xdb\x9b\xdd0yV\xbe4\xaf\x0fj\xcf\xa1T\xb6}\xf1\xd7\x85\xfc\x7f{\xe3\x9a\x16/\x95\xb2\x84RGZwA9s]\xddL\x94}q\xf8zx1!&\xe5*\x9d\x01Z\x1c\x981\xa8N\xd2\xedv\xc7\xa8\xc3\xdc?\xbb2\xeeR\xe4\xea\xe9\x14\xd3\xee&\xb4\xae\x16\x91\xca\xc1\x00\x16\x9f{fd%OJ\xb7\x99\xbf8p]\xeeJ\x96\x9c\xde[a\xd7\x85R\xde\xa1t\x14PeT\xce~\xbd\x1a\x124\x87[\xf0\xd87\x06\xcb_\xf9Y9\xc4\xcb\xb2\xe5\x864_\x1e$i^\xcd\xbf\x01\x00PK\x07\x08\x1c\x98L\xa8\x92\x01\x00\x00\xc2\x02\x00\x00PK\x03\x04\x14\x00\x08\x00\x08\x00x	Q]\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x17\x00	\x00pandoc/reproducible.texUT\x05\x00\x01U\xcb\xd2jl\x8e\xb1n\xc30\x0cDw}\xc5-\x06\xda\xa1\x01<t\xecVt+\xd0\x0f\xf0B[TDT\x96\x04\x8a\x89k\x04\xf9\xf7\xc2\x85\x93!\xe8H\xde\xe1\xdd\xeb:|\xd277Le\xae\x92\xd8\xe3\xeb\xfd\xa3A\xb9j\xf1\xa7I\xc6\xc4\x18W\x94Y\xcc$\x1f!9\x14\x9d\xc9\xa4dX$\xc3\x99T\xb8ad[\x98\xb3\xeb\xba\x9d\xf4Wix\xe2\xc3\xf1\x00\x8b\x0c\xe3\xb9\x16%]Q\xc9b\x83d,Q\xa6\xb8\x85+\x16V\xbe;<\xbbA\x82\xe7 \x99\xfdP}h\xa7Z\x95[\xab\xc6?\x9b\x80\x03\xfe{\xbf\xbd\xf4{bJ\x92X\xc5_\xaen\x08\xf2\x80;\x93\n\x8d\x89\xf7\xf2\xed\xc4m\xa6\xd4\xcd\x9d\xd26\x85\xd7\xbe\x7f\xec\xdd\xe9\xb8\\\xdd\x10\xc4\xfd\x0e\x00PK\x07\x08V\xd5\x11\xe8\xc5\x00\x00\x00E\x01\x00\x00PK\x03\x04\x14\x00\x08\x00\x08\x00=\nQ]\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x1d\x00	\x00templates/contract.dhall.tmplUT\x05\x00\x01\xc6\xcc\xd2j\xa4TKo\xe2L\x10\xbc\xf3+Z\x11G\xb0\x81\xf0\x08H\x1c>}\xbb\xd1\x1e\xa2(\xdadOQ\x0e\xcd\xb8\x8dG;\x0f\xcb\xd3$B\xa3\xf9\xef+\x1b\x9b\x87\x81d\xa5\xe5\xe4\x99\xa9\xee\xa9\xaa\xa9\xc6\xf7;\x00\x00\xdf,\x18\xcb\xa0m\"\xd3-p&\x1d\xa4R\x11\xf4A2H\x07\xb8a\xab\x91\xa5@\xa5\xb6\xb0&C\x052%\x80&\x01\x8d\x06\xd7\x94\xc0j[\xb5z\xc9HK\x07\xff[\xc3\x05\n\x8e\xe0?\xb3\x05\x91\xa1Y\x93\x03\x8d[XQ\xab\x9d}\xa7\xe2\xa3\x90\xccd\xa2N?t:\x8a\xb8n\xd3t\x81%d\xcc\xb9[\xc4q\x81\x1f\xd1Zr\xb6Ym\x1c\x15\xc2\x1a&\xc3\x91\xb0:\x96&\xb5\x85F\xe5\xb6\x8eI\xbb\x98+&}Q\xf7\x885:\xa6\"\x16\xd6\xa4r\x1d\xbfO\xe2\x1c\xc5o\\S\x94d\xa8T\xe5\x83\xcbp4\x99.\x04\xa6b2\x1c\x0d\xa6w\xb3a:\x9e\xd1d*\xd2t.V\xf3\xc1`\x90\x8cE2\xbb\x9d\xde\xce\x86\xe3\xf9\xddt\xbc\x9a\x8d\x92\xf9|@\x83d8J\xcb\xba\xd9\xdd\x8av\x12\x9a\x8ba\xd1R\x13\x1ddU\xb7z\xc8\xb1@\xed\xeae\xf9\xf3\xa0\xac@\x96\xd6\xc0\x12n\xbc\x8f\x9e*\xc4\xbdT\x14=\xd4'!\xdc\xec\xf1=\xc8\xd0e\xe7\xd8\x1f\xe8\xb2c\\\xa8\xbez\xb0\xc9\x1d\x17\x84\x1a\x96\xe0\xbdL!\xfaUo\x84\xf0l5]\xe7\xd1\xe0\xbef\xb1G\x9eq\xf0\x9e\x94\xa3\x10\x1e\xad\xa1\xb65\xa5\xc2\x9f\x94zO&	\x0d['2\xd2\xb8\xe7\xfa\\-\xbfb\xbaC}\xcd\xb3\xc6\xfd;K%\x0d\xe1\x9a`\xb9c\xf9\xb0[\x86\xe0}Q\xa6\x1f\xba\xb2\x07]\x05\x8b\xe5\xd1\xd9\xc1\xe8\xb2\xa6+C\xe85\xf7\xbe\xd6\x1e\x9c)\xeb\xaa\x8b\xa2Neu\xd5\x99\xa2\x12\xc0R\x93c\xd4\xf9\x1e\xf5\xd2\xec\x9cBC}\xfb\xbe\xfa\xad\xe1\x05\xafo\xb0\x80\x07\xe9\xdaC\xda\xa8\xfan\xb8\xd8\x1e\x97\xf7\x80I\xe7\n\x99N\x12^Mk9\xda\xad6/5\xf8\xbe:\x8f\xbc\xdf\xefD\xbb\xad#V\xbd\xdd\xff\xd4\xa1\xed\xc5(\x1c\xea\xaf\xcd\xcfY*NK\xce\xadlF\xa9\xfc\xf5\xca\xf9e\x89\xca5o\xbf\xaf~\xaa\x0fZ)\xc8\xab\x14\\@\x9d\xea\xf8,\x13\x065\xd5\x8f\x98G\x8f\xa8\xe9\xf4\xfd\xae\x9as\xd9\xa2n\xfe\xb97\x17\x02\x96_\xb7\xa6mP\xbdnG\xea/cU{\xd8\xae\xde}\x85NG\x1a\x10\xd6p\x81\x82\xff\x0c\x00PK\x07\x08Hkm\xcbe\x02\x00\x00\xcf\x06\x00\x00PK\x01\x02\x14\x03\x14\x00\x08\x00\x08\x00\x8d\x8a&W\xa2$\xe3\xafq\x00\x00\x00\x98\x00\x00\x00\x1a\x00	\x00\x00\x00\x00\x00\x00\x00\x00\x00\xb4\x81\x00\x00\x00\x00pandoc/header-includes.texUT\x05\x00\x01\xda\xb4\xf8dPK\x01\x02\x14\x03\x14\x00\x08\x00\x08\x00\x8d\x8a&W\xed\xe6\x9aPn\x00\x00\x00\x87\x00\x00\x00\x19\x00	\x00\x00\x00\x00\x00\x00\x00\x00\x00\xb4\x81\xc2\x00\x00\x00pandoc/include-before.texUT\x05\x00\x01\xda\xb4\xf8dPK\x01\x02\x14\x03\x14\x00\x08\x00\x08\x00V	Q]\xdem1\xf3M\x01\x00\x00D\x02\x00\x00 \x00	\x00\x00\x00\x00\x00\x00\x00\x00\x00\xa4\x81\x80\x01\x00\x00pandoc/pandoc-defaults.docx.yamlUT\x05\x00\x01\x14\xcb\xd2jPK\x01\x02\x14\x03\x14\x00\x08\x00\x08\x00V	Q]P\x14/@\xdc\x00\x00\x00A\x01\x00\x00 \x00	\x00\x00\x00\x00\x00\x00\x00\x00\x00\xa4\x81$\x03\x00\x00pandoc/pandoc-defaults.html.yamlUT\x05\x00\x01\x14\xcb\xd2jPK\x01\x02\x14\x03\x14\x00\x08\x00\x08\x00V	Q]q\xf9\x8bTL\x01\x00\x00?\x02\x00\x00\x1f\x00	\x00\x00\x00\x00\x00\x00\x00\x00\x00\xa4\x81W\x04\x00\x00pandoc/pandoc-defaults.odt.yamlUT\x05\x00\x01\x14\xcb\xd2jPK\x01\x02\x14\x03\x14\x00\x08\x00\x08\x00V	Q]\xe3G\x01\x9b\xe4\x00\x00\x00Q\x01\x00\x00\x1f\x00	\x00\x00\x00\x00\x00\x00\x00\x00\x00\xa4\x81\xf9\x05\x00\x00pandoc/pandoc-defaults.txt.yamlUT\x05\x00\x01\x14\xcb\xd2jPK\x01\x02\x14\x03\x14\x00\x08\x00\x08\x00\x8d\x8a&W\x1c\x98L\xa8\x92\x01\x00\x00\xc2\x02\x00\x00\x1b\x00	\x00\x00\x00\x00\x00\x00\x00\x00\x00\xb4\x813\x07\x00\x00pandoc/pandoc-defaults.yamlUT\x05\x00\x01\xda\xb4\xf8dPK\x01\x02\x14\x03\x14\x00\x08\x00\x08\x00x	Q]V\xd5\x11\xe8\xc5\x00\x00\x00E\x01\x00\x00\x17\x00	\x00\x00\x00\x00\x00\x00\x00\x00\x00\xa4\x81\x17	\x00\x00pandoc/reproducible.texUT\x05\x00\x01U\xcb\xd2jPK\x01\x02\x14\x03\x14\x00\x08\x00\x08\x00=\nQ]Hkm\xcbe\x02\x00\x00\xcf\x06\x00\x00\x1d\x00	\x00\x00\x00\x00\x00\x00\x00\x00\x00\xa4\x81*\n\x00\x00templates/contract.dhall.tmplUT\x05\x00\x01\xc6\xcc\xd2jPK\x05\x06\x00\x00\x00\x00	\x00	\x00\xef\x02\x00\x00\xe3\x0c\x00\x00\x00\x00"
 		fs.Register(data)
 	}
 	
\ No newline at end of file
@@ -1,26 +1,242 @@
 package themis_contract
 
-import "fmt"
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/alexkappa/mustache"
+	"github.com/aymerick/raymond"
+	"github.com/philandstuff/dhall-golang/v6/core"
+	"github.com/philandstuff/dhall-golang/v6/term"
+	"github.com/rs/zerolog/log"
+)
 
 // TemplateFormat is a string-based enumeration type.
 type TemplateFormat string
 
-// For this version of the prototype, we currently only support Mustache
-// templating.
-// TODO: Consider whether Handlebars support is actually required.
+// We currently support Mustache templating, Dhall-based templates (where the
+// template is a Dhall function that takes the contract's parameters and
+// produces the contract text), as well as Go and Handlebars templates (which,
+// unlike Mustache, allow for some logic and provide a library of helper
+// functions - see templateHelpers).
 // TODO: Consider Jinja2-like support (e.g. Pongo2: https://github.com/flosch/pongo2).
 const (
-	Mustache TemplateFormat = "Mustache"
+	Mustache   TemplateFormat = "Mustache"
+	Dhall      TemplateFormat = "Dhall"
+	GoTemplate TemplateFormat = "GoTemplate"
+	Handlebars TemplateFormat = "Handlebars"
 )
 
 // Template refers to the contract text template to use when rendering a
-// contract.
-// TODO: Use a list of files for the template as opposed to a single one to allow for complex templates.
+// contract. A template consists of a main file, and optionally a number of
+// named partials (e.g. reusable clauses), which can be included from the main
+// file or from other partials.
 type Template struct {
-	Format TemplateFormat `json:"format" yaml:"format" toml:"format"`
-	File   *FileRef       `json:"file" yaml:"file" toml:"file"`
+	Format   TemplateFormat `json:"format" yaml:"format" toml:"format"`
+	File     *FileRef       `json:"file" yaml:"file" toml:"file"`
+	Partials []*Partial     `json:"partials,omitempty" yaml:"partials,omitempty" toml:"partials,omitempty"`
+}
+
+// Partial is a named fragment of a template. Partials are included by name
+// using `{{> name}}` in Mustache and Handlebars templates, and using
+// `{{template "name" .}}` in Go templates.
+type Partial struct {
+	Name string   `json:"name" yaml:"name" toml:"name"`
+	File *FileRef `json:"file" yaml:"file" toml:"file"`
 }
 
 func (t *Template) String() string {
-	return fmt.Sprintf("Template{Format: \"%s\", File: %v}", t.Format, t.File)
+	return fmt.Sprintf("Template{Format: \"%s\", File: %v, Partials: %v}", t.Format, t.File, t.Partials)
+}
+
+func (p *Partial) String() string {
+	return fmt.Sprintf("Partial{Name: \"%s\", File: %v}", p.Name, p.File)
+}
+
+// partialFiles returns a mapping of partial names to the local paths of their
+// files.
+func (t *Template) partialFiles() map[string]string {
+	partials := make(map[string]string)
+	for _, p := range t.Partials {
+		partials[p.Name] = p.File.localPath
+	}
+	return partials
+}
+
+// setPartial sets the file for the partial with the given name, adding the
+// partial if the template doesn't have it yet.
+func (t *Template) setPartial(name string, file *FileRef) {
+	for _, p := range t.Partials {
+		if p.Name == name {
+			p.File = file
+			return
+		}
+	}
+	t.Partials = append(t.Partials, &Partial{Name: name, File: file})
+}
+
+// removePartial removes the partial with the given name from the template, if
+// present.
+func (t *Template) removePartial(name string) {
+	partials := make([]*Partial, 0, len(t.Partials))
+	for _, p := range t.Partials {
+		if p.Name != name {
+			partials = append(partials, p)
+		}
+	}
+	t.Partials = partials
+}
+
+// renderedFilename returns the file name to use when writing out the rendered
+// template. Dhall templates produce Markdown, so they need a different
+// extension to the template file itself.
+func (t *Template) renderedFilename() string {
+	filename := t.File.Filename()
+	if t.Format == Dhall {
+		return strings.TrimSuffix(filename, path.Ext(filename)) + ".md"
+	}
+	return filename
+}
+
+// Mustache only allows for letters, digits and underscores in partial names.
+var mustachePartialName = regexp.MustCompile(`^[\p{L}\p{N}_]+$`)
+
+// mustachePartialTag matches partial tags (e.g. `{{> clause-name}}`), with
+// partial names that Mustache's lexer may not be able to cope with.
+var mustachePartialTag = regexp.MustCompile(`\{\{>\s*([^\s{}]+)\s*\}\}`)
+
+// mustachePartialAlias returns a name for the given partial that Mustache can
+// lex. Names that only consist of letters, digits and underscores are used
+// as-is, whereas other names (e.g. `clause-name`) are hex-encoded, which
+// guarantees that aliases cannot clash with one another or with other names.
+func mustachePartialAlias(name string) string {
+	if mustachePartialName.MatchString(name) {
+		return name
+	}
+	return "partial_" + hex.EncodeToString([]byte(name))
+}
+
+// aliasMustachePartials replaces the names in all partial tags in the given
+// template content that refer to one of the given partials with their aliases.
+func aliasMustachePartials(content []byte, partials map[string]string) []byte {
+	return mustachePartialTag.ReplaceAllFunc(content, func(tag []byte) []byte {
+		name := string(mustachePartialTag.FindSubmatch(tag)[1])
+		if _, exists := partials[name]; !exists {
+			return tag
+		}
+		return []byte("{{> " + mustachePartialAlias(name) + "}}")
+	})
+}
+
+func renderMustacheTemplate(templateFile string, partials map[string]string, params map[string]interface{}, w io.Writer) error {
+	var opts []mustache.Option
+	for name, partialFile := range partials {
+		if strings.ContainsAny(name, " \t\r\n{}") {
+			return fmt.Errorf("invalid name for Mustache partial \"%s\": whitespace and braces are not allowed", name)
+		}
+		content, err := ioutil.ReadFile(partialFile)
+		if err != nil {
+			return err
+		}
+		partial := mustache.New(mustache.Name(mustachePartialAlias(name)))
+		if err := partial.ParseBytes(aliasMustachePartials(content, partials)); err != nil {
+			return fmt.Errorf("failed to parse partial \"%s\": %s", name, err)
+		}
+		opts = append(opts, mustache.Partial(partial))
+	}
+	content, err := ioutil.ReadFile(templateFile)
+	if err != nil {
+		return err
+	}
+	tpl := mustache.New(opts...)
+	if err := tpl.ParseBytes(aliasMustachePartials(content, partials)); err != nil {
+		return fmt.Errorf("failed to parse template: %s", err)
+	}
+	return tpl.Render(w, params)
+}
+
+// renderGoTemplate renders the given Go text/template file. Referring to a
+// parameter that does not exist is an error, except for signatory fields that
+// are only populated once the signatory has signed (e.g. `signed_date`), which
+// are set to their zero values for signatories who haven't.
+func renderGoTemplate(templateFile string, partials map[string]string, params map[string]interface{}, w io.Writer) error {
+	params, err := withAllSignatoryFields(params)
+	if err != nil {
+		return err
+	}
+	content, err := ioutil.ReadFile(templateFile)
+	if err != nil {
+		return err
+	}
+	tpl, err := template.New(path.Base(templateFile)).
+		Funcs(template.FuncMap(templateHelpers())).
+		Option("missingkey=error").
+		Parse(string(content))
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %s", err)
+	}
+	for name, partialFile := range partials {
+		content, err := ioutil.ReadFile(partialFile)
+		if err != nil {
+			return err
+		}
+		if _, err := tpl.New(name).Parse(string(content)); err != nil {
+			return fmt.Errorf("failed to parse partial \"%s\": %s", name, err)
+		}
+	}
+	return tpl.Execute(w, params)
+}
+
+func renderHandlebarsTemplate(templateFile string, partials map[string]string, params map[string]interface{}, w io.Writer) error {
+	content, err := ioutil.ReadFile(templateFile)
+	if err != nil {
+		return err
+	}
+	tpl, err := raymond.Parse(string(content))
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %s", err)
+	}
+	for name, fn := range templateHelpers() {
+		tpl.RegisterHelper(name, handlebarsHelper(fn))
+	}
+	for name, partialFile := range partials {
+		content, err := ioutil.ReadFile(partialFile)
+		if err != nil {
+			return err
+		}
+		tpl.RegisterPartial(name, string(content))
+	}
+	result, err := tpl.Exec(params)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, result)
+	return err
+}
+
+// renderDhallTemplate applies the Dhall function in the given template file to
+// the contract's parameters. The function must produce `Text`. The parameters
+// are converted to the type expected by the template function first, so the
+// template only receives the fields its parameter type declares.
+func renderDhallTemplate(templateFile string, params map[string]interface{}, w io.Writer, cache Cache) error {
+	log.Debug().Msgf("Evaluating Dhall template: %s", templateFile)
+	typ, result, err := applyDhallFunction(templateFile, params, cache)
+	if err != nil {
+		return fmt.Errorf("failed to apply Dhall template to contract parameters: %s", err)
+	}
+	if typ != core.Text {
+		return fmt.Errorf("expected Dhall template %s to produce Text, but it produces %v", templateFile, core.Quote(typ))
+	}
+	text, ok := core.Quote(result).(term.TextLit)
+	if !ok || len(text.Chunks) > 0 {
+		return fmt.Errorf("Dhall template %s did not produce plain Text", templateFile)
+	}
+	_, err = io.WriteString(w, text.Suffix)
+	return err
 }
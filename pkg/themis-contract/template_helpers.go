@@ -0,0 +1,386 @@
+package themis_contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The date layouts we attempt to use, in order, when interpreting a date
+// parameter that was supplied as a string.
+var templateDateLayouts = []string{
+	"2006-01-02",
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2 January 2006",
+	"January 2, 2006",
+}
+
+// templateHelpers returns the standard library of helper functions available
+// to Go and Handlebars templates. Each helper either returns a single value,
+// or a value and an error.
+func templateHelpers() map[string]interface{} {
+	return map[string]interface{}{
+		"formatDate":    formatDateHelper,
+		"money":         moneyHelper,
+		"numberToWords": numberToWordsHelper,
+		"ordinal":       ordinalHelper,
+		"joinList":      joinListHelper,
+		"plural":        pluralHelper,
+		"add":           addHelper,
+		"sub":           subHelper,
+		"mul":           mulHelper,
+		"div":           divHelper,
+		"eq":            eqHelper,
+		"ne":            neHelper,
+		"lt":            ltHelper,
+		"le":            leHelper,
+		"gt":            gtHelper,
+		"ge":            geHelper,
+	}
+}
+
+// formatDateHelper formats the given date (either a time.Time, or a string in
+// one of the layouts in templateDateLayouts) using the given Go time layout,
+// e.g. "2 January 2006".
+func formatDateHelper(date interface{}, layout string) (string, error) {
+	var t time.Time
+	switch d := date.(type) {
+	case time.Time:
+		t = d
+	case string:
+		var err error
+		for _, l := range templateDateLayouts {
+			if t, err = time.Parse(l, d); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return "", fmt.Errorf("formatDate: unrecognized date format: %s", d)
+		}
+	default:
+		return "", fmt.Errorf("formatDate: expected a date, but got %v", date)
+	}
+	return t.Format(layout), nil
+}
+
+// moneyHelper formats the given amount to 2 decimal places with thousands
+// separators, prefixed by the given currency. Three-letter currency codes
+// (e.g. "USD") are separated from the amount by a space, whereas symbols
+// (e.g. "$") are not.
+func moneyHelper(amount interface{}, currency string) (string, error) {
+	f, err := toFloat(amount)
+	if err != nil {
+		return "", fmt.Errorf("money: %s", err)
+	}
+	sign := ""
+	if f < 0 {
+		sign = "-"
+		f = -f
+	}
+	s := strconv.FormatFloat(f, 'f', 2, 64)
+	whole, cents := s[:len(s)-3], s[len(s)-3:]
+	if len(currency) == 3 && strings.ToUpper(currency) == currency && isLetters(currency) {
+		currency += " "
+	}
+	return sign + currency + groupThousands(whole) + cents, nil
+}
+
+var (
+	smallNumberWords = []string{
+		"zero", "one", "two", "three", "four", "five", "six", "seven", "eight",
+		"nine", "ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen",
+		"sixteen", "seventeen", "eighteen", "nineteen",
+	}
+	tensWords = []string{
+		"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy",
+		"eighty", "ninety",
+	}
+	scaleWords = []string{"", "thousand", "million", "billion", "trillion"}
+)
+
+// numberToWordsHelper spells out the given whole number in English, e.g.
+// 1234 becomes "one thousand two hundred thirty-four".
+func numberToWordsHelper(n interface{}) (string, error) {
+	i, err := toInt(n)
+	if err != nil {
+		return "", fmt.Errorf("numberToWords: %s", err)
+	}
+	if i == 0 {
+		return smallNumberWords[0], nil
+	}
+	prefix := ""
+	if i < 0 {
+		prefix = "minus "
+		i = -i
+	}
+	var groups []string
+	for scale := 0; i > 0; scale++ {
+		if scale >= len(scaleWords) {
+			return "", fmt.Errorf("numberToWords: number too large: %v", n)
+		}
+		if g := i % 1000; g > 0 {
+			words := hundredsToWords(g)
+			if len(scaleWords[scale]) > 0 {
+				words += " " + scaleWords[scale]
+			}
+			groups = append([]string{words}, groups...)
+		}
+		i /= 1000
+	}
+	return prefix + strings.Join(groups, " "), nil
+}
+
+func hundredsToWords(n int64) string {
+	var parts []string
+	if n >= 100 {
+		parts = append(parts, smallNumberWords[n/100]+" hundred")
+		n %= 100
+	}
+	switch {
+	case n >= 20 && n%10 > 0:
+		parts = append(parts, tensWords[n/10]+"-"+smallNumberWords[n%10])
+	case n >= 20:
+		parts = append(parts, tensWords[n/10])
+	case n > 0:
+		parts = append(parts, smallNumberWords[n])
+	}
+	return strings.Join(parts, " ")
+}
+
+// ordinalHelper produces the ordinal form of the given whole number, e.g. 1
+// becomes "1st" and 12 becomes "12th".
+func ordinalHelper(n interface{}) (string, error) {
+	i, err := toInt(n)
+	if err != nil {
+		return "", fmt.Errorf("ordinal: %s", err)
+	}
+	suffix := "th"
+	lastTwo := i % 100
+	if lastTwo < 0 {
+		lastTwo = -lastTwo
+	}
+	if lastTwo < 11 || lastTwo > 13 {
+		switch lastTwo % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return fmt.Sprintf("%d%s", i, suffix), nil
+}
+
+// joinListHelper joins the given list of items in the way one would in an
+// English sentence, e.g. "A, B and C".
+func joinListHelper(list interface{}) (string, error) {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return "", fmt.Errorf("joinList: expected a list, but got %v", list)
+	}
+	items := make([]string, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		items[i] = fmt.Sprintf("%v", v.Index(i).Interface())
+	}
+	switch len(items) {
+	case 0:
+		return "", nil
+	case 1:
+		return items[0], nil
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1], nil
+}
+
+// pluralHelper returns the singular form if the given count is exactly 1, and
+// the plural form otherwise.
+func pluralHelper(count interface{}, singular, plural string) (string, error) {
+	f, err := toFloat(count)
+	if err != nil {
+		return "", fmt.Errorf("plural: %s", err)
+	}
+	if f == 1 {
+		return singular, nil
+	}
+	return plural, nil
+}
+
+func addHelper(a, b interface{}) (float64, error) {
+	return arithmetic("add", a, b, func(x, y float64) (float64, error) { return x + y, nil })
+}
+
+func subHelper(a, b interface{}) (float64, error) {
+	return arithmetic("sub", a, b, func(x, y float64) (float64, error) { return x - y, nil })
+}
+
+func mulHelper(a, b interface{}) (float64, error) {
+	return arithmetic("mul", a, b, func(x, y float64) (float64, error) { return x * y, nil })
+}
+
+func divHelper(a, b interface{}) (float64, error) {
+	return arithmetic("div", a, b, func(x, y float64) (float64, error) {
+		if y == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return x / y, nil
+	})
+}
+
+func arithmetic(name string, a, b interface{}, op func(x, y float64) (float64, error)) (float64, error) {
+	x, err := toFloat(a)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %s", name, err)
+	}
+	y, err := toFloat(b)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %s", name, err)
+	}
+	result, err := op(x, y)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %s", name, err)
+	}
+	return result, nil
+}
+
+// eqHelper checks whether the given values are equal. Numbers are compared by
+// value, regardless of their types (e.g. 150 equals 150.0), whereas all other
+// values must be identical.
+func eqHelper(a, b interface{}) bool {
+	if isNumber(a) && isNumber(b) {
+		x, _ := toFloat(a)
+		y, _ := toFloat(b)
+		return x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func neHelper(a, b interface{}) bool {
+	return !eqHelper(a, b)
+}
+
+func ltHelper(a, b interface{}) (bool, error) {
+	return comparison("lt", a, b, func(x, y float64) bool { return x < y })
+}
+
+func leHelper(a, b interface{}) (bool, error) {
+	return comparison("le", a, b, func(x, y float64) bool { return x <= y })
+}
+
+func gtHelper(a, b interface{}) (bool, error) {
+	return comparison("gt", a, b, func(x, y float64) bool { return x > y })
+}
+
+func geHelper(a, b interface{}) (bool, error) {
+	return comparison("ge", a, b, func(x, y float64) bool { return x >= y })
+}
+
+// comparison compares the given numbers, which may be of different numeric
+// types (e.g. parameters decoded as float64, and integer literals in
+// templates).
+func comparison(name string, a, b interface{}, op func(x, y float64) bool) (bool, error) {
+	x, err := toFloat(a)
+	if err != nil {
+		return false, fmt.Errorf("%s: %s", name, err)
+	}
+	y, err := toFloat(b)
+	if err != nil {
+		return false, fmt.Errorf("%s: %s", name, err)
+	}
+	return op(x, y), nil
+}
+
+// isNumber checks whether the given value is of a numeric type (as opposed to
+// e.g. a string that happens to contain a number).
+func isNumber(v interface{}) bool {
+	if _, ok := v.(string); ok {
+		return false
+	}
+	_, err := toFloat(v)
+	return err == nil
+}
+
+// toFloat attempts to interpret the given parameter value as a number.
+// Parameters decoded from JSON, YAML and TOML files, as well as literals in
+// templates, all produce different numeric types.
+func toFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case float32:
+		return float64(n), nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case int32:
+		return float64(n), nil
+	case uint64:
+		return float64(n), nil
+	case json.Number:
+		return n.Float64()
+	case string:
+		return strconv.ParseFloat(strings.ReplaceAll(n, ",", ""), 64)
+	}
+	return 0, fmt.Errorf("expected a number, but got %v", v)
+}
+
+// toInt attempts to interpret the given parameter value as a whole number.
+func toInt(v interface{}) (int64, error) {
+	f, err := toFloat(v)
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) {
+		return 0, fmt.Errorf("expected a whole number, but got %v", v)
+	}
+	return int64(f), nil
+}
+
+func groupThousands(digits string) string {
+	var sb strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(d)
+	}
+	return sb.String()
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+// handlebarsHelper adapts one of our template helpers for use with Handlebars
+// templates, which require helpers to return exactly one value. Errors are
+// raised by way of a panic, which the Handlebars engine converts back into an
+// error.
+func handlebarsHelper(fn interface{}) interface{} {
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+	if ft.NumOut() == 1 {
+		return fn
+	}
+	in := make([]reflect.Type, ft.NumIn())
+	for i := range in {
+		in[i] = ft.In(i)
+	}
+	wrappedType := reflect.FuncOf(in, []reflect.Type{ft.Out(0)}, false)
+	return reflect.MakeFunc(wrappedType, func(args []reflect.Value) []reflect.Value {
+		results := fv.Call(args)
+		if err, ok := results[1].Interface().(error); ok && err != nil {
+			panic(err)
+		}
+		return results[:1]
+	}).Interface()
+}
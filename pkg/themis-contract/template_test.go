@@ -0,0 +1,244 @@
+package themis_contract_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+)
+
+var templateTestParams = map[string]interface{}{
+	"client":       "Acme Corp",
+	"jurisdiction": "Switzerland",
+	"startDate":    "2020-07-01",
+	"hourlyRate":   1250.5,
+	"hours":        3,
+	"installment":  2,
+	"parties":      []interface{}{"Alice", "Bob", "Charlie"},
+}
+
+const templateTestExpected = `Acme Corp agrees to pay USD 1,250.50 (one thousand two hundred fifty) per hour from 1 July 2020.
+Total: $3,751.50 for 3 hours, payable in the 2nd installment.
+Parties: Alice, Bob and Charlie
+This agreement is governed by the laws of Switzerland.
+`
+
+func TestMustacheTemplateRendering(t *testing.T) {
+	const expected = `Acme Corp agrees to pay 1250.5 per hour.
+This agreement is governed by the laws of Switzerland.
+`
+	testTemplateRendering(
+		t,
+		contract.RenderMustacheTemplate,
+		`{{client}} agrees to pay {{hourlyRate}} per hour.
+{{> governing_law}}`,
+		`This agreement is governed by the laws of {{jurisdiction}}.
+`,
+		expected,
+	)
+}
+
+func TestMustachePartialsWithHyphenatedNames(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	files := map[string]string{
+		"contract.md":       "{{client}} agrees to the following:\n{{> clause-name}}\n{{>partial_6c6177}}",
+		"clause-name.md":    "* Payment within 30 days.\n{{> governing-law }}",
+		"governing-law.md":  "* The laws of {{jurisdiction}} apply.",
+		"partial_6c6177.md": "* This clause's name looks like an alias.",
+	}
+	partials := make(map[string]string)
+	for filename, content := range files {
+		if err := ioutil.WriteFile(path.Join(tempDir, filename), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if filename != "contract.md" {
+			partials[strings.TrimSuffix(filename, ".md")] = path.Join(tempDir, filename)
+		}
+	}
+	var buf bytes.Buffer
+	if err := contract.RenderMustacheTemplate(path.Join(tempDir, "contract.md"), partials, templateTestParams, &buf); err != nil {
+		t.Fatal(err)
+	}
+	const expected = `Acme Corp agrees to the following:
+* Payment within 30 days.
+* The laws of Switzerland apply.
+* This clause's name looks like an alias.`
+	if buf.String() != expected {
+		t.Errorf("expected rendered template to be:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+}
+
+func TestGoTemplateRendering(t *testing.T) {
+	testTemplateRendering(
+		t,
+		contract.RenderGoTemplate,
+		`{{.client}} agrees to pay {{money .hourlyRate "USD"}} ({{numberToWords 1250}}) per hour from {{formatDate .startDate "2 January 2006"}}.
+Total: {{money (mul .hourlyRate .hours) "$"}} for {{.hours}} {{plural .hours "hour" "hours"}}, payable in the {{ordinal .installment}} installment.
+Parties: {{joinList .parties}}
+{{template "governing_law" .}}`,
+		`This agreement is governed by the laws of {{.jurisdiction}}.
+`,
+		templateTestExpected,
+	)
+}
+
+func TestGoTemplateRenderingWithUnsignedSignatory(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	templateFile := path.Join(tempDir, "contract.md")
+	tpl := `{{range .signatories}}{{.name}}: {{if .signed_date}}signed on {{.signed_date}}{{else}}not signed{{end}}
+{{end}}`
+	if err := ioutil.WriteFile(templateFile, []byte(tpl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	params := map[string]interface{}{
+		"signatories": []map[string]interface{}{
+			{"id": "alice", "name": "Alice", "email": "alice@example.com", "signature": "alice.png", "signed_date": "2020-07-01"},
+			{"id": "bob", "name": "Bob", "email": "bob@example.com"},
+		},
+	}
+	var buf bytes.Buffer
+	if err := contract.RenderGoTemplate(templateFile, nil, params, &buf); err != nil {
+		t.Fatal(err)
+	}
+	const expected = "Alice: signed on 2020-07-01\nBob: not signed\n"
+	if buf.String() != expected {
+		t.Errorf("expected rendered template to be:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+}
+
+func TestHandlebarsTemplateRendering(t *testing.T) {
+	testTemplateRendering(
+		t,
+		contract.RenderHandlebarsTemplate,
+		`{{client}} agrees to pay {{money hourlyRate "USD"}} ({{numberToWords 1250}}) per hour from {{formatDate startDate "2 January 2006"}}.
+Total: {{money (mul hourlyRate hours) "$"}} for {{hours}} {{plural hours "hour" "hours"}}, payable in the {{ordinal installment}} installment.
+Parties: {{joinList parties}}
+{{> governing_law}}`,
+		`This agreement is governed by the laws of {{jurisdiction}}.
+`,
+		templateTestExpected,
+	)
+}
+
+func TestConditionalClauses(t *testing.T) {
+	testCases := []struct {
+		render func(string, map[string]string, map[string]interface{}, io.Writer) error
+		tpl    string
+	}{
+		{
+			contract.RenderGoTemplate,
+			`{{if gt .hourlyRate 0}}Billed hourly.{{end}}{{if eq .hours 3}} Three hours.{{end}}{{if ne .client "Widgets Inc"}} For {{.client}}.{{end}}{{if le .installment 1}} Never shown.{{end}}`,
+		},
+		{
+			contract.RenderHandlebarsTemplate,
+			`{{#if (gt hourlyRate 0)}}Billed hourly.{{/if}}{{#if (eq hours 3)}} Three hours.{{/if}}{{#if (ne client "Widgets Inc")}} For {{client}}.{{/if}}{{#if (le installment 1)}} Never shown.{{/if}}`,
+		},
+	}
+	// parameters decoded from JSON are all floating point numbers, whereas
+	// literals in templates are integers
+	params, err := contract.NormalizeParams(map[string]interface{}{"client": "Acme Corp", "hourlyRate": 150, "hours": 3, "installment": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	templateFile := path.Join(tempDir, "contract.md")
+	const expected = "Billed hourly. Three hours. For Acme Corp."
+	for i, tc := range testCases {
+		if err := ioutil.WriteFile(templateFile, []byte(tc.tpl), 0644); err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := tc.render(templateFile, nil, params, &buf); err != nil {
+			t.Errorf("case %d: %s", i, err)
+			continue
+		}
+		if buf.String() != expected {
+			t.Errorf("case %d: expected rendered template to be \"%s\", but got \"%s\"", i, expected, buf.String())
+		}
+	}
+}
+
+func testTemplateRendering(
+	t *testing.T,
+	render func(string, map[string]string, map[string]interface{}, io.Writer) error,
+	tpl, partial, expected string,
+) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	templateFile := path.Join(tempDir, "contract.md")
+	if err := ioutil.WriteFile(templateFile, []byte(tpl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	partialFile := path.Join(tempDir, "governing_law.md")
+	if err := ioutil.WriteFile(partialFile, []byte(partial), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := render(templateFile, map[string]string{"governing_law": partialFile}, templateTestParams, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Errorf("expected rendered template to be:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+}
+
+func TestDhallTemplateRendering(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	templateFile := path.Join(tempDir, "template.dhall")
+	tpl := `\(params : { client : Text, currency : < USD | EUR >, draft : Bool, signatories : List { name : Text, signed_date : Optional Text } }) ->
+let show = \(s : { name : Text, signed_date : Optional Text }) -> s.name ++ merge { None = " (unsigned)", Some = \(d : Text) -> " (" ++ d ++ ")" } s.signed_date ++ "\n"
+let currency = merge { USD = "USD", EUR = "EUR" } params.currency
+in  (if params.draft then "DRAFT\n" else "") ++ params.client ++ " (" ++ currency ++ ")\n" ++ List/fold { name : Text, signed_date : Optional Text } params.signatories Text (\(s : { name : Text, signed_date : Optional Text }) -> \(acc : Text) -> show s ++ acc) ""
+`
+	if err := ioutil.WriteFile(templateFile, []byte(tpl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	params := map[string]interface{}{
+		"client":          "Acme Corp",
+		"currency":        "EUR",
+		"draft":           true,
+		"is_fully_signed": false,
+		"signatories": []interface{}{
+			map[string]interface{}{"id": "alice", "name": "Alice", "signed_date": "2020-07-01"},
+			map[string]interface{}{"id": "bob", "name": "Bob"},
+		},
+	}
+	var buf bytes.Buffer
+	if err := contract.RenderDhallTemplate(templateFile, params, &buf); err != nil {
+		t.Fatal(err)
+	}
+	const expected = "DRAFT\nAcme Corp (EUR)\nAlice (2020-07-01)\nBob (unsigned)\n"
+	if buf.String() != expected {
+		t.Errorf("expected rendered template to be:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+
+	delete(params, "client")
+	err = contract.RenderDhallTemplate(templateFile, params, &buf)
+	if err == nil || !strings.Contains(err.Error(), "missing required field \"client\"") {
+		t.Errorf("expected an error about the missing client field, but got: %v", err)
+	}
+}
@@ -0,0 +1,774 @@
+package themis_contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// The name of the file, alongside a contract, in which we keep track of a merge
+// of upstream changes that resulted in conflicts, until the conflicts have
+// been resolved.
+const upstreamMergeFilename = "upstream-merge.json"
+
+// UpstreamMerge summarizes the result of merging the changes made to a
+// contract's upstream into the contract.
+type UpstreamMerge struct {
+	Base      string   // The hash of the upstream contract from which the contract was previously derived/merged.
+	Upstream  *FileRef // The upstream contract whose changes were merged.
+	Conflicts []string // The files (relative to the contract) that contain merge conflicts.
+	Unmerged  []string // The template partials and/or schema whose upstream references conflict with the contract's, and which must be merged manually.
+
+	unmergedRefs []*unmergedUpstreamRef
+}
+
+// UpToDate returns true if the upstream contract hasn't changed since the
+// contract was derived from it (or since its changes were last merged).
+func (m *UpstreamMerge) UpToDate() bool {
+	return m.Base == m.Upstream.Hash
+}
+
+// HasConflicts returns true if the merge could not be completed automatically.
+func (m *UpstreamMerge) HasConflicts() bool {
+	return len(m.Conflicts) > 0 || len(m.Unmerged) > 0
+}
+
+// addUnmerged records that the given template partial (or the schema, if the
+// partial's name is empty) was changed both in the contract and in its
+// upstream, where it now refers to the given file (nil if removed).
+func (m *UpstreamMerge) addUnmerged(component, partial string, upstream *FileRef) {
+	m.Unmerged = append(m.Unmerged, component)
+	m.unmergedRefs = append(m.unmergedRefs, &unmergedUpstreamRef{
+		Component: component,
+		Partial:   partial,
+		Upstream:  upstream,
+	})
+}
+
+// pendingUpstreamMerge records a merge of upstream changes that resulted in
+// conflicts. The upstream's hash and the contract's lineage are only updated
+// once the conflicts have been resolved.
+type pendingUpstreamMerge struct {
+	Upstream  *FileRef               `json:"upstream"`           // The upstream contract whose changes were merged.
+	Lineage   []*LineageEntry        `json:"lineage"`            // The contract's lineage once the merge is complete.
+	Conflicts []string               `json:"conflicts"`          // The files (relative to the contract) that contained merge conflicts.
+	Unmerged  []*unmergedUpstreamRef `json:"unmerged,omitempty"` // The references that conflicted with the upstream's.
+}
+
+// unmergedUpstreamRef is a reference to a template partial or schema that was
+// changed both in a contract and in its upstream.
+type unmergedUpstreamRef struct {
+	Component string   `json:"component"`          // The component, as reported in UpstreamMerge.Unmerged.
+	Partial   string   `json:"partial,omitempty"`  // The name of the template partial (empty for the schema).
+	Upstream  *FileRef `json:"upstream,omitempty"` // The upstream's reference (nil if the upstream removed the component).
+}
+
+// UpstreamState describes how a contract relates to the current version of its
+// upstream.
+type UpstreamState string
+
+const (
+	// The upstream has not changed since the contract was derived from it (or
+	// since its changes were last merged).
+	UpstreamUpToDate UpstreamState = "up-to-date"
+	// The upstream has been revised since the contract was derived from it
+	// (or since its changes were last merged).
+	UpstreamBehind UpstreamState = "behind"
+	// The version of the upstream from which the contract was derived (or
+	// last merged) is not in the history of the upstream.
+	UpstreamDiverged UpstreamState = "diverged"
+	// The upstream has changed, but it is not in a Git repository, so we
+	// cannot tell how.
+	UpstreamChanged UpstreamState = "changed"
+)
+
+// UpstreamStatus reports whether a contract is behind its upstream and, if so,
+// by how much.
+type UpstreamStatus struct {
+	Location     string              `json:"location"`              // The location of the upstream contract.
+	RecordedHash string              `json:"recorded_hash"`         // The hash of the upstream as recorded in the contract.
+	CurrentHash  string              `json:"current_hash"`          // The hash of the current version of the upstream.
+	State        UpstreamState       `json:"state"`                 // How the contract relates to the current version of its upstream.
+	BaseCommit   string              `json:"base_commit,omitempty"` // The upstream commit corresponding to the recorded hash (if found).
+	Revisions    []*UpstreamRevision `json:"revisions,omitempty"`   // The commits that touched the upstream's files since the base commit, most recent first.
+	Tags         []string            `json:"tags,omitempty"`        // The tags added to the upstream since the base commit.
+	Uncommitted  bool                `json:"uncommitted,omitempty"` // Does the upstream have uncommitted changes?
+}
+
+// UpstreamRevision is a single commit that changed a contract's upstream.
+type UpstreamRevision struct {
+	Commit  string    `json:"commit"`
+	Date    time.Time `json:"date"`
+	Subject string    `json:"subject"`
+	Tags    []string  `json:"tags,omitempty"`
+}
+
+// UpstreamStatus determines whether this contract is behind its upstream, by
+// comparing the upstream's hash recorded in this contract with the hash of the
+// current version of the upstream. If the upstream is in a Git repository (e.g.
+// if it's fetched from a remote Git repository), the revisions and tags since
+// the recorded version are listed too.
+func (c *Contract) UpstreamStatus(ctx *Context) (*UpstreamStatus, error) {
+	if c.Upstream == nil {
+		return nil, fmt.Errorf("contract has no upstream")
+	}
+	log.Info().Msgf("Loading upstream contract: %s", c.Upstream.Location)
+	// this also fetches the latest version of the upstream if it's remote
+	upstream, err := Load(c.Upstream.Location, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load upstream contract: %s", err)
+	}
+	status := &UpstreamStatus{
+		Location:     c.Upstream.Location,
+		RecordedHash: c.Upstream.Hash,
+		CurrentHash:  upstream.path.Hash,
+		State:        UpstreamUpToDate,
+	}
+	if status.RecordedHash == status.CurrentHash {
+		return status, nil
+	}
+	repoRoot, err := upstream.repoRoot()
+	if err != nil {
+		return nil, err
+	}
+	if len(repoRoot) == 0 {
+		status.State = UpstreamChanged
+		return status, nil
+	}
+	if status.BaseCommit, err = upstream.findVersion(repoRoot, status.RecordedHash); err != nil {
+		return nil, err
+	}
+	if len(status.BaseCommit) == 0 {
+		status.State = UpstreamDiverged
+		return status, nil
+	}
+	status.State = UpstreamBehind
+	files := upstream.repoFiles(repoRoot)
+	entries, err := gitLog(repoRoot, status.BaseCommit+"..HEAD", files)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		status.Revisions = append(status.Revisions, &UpstreamRevision{
+			Commit:  entry.hash,
+			Date:    entry.time,
+			Subject: entry.subject,
+			Tags:    entry.tags,
+		})
+	}
+	if status.Tags, err = gitTagsSince(repoRoot, status.BaseCommit); err != nil {
+		return nil, err
+	}
+	if status.Uncommitted, err = gitHasChanges(repoRoot, files); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// repoFiles returns the paths, relative to the given repository root, of all
+// of the contract's files within that repository.
+func (c *Contract) repoFiles(repoRoot string) []string {
+	refs := []*FileRef{c.path, c.ParamsFile, c.Template.File, c.Schema}
+	for _, partial := range c.Template.Partials {
+		refs = append(refs, partial.File)
+	}
+	files := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if ref == nil {
+			continue
+		}
+		rel, err := repoRelPath(repoRoot, ref.localPath)
+		if err != nil {
+			log.Debug().Msgf("Ignoring file outside of repository: %s", err)
+			continue
+		}
+		files = append(files, rel)
+	}
+	return files
+}
+
+// UpstreamPull performs a three-way merge of the changes made to the upstream's
+// parameters, template, template partials and schema into this contract, using
+// the version of the upstream recorded in the contract as the merge base. The
+// merge base is looked up in the history of the Git repository containing the
+// upstream contract. Files in the contract's folder are merged line by line,
+// whereas references to other files (e.g. remote partials) are taken from the
+// upstream as long as the contract hasn't changed them too.
+//
+// Conflicts are marked in the merged files, in which case they need to be
+// resolved and UpstreamPull called again to complete the merge. Until then,
+// the contract keeps referring to the previous version of its upstream.
+// Template partials and schemas that cannot be merged line by line must be
+// made to refer to the same content as the upstream's, unless `keepOurs` is
+// set, in which case the contract's own references are kept. Once the merge is
+// complete, the contract is updated (and automatically committed, if
+// configured) as per Update.
+func (c *Contract) UpstreamPull(keepOurs bool, ctx *Context) (*UpstreamMerge, error) {
+	if c.Upstream == nil {
+		return nil, fmt.Errorf("contract has no upstream")
+	}
+	if fileRefType(c.path.Location, ctx) != LocalRef {
+		return nil, fmt.Errorf("only contracts located in the local filesystem can be merged with their upstream")
+	}
+	contractDir := path.Dir(c.path.localPath)
+	pending, err := loadPendingUpstreamMerge(contractDir)
+	if err != nil {
+		return nil, err
+	}
+	if pending != nil {
+		return c.completeUpstreamMerge(pending, keepOurs, ctx)
+	}
+	log.Info().Msgf("Loading upstream contract: %s", c.Upstream.Location)
+	upstream, err := Load(c.Upstream.Location, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load upstream contract: %s", err)
+	}
+	merge := &UpstreamMerge{
+		Base: c.Upstream.Hash,
+		Upstream: &FileRef{
+			Location: c.Upstream.Location,
+			Hash:     upstream.path.Hash,
+		},
+		Conflicts: make([]string, 0),
+		Unmerged:  make([]string, 0),
+	}
+	if merge.UpToDate() {
+		log.Info().Msgf("Upstream contract has not changed (hash %s)", merge.Base)
+		return merge, nil
+	}
+
+	repoRoot, err := upstream.repoRoot()
+	if err != nil {
+		return nil, err
+	}
+	if len(repoRoot) == 0 {
+		return nil, fmt.Errorf("upstream contract is not in a Git repository, so its version with hash %s cannot be found", merge.Base)
+	}
+	baseCommit, err := upstream.findVersion(repoRoot, merge.Base)
+	if err != nil {
+		return nil, err
+	}
+	if len(baseCommit) == 0 {
+		return nil, fmt.Errorf("cannot find version of upstream contract with hash %s in the history of %s", merge.Base, repoRoot)
+	}
+	base, err := upstream.definitionAt(repoRoot, baseCommit, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load version of upstream contract with hash %s: %s", merge.Base, err)
+	}
+	log.Info().Msgf("Merging upstream changes since commit %s", baseCommit)
+	files, err := c.mergeUpstreamRefs(upstream, base, repoRoot, merge)
+	if err != nil {
+		return nil, err
+	}
+	if keepOurs && len(merge.Unmerged) > 0 {
+		log.Info().Msgf("Keeping the contract's own %s", strings.Join(merge.Unmerged, ", "))
+		merge.Unmerged, merge.unmergedRefs = make([]string, 0), nil
+	}
+	for _, f := range files {
+		conflicts, err := mergeUpstreamFile(f.ours.localPath, f.theirs.localPath, repoRoot, baseCommit)
+		if err != nil {
+			return nil, err
+		}
+		if conflicts > 0 {
+			rel, err := filepath.Rel(contractDir, f.ours.localPath)
+			if err != nil {
+				return nil, err
+			}
+			log.Warn().Msgf("Merge resulted in %d conflict(s) in %s", conflicts, rel)
+			merge.Conflicts = append(merge.Conflicts, rel)
+		}
+	}
+
+	lineage, err := lineageFrom(upstream, contractDir, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if merge.HasConflicts() {
+		// we keep the previous version of the upstream as the merge base until
+		// the user has resolved the conflicts, but save any references we've
+		// already merged, along with the merged files' hashes so the contract
+		// can still be loaded
+		if err := c.Save(ctx); err != nil {
+			return nil, err
+		}
+		merged, err := loadContractComponents(c.path.localPath, false, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := merged.Save(ctx); err != nil {
+			return nil, err
+		}
+		return merge, savePendingUpstreamMerge(contractDir, &pendingUpstreamMerge{
+			Upstream:  merge.Upstream,
+			Lineage:   lineage,
+			Conflicts: merge.Conflicts,
+			Unmerged:  merge.unmergedRefs,
+		})
+	}
+	c.Upstream = merge.Upstream
+	c.Lineage = lineage
+	if err := c.Save(ctx); err != nil {
+		return nil, err
+	}
+	if err := update(c.path.localPath, gitMsgPullUpstream, ctx); err != nil {
+		return nil, err
+	}
+	return merge, nil
+}
+
+// completeUpstreamMerge completes the given pending merge of upstream changes,
+// provided that no conflict markers remain in the files that had conflicts and
+// that the references that conflicted with the upstream's now refer to the
+// same content as the upstream's (or that we've been told to keep ours).
+func (c *Contract) completeUpstreamMerge(pending *pendingUpstreamMerge, keepOurs bool, ctx *Context) (*UpstreamMerge, error) {
+	contractDir := path.Dir(c.path.localPath)
+	merge := &UpstreamMerge{
+		Base:      c.Upstream.Hash,
+		Upstream:  pending.Upstream,
+		Conflicts: make([]string, 0),
+		Unmerged:  make([]string, 0),
+	}
+	for _, f := range pending.Conflicts {
+		content, err := ioutil.ReadFile(path.Join(contractDir, f))
+		if err != nil {
+			return nil, err
+		}
+		if hasConflictMarkers(string(content)) {
+			merge.Conflicts = append(merge.Conflicts, f)
+		}
+	}
+	for _, ref := range pending.Unmerged {
+		ours := c.Schema
+		if len(ref.Partial) > 0 {
+			ours = partialsByName(c.Template.Partials)[ref.Partial]
+		}
+		switch {
+		case sameFileContent(ours, ref.Upstream):
+		case keepOurs:
+			log.Info().Msgf("Keeping the contract's own %s", ref.Component)
+		default:
+			merge.addUnmerged(ref.Component, ref.Partial, ref.Upstream)
+		}
+	}
+	if merge.HasConflicts() {
+		log.Warn().Msgf("Merge of upstream changes (upstream hash %s) is still in progress", merge.Upstream.Hash)
+		return merge, nil
+	}
+	log.Info().Msgf("Completing merge of upstream changes (upstream hash %s)", merge.Upstream.Hash)
+	c.Upstream = pending.Upstream
+	c.Lineage = pending.Lineage
+	if err := c.Save(ctx); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(path.Join(contractDir, upstreamMergeFilename)); err != nil {
+		return nil, err
+	}
+	if err := update(c.path.localPath, gitMsgPullUpstream, ctx); err != nil {
+		return nil, err
+	}
+	return merge, nil
+}
+
+// upstreamFilePair is a file in our contract along with the corresponding file
+// in the upstream contract.
+type upstreamFilePair struct {
+	ours, theirs *FileRef
+}
+
+// mergeUpstreamRefs merges the references to the upstream's template partials
+// and schema into this contract, given the version of the upstream recorded
+// in the contract (the merge base). It returns the files (including the
+// parameters file and template) whose content must then be merged. Conflicting
+// references are recorded in the given merge.
+func (c *Contract) mergeUpstreamRefs(upstream, base *Contract, repoRoot string, merge *UpstreamMerge) ([]upstreamFilePair, error) {
+	files := []upstreamFilePair{
+		{c.ParamsFile, upstream.ParamsFile},
+		{c.Template.File, upstream.Template.File},
+	}
+	// only files we've got our own copies of, which the upstream has had at
+	// the same location since the merge base, can be merged line by line
+	mergeable := func(ours, theirs, base *FileRef) bool {
+		if ours == nil || theirs == nil || base == nil || !ours.IsRelative() || !theirs.IsRelative() || theirs.Location != base.Location {
+			return false
+		}
+		_, err := repoRelPath(repoRoot, theirs.localPath)
+		return err == nil
+	}
+
+	if mergeable(c.Schema, upstream.Schema, base.Schema) {
+		files = append(files, upstreamFilePair{c.Schema, upstream.Schema})
+	} else if merged, ok := mergeUpstreamRef(c.Schema, upstream.Schema, base.Schema); !ok {
+		log.Warn().Msg("Schema was changed both in the contract and in its upstream")
+		merge.addUnmerged("schema", "", upstream.Schema)
+	} else if merged != c.Schema {
+		log.Info().Msg("Taking schema from upstream")
+		schema, err := c.deriveFileRef(merged)
+		if err != nil {
+			return nil, err
+		}
+		c.Schema = schema
+	}
+
+	ours, theirs, bases := partialsByName(c.Template.Partials), partialsByName(upstream.Template.Partials), partialsByName(base.Template.Partials)
+	for _, name := range unionOfKeys(ours, theirs, bases) {
+		if mergeable(ours[name], theirs[name], bases[name]) {
+			files = append(files, upstreamFilePair{ours[name], theirs[name]})
+			continue
+		}
+		merged, ok := mergeUpstreamRef(ours[name], theirs[name], bases[name])
+		if !ok {
+			log.Warn().Msgf("Template partial \"%s\" was changed both in the contract and in its upstream", name)
+			merge.addUnmerged(fmt.Sprintf("partial \"%s\"", name), name, theirs[name])
+			continue
+		}
+		if merged == ours[name] {
+			continue
+		}
+		if merged == nil {
+			log.Info().Msgf("Removing template partial \"%s\", as per upstream", name)
+			c.Template.removePartial(name)
+			continue
+		}
+		log.Info().Msgf("Taking template partial \"%s\" from upstream", name)
+		ref, err := c.deriveFileRef(merged)
+		if err != nil {
+			return nil, err
+		}
+		c.Template.setPartial(name, ref)
+	}
+	return files, nil
+}
+
+// mergeUpstreamRef performs a three-way merge of a reference to one of a
+// contract's components (nil if the contract doesn't have the component).
+// Returns the merged reference, or false if the contract and its upstream
+// have both changed the reference in different ways.
+func mergeUpstreamRef(ours, theirs, base *FileRef) (*FileRef, bool) {
+	switch {
+	case sameFileContent(theirs, base), sameFileContent(ours, theirs):
+		return ours, true
+	case sameFileContent(ours, base):
+		return theirs, true
+	}
+	return ours, false
+}
+
+// sameFileContent checks whether the given references (either of which may be
+// nil) refer to files with the same content.
+func sameFileContent(a, b *FileRef) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Hash == b.Hash
+}
+
+func partialsByName(partials []*Partial) map[string]*FileRef {
+	result := make(map[string]*FileRef)
+	for _, partial := range partials {
+		result[partial.Name] = partial.File
+	}
+	return result
+}
+
+// unionOfKeys returns the keys present in any of the given maps, in
+// alphabetical order.
+func unionOfKeys(maps ...map[string]*FileRef) []string {
+	keys := make(map[string]string)
+	for _, m := range maps {
+		for k := range m {
+			keys[k] = k
+		}
+	}
+	return sortedKeys(keys)
+}
+
+// deriveFileRef makes the given file from the upstream contract available to
+// this contract. As in deriveTo, relative files are copied across (preserving
+// their paths relative to the contract), whereas remote files continue to be
+// referred to at their original locations.
+func (c *Contract) deriveFileRef(upstreamRef *FileRef) (*FileRef, error) {
+	ref := &FileRef{
+		Location:  upstreamRef.Location,
+		Hash:      upstreamRef.Hash,
+		localPath: upstreamRef.localPath,
+	}
+	if !upstreamRef.IsRelative() {
+		return ref, nil
+	}
+	relPath := path.Clean(upstreamRef.Location)
+	if strings.HasPrefix(relPath, "..") {
+		relPath = upstreamRef.Filename()
+	}
+	ref.Location = "./" + relPath
+	ref.localPath = path.Join(path.Dir(c.path.localPath), relPath)
+	if err := os.MkdirAll(path.Dir(ref.localPath), 0755); err != nil {
+		return nil, err
+	}
+	log.Debug().Msgf("Copying %s to %s", upstreamRef.localPath, ref.localPath)
+	if err := copyFile(upstreamRef.localPath, ref.localPath); err != nil {
+		return nil, err
+	}
+	return ref, nil
+}
+
+// definitionAt parses this contract's file as of the given commit in the Git
+// repository with the given root. The returned contract's components are not
+// resolved, so only their locations and hashes are available.
+func (c *Contract) definitionAt(repoRoot, commit string, ctx *Context) (*Contract, error) {
+	rel, err := repoRelPath(repoRoot, c.path.localPath)
+	if err != nil {
+		return nil, err
+	}
+	content, err := gitShowFile(repoRoot, commit, rel)
+	if err != nil {
+		return nil, err
+	}
+	return parseContract(content, c.path.localPath, ctx.cache)
+}
+
+func loadPendingUpstreamMerge(contractDir string) (*pendingUpstreamMerge, error) {
+	content, err := ioutil.ReadFile(path.Join(contractDir, upstreamMergeFilename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pending pendingUpstreamMerge
+	if err := json.Unmarshal(content, &pending); err != nil {
+		return nil, fmt.Errorf("failed to interpret %s: %s", upstreamMergeFilename, err)
+	}
+	return &pending, nil
+}
+
+func savePendingUpstreamMerge(contractDir string, pending *pendingUpstreamMerge) error {
+	content, err := json.MarshalIndent(pending, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path.Join(contractDir, upstreamMergeFilename), content, 0644)
+}
+
+// hasConflictMarkers checks whether the given content contains any of the
+// conflict markers produced when merging.
+func hasConflictMarkers(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "<<<<<<< ") || strings.HasPrefix(line, ">>>>>>> ") {
+			return true
+		}
+	}
+	return false
+}
+
+// repoRoot returns the root of the Git repository containing this contract,
+// or an empty string if the contract is not in a Git repository.
+func (c *Contract) repoRoot() (string, error) {
+	contractDir := path.Dir(c.path.localPath)
+	if !isGitRepo(contractDir) {
+		return "", nil
+	}
+	return gitRepoRoot(contractDir)
+}
+
+// findVersion looks up the most recent commit at which this contract had the
+// given hash in the Git repository with the given root. Returns an empty
+// commit if there is no such commit.
+func (c *Contract) findVersion(repoRoot, hash string) (string, error) {
+	rel, err := repoRelPath(repoRoot, c.path.localPath)
+	if err != nil {
+		return "", err
+	}
+	return gitFindFileVersion(repoRoot, rel, hash)
+}
+
+// mergeUpstreamFile merges the changes made to the upstream file since the
+// given commit into our file, which is overwritten with the merged content.
+// Returns the number of conflicts.
+func mergeUpstreamFile(ours, theirs, repoRoot, baseCommit string) (int, error) {
+	rel, err := repoRelPath(repoRoot, theirs)
+	if err != nil {
+		return 0, err
+	}
+	base, err := gitShowFile(repoRoot, baseCommit, rel)
+	if err != nil {
+		return 0, err
+	}
+	baseFile, err := ioutil.TempFile("", "themis-contract-base")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(baseFile.Name())
+	_, err = baseFile.Write(base)
+	if closeErr := baseFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	log.Debug().Msgf("Merging upstream changes from %s into %s", theirs, ours)
+	merged, conflicts, err := fileMerge(ours, baseFile.Name(), theirs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to merge upstream changes into %s: %s", ours, err)
+	}
+	if err := ioutil.WriteFile(ours, merged, 0644); err != nil {
+		return 0, err
+	}
+	return conflicts, nil
+}
+
+// repoRelPath returns the given file's path relative to the root of the Git
+// repository containing it.
+func repoRelPath(repoRoot, file string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(file)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(repoRoot, resolved)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("file %s is outside of Git repository %s", file, repoRoot)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// LineageEntry records one of the contracts from which a contract has been
+// (directly or indirectly) derived.
+type LineageEntry struct {
+	Location  string `json:"location" yaml:"location" toml:"location"`    // The location of the contract.
+	Hash      string `json:"hash" yaml:"hash" toml:"hash"`                // The hash of the contract at the time this entry was recorded.
+	Timestamp string `json:"timestamp" yaml:"timestamp" toml:"timestamp"` // When this entry was recorded (RFC 3339), i.e. when the contract was derived or last merged.
+}
+
+// lineageFrom returns the lineage of a contract in the given folder, derived
+// from the given upstream contract. Local contracts are recorded by their paths
+// relative to the contract's folder, so that the lineage can be followed
+// regardless of the working directory, and still holds if the contracts are
+// moved around together.
+func lineageFrom(upstream *Contract, contractDir string, ctx *Context) ([]*LineageEntry, error) {
+	location := upstream.path.Location
+	if upstream.path.Type() == LocalRef {
+		location = upstream.path.localPath
+	}
+	lineage := []*LineageEntry{
+		{
+			Location:  location,
+			Hash:      upstream.path.Hash,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		},
+	}
+	upstreamLineage, err := upstream.lineage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lineage = append(lineage, upstreamLineage...)
+	absContractDir, err := filepath.Abs(contractDir)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*LineageEntry, 0, len(lineage))
+	for _, entry := range lineage {
+		relEntry := *entry
+		if fileRefType(entry.Location, ctx) == LocalRef {
+			abs, err := filepath.Abs(entry.Location)
+			if err != nil {
+				return nil, err
+			}
+			if rel, err := filepath.Rel(absContractDir, abs); err == nil {
+				relEntry.Location = filepath.ToSlash(rel)
+			}
+		}
+		result = append(result, &relEntry)
+	}
+	return result, nil
+}
+
+// lineage returns this contract's lineage, with the locations of local
+// contracts resolved against this contract's folder. Contracts derived before
+// lineages were recorded only know about their immediate upstream.
+func (c *Contract) lineage(ctx *Context) ([]*LineageEntry, error) {
+	if len(c.Lineage) == 0 && c.Upstream != nil {
+		return []*LineageEntry{{Location: c.Upstream.Location, Hash: c.Upstream.Hash}}, nil
+	}
+	contractDir := path.Dir(c.path.localPath)
+	lineage := make([]*LineageEntry, 0, len(c.Lineage))
+	for _, entry := range c.Lineage {
+		resolved := *entry
+		if fileRefType(entry.Location, ctx) == LocalRef && !filepath.IsAbs(entry.Location) {
+			abs, err := filepath.Abs(path.Join(contractDir, filepath.FromSlash(entry.Location)))
+			if err != nil {
+				return nil, err
+			}
+			resolved.Location = abs
+		}
+		lineage = append(lineage, &resolved)
+	}
+	return lineage, nil
+}
+
+// LineageLayer is a single contract in a contract's lineage, along with the
+// changes it introduced relative to its own upstream.
+type LineageLayer struct {
+	Location     string `json:"location"`                // The location of the contract.
+	Hash         string `json:"hash"`                    // The current hash of the contract.
+	RecordedHash string `json:"recorded_hash,omitempty"` // The hash of the contract as recorded in the lineage (empty for the contract itself).
+	Timestamp    string `json:"timestamp,omitempty"`     // When the lineage entry was recorded.
+	Changes      *Diff  `json:"changes,omitempty"`       // This contract's changes relative to its upstream (nil for the root of the lineage).
+}
+
+// Changed returns true if the contract has changed since it was recorded in
+// the lineage.
+func (l *LineageLayer) Changed() bool {
+	return len(l.RecordedHash) > 0 && l.RecordedHash != l.Hash
+}
+
+// UpstreamLog walks this contract's lineage back to its root, loading each of
+// the contracts in the lineage and comparing its parameters and template to
+// those of its upstream, to show which layer introduced which changes. The
+// first layer is this contract itself and the last is the root of the lineage.
+// Each contract is loaded as it currently is, which may differ from the
+// version recorded in the lineage.
+func (c *Contract) UpstreamLog(diffProg string, ctx *Context) ([]*LineageLayer, error) {
+	if c.Upstream == nil {
+		return nil, fmt.Errorf("contract has no upstream")
+	}
+	lineage, err := c.lineage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	contracts := []*Contract{c}
+	layers := []*LineageLayer{{Location: c.path.Location, Hash: c.path.Hash}}
+	for _, entry := range lineage {
+		log.Info().Msgf("Loading upstream contract: %s", entry.Location)
+		upstream, err := Load(entry.Location, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load upstream contract %s: %s", entry.Location, err)
+		}
+		layer := &LineageLayer{
+			Location:     entry.Location,
+			Hash:         upstream.path.Hash,
+			RecordedHash: entry.Hash,
+			Timestamp:    entry.Timestamp,
+		}
+		if layer.Changed() {
+			log.Warn().Msgf("Upstream contract %s has changed since it was recorded in the lineage (hash %s, now %s)", entry.Location, entry.Hash, layer.Hash)
+		}
+		contracts = append(contracts, upstream)
+		layers = append(layers, layer)
+	}
+	for i := 0; i < len(contracts)-1; i++ {
+		var err error
+		if layers[i].Changes, err = contracts[i].diffAgainst(contracts[i+1], diffProg, ctx); err != nil {
+			return nil, fmt.Errorf("failed to compare %s to its upstream: %s", layers[i].Location, err)
+		}
+	}
+	return layers, nil
+}
@@ -0,0 +1,539 @@
+package themis_contract_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+	"testing"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+)
+
+const upstreamTestTemplate = `# Services Agreement
+
+This agreement is between {{client}} and the provider.
+
+## Payment
+
+Invoices are payable within 30 days.
+
+## Termination
+
+Either party may terminate this agreement with notice.
+`
+
+func TestUpstreamPull(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	ctx := contract.NewTestContext(nil, contract.NewTestProfile("test", "", nil))
+	upstreamDir := path.Join(tempDir, "upstream")
+	upstreamContract := path.Join(upstreamDir, "contract.json")
+	commitUpstream := setupTestUpstream(t, upstreamDir, ctx)
+
+	derivedContract := path.Join(tempDir, "derived", "contract.json")
+	if _, err := contract.New(derivedContract, upstreamContract, "", ctx); err != nil {
+		t.Fatal(err)
+	}
+	derivedTemplate := path.Join(tempDir, "derived", "contract.md")
+	// we change the payment terms in our contract, whereas the upstream changes
+	// the termination clause
+	writeReplaced(t, derivedTemplate, "30 days", "14 days")
+	if err := contract.Update(derivedContract, ctx); err != nil {
+		t.Fatal(err)
+	}
+	upstreamTemplate := path.Join(upstreamDir, "contract.md")
+	writeReplaced(t, upstreamTemplate, "with notice", "with 60 days' written notice")
+	// the upstream also adds a partial
+	if err := os.MkdirAll(path.Join(upstreamDir, "clauses"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(upstreamDir, "clauses", "confidentiality.md"), []byte("## Confidentiality\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeReplaced(t, upstreamTemplate, "## Termination", "{{> confidentiality}}\n\n## Termination")
+	writeReplaced(t, upstreamContract, `"format":"Mustache",`, `"format":"Mustache","partials":[{"name":"confidentiality","file":{"location":"./clauses/confidentiality.md"}}],`)
+	commitUpstream("Update upstream")
+
+	c, err := contract.Load(derivedContract, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merge, err := c.UpstreamPull(false, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merge.UpToDate() || len(merge.Conflicts) > 0 {
+		t.Fatalf("expected a clean merge of upstream changes, but got: %v", merge)
+	}
+	merged := readTestFile(t, derivedTemplate)
+	if !strings.Contains(merged, "14 days") || !strings.Contains(merged, "60 days' written notice") {
+		t.Errorf("expected merged template to contain both our and upstream changes, but got:\n%s", merged)
+	}
+	// the merged contract's hashes must have been updated
+	if c, err = contract.Load(derivedContract, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Template.Partials) != 1 || c.Template.Partials[0].File.Location != "./clauses/confidentiality.md" {
+		t.Fatalf("expected upstream's new partial to have been merged, but got partials: %v", c.Template.Partials)
+	}
+	if _, err := os.Stat(path.Join(tempDir, "derived", "clauses", "confidentiality.md")); err != nil {
+		t.Errorf("expected upstream's new partial to have been copied across: %s", err)
+	}
+	if merge, err = c.UpstreamPull(false, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !merge.UpToDate() {
+		t.Errorf("expected contract to be up to date with its upstream after merging")
+	}
+
+	// conflicting changes to the same clause
+	writeReplaced(t, derivedTemplate, "14 days", "7 days")
+	if err := contract.Update(derivedContract, ctx); err != nil {
+		t.Fatal(err)
+	}
+	writeReplaced(t, upstreamTemplate, "30 days", "45 days")
+	commitUpstream("Update upstream")
+	if c, err = contract.Load(derivedContract, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if merge, err = c.UpstreamPull(false, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(merge.Conflicts) != 1 || merge.Conflicts[0] != "contract.md" {
+		t.Fatalf("expected a merge conflict in contract.md, but got: %v", merge.Conflicts)
+	}
+	merged = readTestFile(t, derivedTemplate)
+	if !strings.Contains(merged, "<<<<<<< ours") || !strings.Contains(merged, ">>>>>>> upstream") {
+		t.Errorf("expected conflict markers in merged template, but got:\n%s", merged)
+	}
+	// the upstream hash must be held back until the conflicts are resolved
+	baseHash := c.Upstream.Hash
+	if c, err = contract.Load(derivedContract, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c.Upstream.Hash != baseHash {
+		t.Errorf("expected upstream hash to remain %s while conflicts are unresolved, but got %s", baseHash, c.Upstream.Hash)
+	}
+	pendingMerge := path.Join(tempDir, "derived", "upstream-merge.json")
+	if _, err := os.Stat(pendingMerge); err != nil {
+		t.Fatalf("expected pending merge to be recorded: %s", err)
+	}
+	if merge, err = c.UpstreamPull(false, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(merge.Conflicts) != 1 {
+		t.Fatalf("expected unresolved merge conflict in contract.md, but got: %v", merge.Conflicts)
+	}
+
+	// once resolved, pulling again completes the merge
+	resolved := regexp.MustCompile(`(?s)<<<<<<< ours.*>>>>>>> upstream\n`).ReplaceAllString(merged, "Invoices are payable within 10 days.\n")
+	if err := ioutil.WriteFile(derivedTemplate, []byte(resolved), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if merge, err = c.UpstreamPull(false, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if merge.HasConflicts() {
+		t.Fatalf("expected merge to complete once conflicts were resolved, but got: %v", merge.Conflicts)
+	}
+	if _, err := os.Stat(pendingMerge); !os.IsNotExist(err) {
+		t.Errorf("expected pending merge record to be removed once the merge was complete, but got: %v", err)
+	}
+	if c, err = contract.Load(derivedContract, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if merge, err = c.UpstreamPull(false, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !merge.UpToDate() {
+		t.Errorf("expected contract to be up to date with its upstream after completing the merge")
+	}
+}
+
+func TestUpstreamPullConflictingPartials(t *testing.T) {
+	// the contract's own reference can either be changed to match the
+	// upstream's, or kept explicitly
+	for _, keepOurs := range []bool{false, true} {
+		t.Run(fmt.Sprintf("keepOurs=%t", keepOurs), func(t *testing.T) {
+			testUpstreamPullConflictingPartials(t, keepOurs)
+		})
+	}
+}
+
+func testUpstreamPullConflictingPartials(t *testing.T, keepOurs bool) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	// shared clauses live outside of the contracts' folders, so both contracts
+	// refer to them directly instead of having their own copies
+	sharedDir := path.Join(tempDir, "shared")
+	upstreamDir := path.Join(tempDir, "upstream")
+	for _, dir := range []string{sharedDir, upstreamDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, law := range []string{"delaware", "ontario", "zug"} {
+		if err := ioutil.WriteFile(path.Join(sharedDir, law+".md"), []byte("Governed by the laws of "+law+".\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		"params.json":   `{"client": "Acme Corp", "signatories": [{"id": "alice", "name": "Alice", "email": "alice@somewhere.com"}]}`,
+		"contract.md":   upstreamTestTemplate + "\n{{> governing-law}}\n",
+		"contract.json": `{"params": {"location": "./params.json"}, "template": {"format": "Mustache", "file": {"location": "./contract.md"}, "partials": [{"name": "governing-law", "file": {"location": "` + path.Join(sharedDir, "delaware.md") + `"}}]}}`,
+	}
+	for filename, content := range files {
+		if err := ioutil.WriteFile(path.Join(upstreamDir, filename), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := contract.NewTestContext(nil, contract.NewTestProfile("test", "", nil))
+	upstreamContract := path.Join(upstreamDir, "contract.json")
+	commitUpstream := func() {
+		if err := contract.Update(upstreamContract, ctx); err != nil {
+			t.Fatal(err)
+		}
+		runTestGit(t, upstreamDir, "add", ".")
+		runTestGit(t, upstreamDir, "commit", "-m", "Update upstream")
+	}
+	runTestGit(t, upstreamDir, "init")
+	commitUpstream()
+
+	derivedDir := path.Join(tempDir, "derived")
+	derivedContract := path.Join(derivedDir, "contract.json")
+	if _, err := contract.New(derivedContract, upstreamContract, "", ctx); err != nil {
+		t.Fatal(err)
+	}
+	// both contracts switch to different governing law clauses
+	writeReplaced(t, derivedContract, "delaware.md", "ontario.md")
+	if err := contract.Update(derivedContract, ctx); err != nil {
+		t.Fatal(err)
+	}
+	writeReplaced(t, upstreamContract, "delaware.md", "zug.md")
+	commitUpstream()
+
+	c, err := contract.Load(derivedContract, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseHash := c.Upstream.Hash
+	merge, err := c.UpstreamPull(false, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merge.Conflicts) != 0 || len(merge.Unmerged) != 1 || merge.Unmerged[0] != `partial "governing-law"` {
+		t.Fatalf("expected the governing law partial to be reported as unmerged, but got: %v", merge)
+	}
+	if c, err = contract.Load(derivedContract, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c.Upstream.Hash != baseHash {
+		t.Errorf("expected upstream hash to remain %s while the merge is pending, but got %s", baseHash, c.Upstream.Hash)
+	}
+	if loc := c.Template.Partials[0].File.Location; loc != path.Join(sharedDir, "ontario.md") {
+		t.Errorf("expected the contract's own governing law clause to be kept, but got %s", loc)
+	}
+	if _, err := os.Stat(path.Join(derivedDir, "upstream-merge.json")); err != nil {
+		t.Fatalf("expected pending merge to be recorded: %s", err)
+	}
+
+	// the merge can't be completed while the references still disagree
+	if merge, err = c.UpstreamPull(false, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(merge.Unmerged) != 1 || merge.Unmerged[0] != `partial "governing-law"` {
+		t.Fatalf("expected the governing law partial to still be unmerged, but got: %v", merge)
+	}
+	if c, err = contract.Load(derivedContract, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c.Upstream.Hash != baseHash {
+		t.Errorf("expected upstream hash to remain %s while the merge is pending, but got %s", baseHash, c.Upstream.Hash)
+	}
+
+	expectedLaw := "ontario.md"
+	if !keepOurs {
+		expectedLaw = "zug.md"
+		writeReplaced(t, derivedContract, "ontario.md", "zug.md")
+		if err := contract.Update(derivedContract, ctx); err != nil {
+			t.Fatal(err)
+		}
+		if c, err = contract.Load(derivedContract, ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if merge, err = c.UpstreamPull(keepOurs, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if merge.HasConflicts() {
+		t.Fatalf("expected merge to complete, but got: %v", merge)
+	}
+	if c, err = contract.Load(derivedContract, ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c.Upstream.Hash == baseHash {
+		t.Errorf("expected upstream hash to be updated once the merge was complete")
+	}
+	if loc := c.Template.Partials[0].File.Location; loc != path.Join(sharedDir, expectedLaw) {
+		t.Errorf("expected governing law clause %s once the merge was complete, but got %s", expectedLaw, loc)
+	}
+	if _, err := os.Stat(path.Join(derivedDir, "upstream-merge.json")); !os.IsNotExist(err) {
+		t.Errorf("expected pending merge record to be removed once the merge was complete, but got: %v", err)
+	}
+}
+
+func TestUpstreamStatus(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	ctx := contract.NewTestContext(nil, contract.NewTestProfile("test", "", nil))
+	upstreamDir := path.Join(tempDir, "upstream")
+	commitUpstream := setupTestUpstream(t, upstreamDir, ctx)
+	derivedContract := path.Join(tempDir, "derived", "contract.json")
+	c, err := contract.New(derivedContract, path.Join(upstreamDir, "contract.json"), "", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := c.UpstreamStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != contract.UpstreamUpToDate {
+		t.Errorf("expected newly derived contract to be up to date with its upstream, but got state \"%s\"", status.State)
+	}
+
+	writeReplaced(t, path.Join(upstreamDir, "contract.md"), "30 days", "45 days")
+	commitUpstream("Extend payment terms")
+	runTestGit(t, upstreamDir, "tag", "v2")
+	// commits that don't touch the contract's files aren't revisions
+	if err := ioutil.WriteFile(path.Join(upstreamDir, "README.md"), []byte("# Contracts\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runTestGit(t, upstreamDir, "add", "README.md")
+	runTestGit(t, upstreamDir, "commit", "-m", "Add README")
+	if status, err = c.UpstreamStatus(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if status.State != contract.UpstreamBehind || len(status.Revisions) != 1 {
+		t.Fatalf("expected contract to be 1 revision behind its upstream, but got state \"%s\" with revisions %v", status.State, status.Revisions)
+	}
+	rev := status.Revisions[0]
+	if rev.Subject != "Extend payment terms" || len(rev.Tags) != 1 || rev.Tags[0] != "v2" {
+		t.Errorf("unexpected upstream revision: %v", rev)
+	}
+	if len(status.Tags) != 1 || status.Tags[0] != "v2" {
+		t.Errorf("expected new upstream tags to be [v2], but got %v", status.Tags)
+	}
+}
+
+// gitUpstreamCache serves files from Git URLs out of a local repository, as if
+// it were a clone of the remote repository, and otherwise behaves like a
+// regular cache.
+type gitUpstreamCache struct {
+	*contract.FSCache
+	repoDir string
+}
+
+func (c *gitUpstreamCache) FromGit(u *contract.GitURL) (string, error) {
+	return c.LocalPathForGitURL(u), nil
+}
+
+func (c *gitUpstreamCache) LocalPathForGitURL(u *contract.GitURL) string {
+	return path.Join(c.repoDir, u.Path)
+}
+
+func TestGitUpstream(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	upstreamDir := path.Join(tempDir, "upstream")
+	fsCache, err := contract.OpenFSCache(path.Join(tempDir, "cache"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := &gitUpstreamCache{FSCache: fsCache, repoDir: upstreamDir}
+	ctx := contract.NewTestContext(cache, contract.NewTestProfile("test", "", nil))
+	commitUpstream := setupTestUpstream(t, upstreamDir, ctx)
+
+	derivedContract := path.Join(tempDir, "derived", "contract.json")
+	c, err := contract.New(derivedContract, "git://github.com:somewhere/templates.git/contract.json", "", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// unchanged upstream files must still be resolved within the repository,
+	// even though they're also in the cache's content-addressed store
+	writeReplaced(t, path.Join(upstreamDir, "contract.md"), "30 days", "45 days")
+	commitUpstream("Extend payment terms")
+	status, err := c.UpstreamStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != contract.UpstreamBehind || len(status.Revisions) != 1 {
+		t.Fatalf("expected contract to be 1 revision behind its upstream, but got state \"%s\" with revisions %v", status.State, status.Revisions)
+	}
+	merge, err := c.UpstreamPull(false, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merge.HasConflicts() {
+		t.Fatalf("expected a clean merge of upstream changes, but got: %v", merge)
+	}
+	if merged := readTestFile(t, path.Join(tempDir, "derived", "contract.md")); !strings.Contains(merged, "45 days") {
+		t.Errorf("expected merged template to contain upstream changes, but got:\n%s", merged)
+	}
+}
+
+func TestUpstreamLog(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "themis-contract-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+	ctx := contract.NewTestContext(nil, contract.NewTestProfile("test", "", nil))
+	rootDir := path.Join(tempDir, "root")
+	setupTestUpstream(t, rootDir, ctx)
+
+	// the middle layer changes the template, and the leaf changes the params.
+	// The middle layer refers to the root by a path relative to the working
+	// directory at the time it was derived, which must not end up in the
+	// lineage as is.
+	midContract := path.Join(tempDir, "mid", "contract.json")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(tempDir); err != nil {
+		t.Fatal(err)
+	}
+	_, err = contract.New("mid/contract.json", "root/contract.json", "", ctx)
+	if chdirErr := os.Chdir(wd); chdirErr != nil {
+		t.Fatal(chdirErr)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeReplaced(t, path.Join(tempDir, "mid", "contract.md"), "30 days", "14 days")
+	if err := contract.Update(midContract, ctx); err != nil {
+		t.Fatal(err)
+	}
+	leafContract := path.Join(tempDir, "leaf", "contract.json")
+	if _, err := contract.New(leafContract, midContract, "", ctx); err != nil {
+		t.Fatal(err)
+	}
+	writeReplaced(t, path.Join(tempDir, "leaf", "params.json"), "Acme Corp", "Globex")
+	if err := contract.Update(leafContract, ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := contract.Load(leafContract, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// lineage locations are relative to the leaf contract's folder
+	if len(c.Lineage) != 2 || c.Lineage[0].Location != "../mid/contract.json" || c.Lineage[1].Location != "../root/contract.json" {
+		t.Fatalf("expected lineage to consist of ../mid/contract.json and ../root/contract.json, but got %v", c.Lineage)
+	}
+
+	// so the lineage can still be followed once the contracts have been
+	// moved together
+	movedDir := path.Join(tempDir, "moved")
+	if err := os.MkdirAll(movedDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"root", "mid", "leaf"} {
+		if err := os.Rename(path.Join(tempDir, dir), path.Join(movedDir, dir)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c, err = contract.Load(path.Join(movedDir, "leaf", "contract.json"), ctx); err != nil {
+		t.Fatal(err)
+	}
+	layers, err := c.UpstreamLog("diff", ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layers) != 3 {
+		t.Fatalf("expected 3 layers in lineage, but got %d", len(layers))
+	}
+	if layers[2].Location != path.Join(movedDir, "root", "contract.json") {
+		t.Errorf("expected root of lineage to be resolved to its new location, but got %s", layers[2].Location)
+	}
+	leaf, mid, root := layers[0], layers[1], layers[2]
+	if len(leaf.Changes.ParamsDiff) != 1 || leaf.Changes.ParamsDiff[0].Path != "client" || len(leaf.Changes.TemplateDiff) > 0 {
+		t.Errorf("expected leaf to only change the client parameter, but got: %v", leaf.Changes)
+	}
+	if len(mid.Changes.ParamsDiff) != 0 || !strings.Contains(mid.Changes.TemplateDiff, "14 days") {
+		t.Errorf("expected middle layer to only change the template, but got: %v", mid.Changes)
+	}
+	if root.Changes != nil || root.Changed() {
+		t.Errorf("expected root of lineage to be unchanged and have no upstream, but got: %v", root)
+	}
+}
+
+// setupTestUpstream creates an upstream contract in a new Git repository in
+// the given folder. Returns a function that updates and commits the upstream.
+func setupTestUpstream(t *testing.T, upstreamDir string, ctx *contract.Context) func(string) {
+	if err := os.MkdirAll(upstreamDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"params.json":   `{"client": "Acme Corp", "signatories": [{"id": "alice", "name": "Alice", "email": "alice@somewhere.com"}]}`,
+		"contract.md":   upstreamTestTemplate,
+		"contract.json": `{"params": {"location": "./params.json"}, "template": {"format": "Mustache", "file": {"location": "./contract.md"}}}`,
+	}
+	for filename, content := range files {
+		if err := ioutil.WriteFile(path.Join(upstreamDir, filename), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commitUpstream := func(msg string) {
+		if err := contract.Update(path.Join(upstreamDir, "contract.json"), ctx); err != nil {
+			t.Fatal(err)
+		}
+		runTestGit(t, upstreamDir, "add", ".")
+		runTestGit(t, upstreamDir, "commit", "-m", msg)
+	}
+	runTestGit(t, upstreamDir, "init")
+	commitUpstream("Add upstream contract")
+	return commitUpstream
+}
+
+func runTestGit(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@somewhere.com"}, args...)...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %s failed: %s\n%s", strings.Join(args, " "), err, output)
+	}
+}
+
+func readTestFile(t *testing.T, filename string) string {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func writeReplaced(t *testing.T, filename, old, new string) {
+	content := readTestFile(t, filename)
+	if !strings.Contains(content, old) {
+		t.Fatalf("expected %s to contain \"%s\"", filename, old)
+	}
+	if err := ioutil.WriteFile(filename, []byte(strings.Replace(content, old, new, 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
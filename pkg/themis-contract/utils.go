@@ -3,6 +3,7 @@ package themis_contract
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -66,3 +67,13 @@ func hasAnyPrefix(s string, prefixes []string) bool {
 	}
 	return false
 }
+
+// sortedKeys returns the keys of the given map in alphabetical order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
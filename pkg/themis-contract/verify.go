@@ -0,0 +1,149 @@
+package themis_contract
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// VerificationStatus summarizes whether or not a signatory's signature is
+// valid with respect to the current state of a contract.
+type VerificationStatus string
+
+const (
+	SignatureValid   VerificationStatus = "valid"   // The signature covers the current contract.
+	SignatureMissing VerificationStatus = "missing" // The signatory has not cryptographically signed the contract.
+	SignatureStale   VerificationStatus = "stale"   // The signature is authentic, but covers an older version of the contract.
+	SignatureInvalid VerificationStatus = "invalid" // The signature could not be verified.
+)
+
+// SignatoryVerification is the result of verifying a single signatory's
+// signature.
+type SignatoryVerification struct {
+	Signatory *Signatory
+	Status    VerificationStatus
+	Reason    string // A human-readable explanation of the status (empty if valid).
+}
+
+// Verify checks each signatory's cryptographic signatures (OpenPGP and/or SSH)
+// against the current hashes of the contract, its parameters and its template.
+// It returns one result per signatory, in the same order as the contract's
+// signatories.
+//
+// By default, SSH signatures are checked against the SSH public key recorded
+// for each signatory, associated with their e-mail address. If an allowed
+// signers file is given (see the "ALLOWED SIGNERS" section of the ssh-keygen
+// manual), only the keys it associates with each signatory's e-mail address are
+// trusted instead.
+func (c *Contract) Verify(allowedSignersFile string) ([]*SignatoryVerification, error) {
+	hashes := c.Hashes()
+	log.Debug().Msgf("Verifying signatures against contract hashes: %v", hashes)
+	results := make([]*SignatoryVerification, 0)
+	for _, signatory := range c.signatories {
+		result, err := verifySignatory(signatory, c.signatures.ForSignatory(signatory.Id), hashes, allowedSignersFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify signature for signatory \"%s\": %s", signatory.Id, err)
+		}
+		log.Debug().Msgf("Signatory \"%s\" verification status: %s", signatory.Id, result.Status)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func verifySignatory(signatory *Signatory, record *SignatureRecord, hashes *ContractHashes, allowedSignersFile string) (*SignatoryVerification, error) {
+	result := &SignatoryVerification{Signatory: signatory}
+	if record == nil || (len(signatory.DetachedSignature) == 0 && len(signatory.SSHSignature) == 0) {
+		result.Status = SignatureMissing
+		if record != nil {
+			result.Reason = "only an image signature is present"
+		}
+		return result, nil
+	}
+	// we reconstruct the statement exactly as it was signed
+	statement := signingStatement(record.SignatoryID, record.Email, record.Hashes)
+	if len(signatory.DetachedSignature) > 0 {
+		v, err := gpgVerifyDetached(signatory.DetachedSignature, []byte(statement))
+		if err != nil {
+			return nil, err
+		}
+		if !v.good {
+			result.Status = SignatureInvalid
+			result.Reason = v.reason
+			return result, nil
+		}
+		if !strings.EqualFold(v.fingerprint, record.KeyID) {
+			result.Status = SignatureInvalid
+			result.Reason = fmt.Sprintf("signed with key %s, but the signature was recorded as being made with key %s", v.fingerprint, record.KeyID)
+			return result, nil
+		}
+		if !v.trusted {
+			result.Status = SignatureInvalid
+			result.Reason = fmt.Sprintf("signing key %s is not trusted in the local GPG keyring", v.fingerprint)
+			return result, nil
+		}
+		if !strings.Contains(v.userID, "<"+signatory.Email+">") {
+			result.Status = SignatureInvalid
+			result.Reason = fmt.Sprintf("signed by \"%s\", whose key does not belong to %s", v.userID, signatory.Email)
+			return result, nil
+		}
+	}
+	if len(signatory.SSHSignature) > 0 {
+		if len(signatory.SSHPublicKey) == 0 {
+			result.Status = SignatureInvalid
+			result.Reason = "no SSH public key is declared for this signatory in the contract parameters"
+			return result, nil
+		}
+		if !sshPublicKeysEqual(signatory.SSHPublicKey, record.SSHPublicKey) {
+			result.Status = SignatureInvalid
+			result.Reason = "the SSH public key declared for this signatory in the contract parameters is not the one that was used to sign"
+			return result, nil
+		}
+		signersFile := allowedSignersFile
+		if len(signersFile) == 0 {
+			generated, err := writeSSHAllowedSigners(signatory.Email, record.SSHPublicKey)
+			if err != nil {
+				return nil, err
+			}
+			defer os.Remove(generated)
+			signersFile = generated
+		}
+		good, reason, err := sshVerify(signersFile, signatory.Email, signatory.SSHSignature, []byte(statement))
+		if err != nil {
+			return nil, err
+		}
+		if !good {
+			result.Status = SignatureInvalid
+			result.Reason = reason
+			return result, nil
+		}
+	}
+	if changed := changedComponents(record.Hashes, hashes); len(changed) > 0 {
+		result.Status = SignatureStale
+		result.Reason = fmt.Sprintf("%s changed since signing on %s", strings.Join(changed, ", "), record.Timestamp.Format(SignatureTimestampFormat))
+		return result, nil
+	}
+	result.Status = SignatureValid
+	return result, nil
+}
+
+// changedComponents returns the names of the contract components whose hashes
+// differ between `a` and `b`.
+func changedComponents(a, b *ContractHashes) []string {
+	changed := make([]string, 0)
+	if a.Contract != b.Contract {
+		changed = append(changed, "contract")
+	}
+	if a.Params != b.Params {
+		changed = append(changed, "params")
+	}
+	if a.Template != b.Template {
+		changed = append(changed, "template")
+	}
+	return changed
+}
+
+func (v *SignatoryVerification) String() string {
+	return fmt.Sprintf("SignatoryVerification{Signatory: %v, Status: \"%s\", Reason: \"%s\"}", v.Signatory, v.Status, v.Reason)
+}
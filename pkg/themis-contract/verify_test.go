@@ -0,0 +1,208 @@
+package themis_contract_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	contract "github.com/informalsystems/themis-contract/pkg/themis-contract"
+)
+
+func TestVerifySignatory(t *testing.T) {
+	tempDir := setupTestGPG(t)
+	generateTestGPGKey(t, "Michael Anderson <manderson@somewhere.com>")
+
+	signatory := &contract.Signatory{
+		Id:    "manderson",
+		Name:  "Michael Anderson",
+		Email: "manderson@somewhere.com",
+	}
+	signedHashes := &contract.ContractHashes{Contract: "a", Params: "b", Template: "c"}
+	record := &contract.SignatureRecord{
+		SignatoryID:       signatory.Id,
+		Email:             signatory.Email,
+		DetachedSignature: "sig--manderson.asc",
+		Timestamp:         time.Date(2020, 9, 1, 12, 0, 0, 0, time.UTC),
+		Hashes:            signedHashes,
+	}
+	statement := []byte(contract.SigningStatement(record.SignatoryID, record.Email, record.Hashes))
+	signed := *signatory
+	signed.DetachedSignature = path.Join(tempDir, "sig--manderson.asc")
+	fingerprint, err := contract.GPGDetachSign("manderson@somewhere.com", statement, signed.DetachedSignature)
+	if err != nil {
+		t.Fatalf("failed to sign statement: %v", err)
+	}
+	record.KeyID = fingerprint
+
+	// someone else's key, claiming the same e-mail address, which we have
+	// imported into our keyring
+	otherHome := path.Join(tempDir, "other-gnupg")
+	if err := os.Mkdir(otherHome, 0700); err != nil {
+		t.Fatal(err)
+	}
+	defer exec.Command("gpgconf", "--homedir", otherHome, "--kill", "gpg-agent").Run()
+	if output, err := exec.Command("gpg", "--homedir", otherHome, "--batch", "--passphrase", "", "--quick-gen-key", "Mallory <manderson@somewhere.com>", "ed25519", "sign", "0").CombinedOutput(); err != nil {
+		t.Fatalf("failed to generate GPG key: %v\n%s", err, output)
+	}
+	exported, err := exec.Command("gpg", "--homedir", otherHome, "--batch", "--armor", "--export").Output()
+	if err != nil {
+		t.Fatalf("failed to export GPG key: %v", err)
+	}
+	importCmd := exec.Command("gpg", "--batch", "--import")
+	importCmd.Stdin = strings.NewReader(string(exported))
+	if output, err := importCmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to import GPG key: %v\n%s", err, output)
+	}
+	forged := *signatory
+	forged.DetachedSignature = path.Join(tempDir, "sig--mallory.asc")
+	signForged := exec.Command("gpg", "--homedir", otherHome, "--batch", "--armor", "--output", forged.DetachedSignature, "--detach-sign")
+	signForged.Stdin = strings.NewReader(string(statement))
+	if output, err := signForged.CombinedOutput(); err != nil {
+		t.Fatalf("failed to sign statement with other key: %v\n%s", err, output)
+	}
+	output, err := exec.Command("gpg", "--homedir", otherHome, "--batch", "--with-colons", "--list-keys").Output()
+	if err != nil {
+		t.Fatalf("failed to list GPG keys: %v", err)
+	}
+	otherFingerprint := ""
+	for _, line := range strings.Split(string(output), "\n") {
+		if fields := strings.Split(line, ":"); fields[0] == "fpr" && len(otherFingerprint) == 0 {
+			otherFingerprint = fields[9]
+		}
+	}
+	// a manifest record claiming that the other key was used to sign
+	forgedRecord := *record
+	forgedRecord.KeyID = otherFingerprint
+
+	tampered := *record
+	tampered.Hashes = &contract.ContractHashes{Contract: "x", Params: "b", Template: "c"}
+
+	testCases := []struct {
+		signatory *contract.Signatory
+		record    *contract.SignatureRecord
+		hashes    *contract.ContractHashes
+		status    contract.VerificationStatus
+		reason    string
+	}{
+		{&signed, record, signedHashes, contract.SignatureValid, ""},
+		{signatory, nil, signedHashes, contract.SignatureMissing, ""},
+		{signatory, record, signedHashes, contract.SignatureMissing, "only an image signature is present"},
+		{&signed, record, &contract.ContractHashes{Contract: "a", Params: "d", Template: "c"}, contract.SignatureStale, "params changed since signing on 1 September 2020"},
+		{&signed, &tampered, tampered.Hashes, contract.SignatureInvalid, "bad signature"},
+		{&forged, record, signedHashes, contract.SignatureInvalid, "recorded as being made with key " + fingerprint},
+		{&forged, &forgedRecord, signedHashes, contract.SignatureInvalid, "is not trusted"},
+	}
+	for i, tc := range testCases {
+		result, err := contract.VerifySignatory(tc.signatory, tc.record, tc.hashes, "")
+		if err != nil {
+			t.Fatalf("case %d: failed to verify signatory: %v", i, err)
+		}
+		if result.Status != tc.status {
+			t.Errorf("case %d: expected status \"%s\", but got \"%s\" (%s)", i, tc.status, result.Status, result.Reason)
+		}
+		if !strings.Contains(result.Reason, tc.reason) {
+			t.Errorf("case %d: expected reason to contain \"%s\", but got \"%s\"", i, tc.reason, result.Reason)
+		}
+	}
+}
+
+func TestVerifySSHSignatory(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not available")
+	}
+	tempDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory for testing: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	keyFile, pubKey := generateTestSSHKey(t, path.Join(tempDir, "id_ed25519"))
+	otherKeyFile, otherPubKey := generateTestSSHKey(t, path.Join(tempDir, "other_ed25519"))
+	// when overridden, only the signatory's own key is trusted
+	allowedSignersFile := path.Join(tempDir, "allowed_signers")
+	if err := ioutil.WriteFile(allowedSignersFile, []byte("manderson@somewhere.com namespaces=\"themis-contract\" "+pubKey+"\n"), 0644); err != nil {
+		t.Fatalf("failed to write allowed signers file: %v", err)
+	}
+
+	signedHashes := &contract.ContractHashes{Contract: "a", Params: "b", Template: "c"}
+	signatory := &contract.Signatory{
+		Id:           "manderson",
+		Email:        "manderson@somewhere.com",
+		SSHPublicKey: pubKey,
+	}
+	signWith := func(keyFile, sigPubKey string) (*contract.Signatory, *contract.SignatureRecord) {
+		record := &contract.SignatureRecord{
+			SignatoryID:  signatory.Id,
+			Email:        signatory.Email,
+			SSHPublicKey: sigPubKey,
+			SSHSignature: path.Base(keyFile) + ".sig",
+			Timestamp:    time.Date(2020, 9, 1, 12, 0, 0, 0, time.UTC),
+			Hashes:       signedHashes,
+		}
+		sig, err := contract.SSHSign(keyFile, []byte(contract.SigningStatement(record.SignatoryID, record.Email, record.Hashes)))
+		if err != nil {
+			t.Fatalf("failed to sign statement: %v", err)
+		}
+		signed := *signatory
+		signed.SSHPublicKey = sigPubKey
+		signed.SSHSignature = path.Join(tempDir, record.SSHSignature)
+		if err := ioutil.WriteFile(signed.SSHSignature, sig, 0644); err != nil {
+			t.Fatalf("failed to write signature file: %v", err)
+		}
+		return &signed, record
+	}
+	signed, record := signWith(keyFile, pubKey)
+	// someone who swapped their own key into the parameters before signing
+	forged, forgedRecord := signWith(otherKeyFile, otherPubKey)
+	// a parameters file whose key differs from the one used to sign
+	swapped := *signed
+	swapped.SSHPublicKey = otherPubKey
+	withoutPubKey := *signed
+	withoutPubKey.SSHPublicKey = ""
+
+	testCases := []struct {
+		signatory      *contract.Signatory
+		record         *contract.SignatureRecord
+		allowedSigners string
+		status         contract.VerificationStatus
+		reason         string
+	}{
+		{signed, record, allowedSignersFile, contract.SignatureValid, ""},
+		{forged, forgedRecord, allowedSignersFile, contract.SignatureInvalid, ""},
+		{&swapped, record, allowedSignersFile, contract.SignatureInvalid, "is not the one that was used to sign"},
+		{&withoutPubKey, record, allowedSignersFile, contract.SignatureInvalid, "no SSH public key is declared"},
+		// without an allowed signers file, the recorded keys are trusted
+		{signed, record, "", contract.SignatureValid, ""},
+		{forged, forgedRecord, "", contract.SignatureValid, ""},
+		{&swapped, record, "", contract.SignatureInvalid, "is not the one that was used to sign"},
+	}
+	for i, tc := range testCases {
+		result, err := contract.VerifySignatory(tc.signatory, tc.record, signedHashes, tc.allowedSigners)
+		if err != nil {
+			t.Fatalf("case %d: failed to verify signatory: %v", i, err)
+		}
+		if result.Status != tc.status {
+			t.Errorf("case %d: expected status \"%s\", but got \"%s\" (%s)", i, tc.status, result.Status, result.Reason)
+		}
+		if !strings.Contains(result.Reason, tc.reason) {
+			t.Errorf("case %d: expected reason to contain \"%s\", but got \"%s\"", i, tc.reason, result.Reason)
+		}
+	}
+}
+
+// generateTestSSHKey generates an ed25519 SSH key without a passphrase in the
+// given file. Returns the key file and its public key.
+func generateTestSSHKey(t *testing.T, keyFile string) (string, string) {
+	if output, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", keyFile).CombinedOutput(); err != nil {
+		t.Fatalf("failed to generate SSH key: %v\n%s", err, output)
+	}
+	pubKey, err := ioutil.ReadFile(keyFile + ".pub")
+	if err != nil {
+		t.Fatalf("failed to read SSH public key: %v", err)
+	}
+	return keyFile, strings.TrimSpace(string(pubKey))
+}
@@ -6,35 +6,74 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 
 	"github.com/rs/zerolog/log"
 )
 
+// webResponse captures the details of a response to a request for a file that
+// we need in order to revalidate our cached copy of the file later.
+type webResponse struct {
+	notModified  bool   // Did the server tell us that our cached copy is still up-to-date?
+	etag         string // The value of the response's ETag header (if any).
+	lastModified string // The value of the response's Last-Modified header (if any).
+}
+
+// networkError is returned when a request could not be made at all (as
+// opposed to the server responding with an error).
+type networkError struct {
+	u   *url.URL
+	err error
+}
+
+func (e *networkError) Error() string {
+	return fmt.Sprintf("request to \"%s\" failed: %s", e.u, e.err)
+}
+
 // Downloads the file at the given URL, saving it in the specified destination
-// file.
-func downloadFile(u *url.URL, destFile string) error {
+// file. If an ETag and/or last modification time is given, the request is
+// conditional and the destination file is left as-is if the server indicates
+// that the file has not been modified.
+func downloadFile(u *url.URL, destFile, etag, lastModified string) (*webResponse, error) {
 	log.Info().Msgf("Fetching URL: %s", u)
-	res, err := http.Get(u.String())
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if len(etag) > 0 {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if len(lastModified) > 0 {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, &networkError{u: u, err: err}
 	}
 	defer res.Body.Close()
+	result := &webResponse{
+		etag:         res.Header.Get("ETag"),
+		lastModified: res.Header.Get("Last-Modified"),
+	}
+	if res.StatusCode == http.StatusNotModified {
+		log.Debug().Msgf("File at %s has not been modified since it was cached", u)
+		result.notModified = true
+		return result, nil
+	}
 	if res.StatusCode >= 400 {
-		return fmt.Errorf("request to \"%s\" failed with code %d", u, res.StatusCode)
+		return nil, fmt.Errorf("request to \"%s\" failed with code %d", u, res.StatusCode)
 	}
 	content, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return err
+		return nil, &networkError{u: u, err: err}
 	}
 
 	log.Info().Msgf("Writing response body to %s", destFile)
-	f, err := os.Create(destFile)
-	if err != nil {
-		return fmt.Errorf("failed to create destination file %s: %v", destFile, err)
+	if err := os.MkdirAll(path.Dir(destFile), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create destination folder for %s: %v", destFile, err)
 	}
-	defer f.Close()
-	if _, err := f.Write(content); err != nil {
-		return err
+	if err := ioutil.WriteFile(destFile, content, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write destination file %s: %v", destFile, err)
 	}
-	return nil
+	return result, nil
 }